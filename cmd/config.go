@@ -0,0 +1,118 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/presentation/postmortem"
+	"github.com/slack-go/slack"
+	"github.com/spf13/cobra"
+)
+
+var checkSlack bool
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "inspect the config file",
+}
+
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "validate the config file",
+	Run: func(cmd *cobra.Command, args []string) {
+		ok, err := runConfigCheck(false)
+		if err != nil {
+			slog.Error("Failed to validate config", slog.Any("error", err))
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+var configLintCmd = &cobra.Command{
+	Use:   "lint",
+	Short: "validate the config file and report suspicious settings",
+	Run: func(cmd *cobra.Command, args []string) {
+		ok, err := runConfigCheck(true)
+		if err != nil {
+			slog.Error("Failed to lint config", slog.Any("error", err))
+			os.Exit(1)
+		}
+		if !ok {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	configCmd.PersistentFlags().BoolVar(&checkSlack, "slack", false, "resolve incident_team_members and announcement channels through Slack (requires SLACK_BOT_TOKEN)")
+	configCmd.AddCommand(configValidateCmd)
+	configCmd.AddCommand(configLintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// runConfigCheck は問題を標準出力に表示し、エラーが無ければ true を返す
+// lint の場合は警告も表示するが、警告だけでは失敗にしない
+func runConfigCheck(lint bool) (bool, error) {
+	ctx := context.Background()
+
+	cfg, err := repository.NewConfigRepository(configPath)
+	if err != nil {
+		var verrs validator.ValidationErrors
+		if !errors.As(err, &verrs) {
+			return false, err
+		}
+		for _, e := range verrs {
+			fmt.Printf("error: %s: failed on %q\n", e.Namespace(), e.Tag())
+		}
+		return false, nil
+	}
+
+	errs := cfg.Validate()
+	errs = append(errs, validatePostmortemTemplates(cfg)...)
+	if checkSlack {
+		if os.Getenv("SLACK_BOT_TOKEN") == "" {
+			return false, fmt.Errorf("environment variable SLACK_BOT_TOKEN is required for --slack")
+		}
+		errs = append(errs, cfg.ValidateSlack(ctx, repository.NewSlackRepository(slack.New(os.Getenv("SLACK_BOT_TOKEN"))))...)
+	}
+	for _, e := range errs {
+		fmt.Printf("error: %s\n", e)
+	}
+	if lint {
+		for _, w := range cfg.Lint() {
+			fmt.Printf("warning: %s\n", w)
+		}
+	}
+	if len(errs) > 0 {
+		return false, nil
+	}
+	fmt.Printf("%s: ok\n", configPath)
+	return true, nil
+}
+
+// validatePostmortemTemplates は設定されたポストモーテムのテンプレートが読み込めて描画できるかを確認する
+func validatePostmortemTemplates(cfg *repository.Config) []error {
+	var errs []error
+	if path := cfg.PostmortemTemplatePath(nil); path != "" {
+		if _, err := postmortem.Load(path); err != nil {
+			errs = append(errs, fmt.Errorf("postmortem_template: %w", err))
+		}
+	}
+	for _, svc := range cfg.ServiceList {
+		if svc.PostmortemTemplate == "" {
+			continue
+		}
+		if _, err := postmortem.Load(cfg.PostmortemTemplatePath(&svc)); err != nil {
+			errs = append(errs, fmt.Errorf("services[%d]: postmortem_template: %w", svc.ID, err))
+		}
+	}
+	return errs
+}
@@ -0,0 +1,92 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/joho/godotenv"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/spf13/cobra"
+)
+
+var (
+	migrateTimeout     time.Duration
+	backfillEmbeddings bool
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "migrate database schema to the latest version",
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := runMigrate(); err != nil {
+			slog.Error("Failed to migrate", slog.Any("error", err))
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	migrateCmd.Flags().DurationVar(&migrateTimeout, "timeout", 30*time.Minute, "timeout for migration")
+	migrateCmd.Flags().BoolVar(&backfillEmbeddings, "backfill-embeddings", false, "embed recovered incidents for similar incident search using the ai settings in the config file")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate() error {
+	if _, err := os.Stat(".env"); err == nil {
+		err := godotenv.Load()
+		if err != nil {
+			log.Fatal("Error loading .env file")
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), migrateTimeout)
+	defer cancel()
+
+	r, err := repository.NewDBRepository()
+	if err != nil {
+		return err
+	}
+
+	slog.Info("Migration started")
+	if err := r.Migrate(ctx); err != nil {
+		return err
+	}
+	if backfillEmbeddings {
+		if err := runBackfillEmbeddings(ctx, r); err != nil {
+			return err
+		}
+	}
+	slog.Info("Migration completed")
+	return nil
+}
+
+// runBackfillEmbeddings は類似インシデントの検索を導入する前に復旧したインシデントのベクトルを計算する
+func runBackfillEmbeddings(ctx context.Context, r repository.DBRepositoryer) error {
+	cfg, err := repository.NewConfigRepository(configPath)
+	if err != nil {
+		return err
+	}
+	if cfg.AI.SimilarIncidents.Disabled {
+		return fmt.Errorf("similar incident search is disabled in %s", configPath)
+	}
+	ai, err := repository.NewAIRepository(cfg.AI)
+	if err != nil {
+		return err
+	}
+	if ai == nil || !ai.SupportsEmbedding() {
+		return fmt.Errorf("no AI provider supporting embeddings is configured")
+	}
+
+	index, err := repository.NewIncidentIndex(ctx, r)
+	if err != nil {
+		return fmt.Errorf("failed to load incident embeddings: %w", err)
+	}
+	slog.Info("Backfilling incident embeddings")
+	saved, err := index.Backfill(ctx, r, ai)
+	slog.Info("Backfilled incident embeddings", slog.Int("saved", saved))
+	return err
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"log"
 	"log/slog"
 	"os"
@@ -13,7 +14,10 @@ import (
 )
 
 var (
-	configPath string
+	configPath     string
+	mode           string
+	listenAddr     string
+	leaderElection bool
 )
 
 var rootCmd = &cobra.Command{
@@ -42,7 +46,23 @@ func init() {
 		slog.Error("Failed to get user home directory", slog.Any("error", err))
 		os.Exit(1)
 	}
-	rootCmd.Flags().StringVar(&configPath, "config", path.Join(home, "yas3.toml"), "config file path")
+	rootCmd.PersistentFlags().StringVar(&configPath, "config", path.Join(home, "yas3.toml"), "config file path")
+	rootCmd.Flags().StringVar(&mode, "mode", handler.ModeSocket, "how to receive events from Slack (socket|http)")
+	rootCmd.Flags().StringVar(&listenAddr, "listen", ":3000", "listen address for http mode, alert webhooks and api")
+	rootCmd.Flags().BoolVar(&leaderElection, "leader-election", false, "run periodic jobs on only one replica using a lease in the database")
+}
+
+// config validate などオフラインで動くコマンドのため、ボットの起動時にだけ確認する
+func validateEnv() error {
+	requiredEnv := []string{
+		"SLACK_BOT_TOKEN",
+	}
+	for _, env := range requiredEnv {
+		if os.Getenv(env) == "" {
+			return fmt.Errorf("environment variable %s is required but not set", env)
+		}
+	}
+	return nil
 }
 
 func run() error {
@@ -55,8 +75,12 @@ func run() error {
 		}
 	}
 
+	if err := validateEnv(); err != nil {
+		return err
+	}
+
 	slog.Info("Server started")
-	if err := handler.Handle(ctx, configPath); err != nil {
+	if err := handler.Handle(ctx, configPath, handler.Options{Mode: mode, Listen: listenAddr, LeaderElection: leaderElection}); err != nil {
 		return err
 	}
 
@@ -0,0 +1,76 @@
+package entity
+
+// AI プロバイダーの種類
+const (
+	AIProviderOpenAI           = "openai"
+	AIProviderAzure            = "azure"
+	AIProviderAnthropic        = "anthropic"
+	AIProviderOpenAICompatible = "openai_compatible"
+)
+
+// AIConfig は AI の呼び出し先。Providers が空の場合は環境変数の OpenAI / Azure OpenAI を使う
+type AIConfig struct {
+	// 既定で使うプロバイダー名
+	Provider string `mapstructure:"provider"`
+	// 既定で使うモデル。プロバイダーやタスクの指定が優先される
+	Model     string                      `mapstructure:"model"`
+	Providers map[string]AIProviderConfig `mapstructure:"providers" validate:"dive"`
+	// タスクごとにプロバイダーやモデルを変える。キーはタスク名
+	Tasks              map[string]AITaskConfig  `mapstructure:"tasks"`
+	Redaction          RedactionConfig          `mapstructure:"redaction"`
+	SimilarIncidents   SimilarIncidentsConfig   `mapstructure:"similar_incidents"`
+	SeveritySuggestion SeveritySuggestionConfig `mapstructure:"severity_suggestion"`
+}
+
+// SeveritySuggestionConfig は AI にインシデントレベルと緊急度を提案させる設定
+type SeveritySuggestionConfig struct {
+	// true の場合は提案しない
+	Disabled bool `mapstructure:"disabled"`
+}
+
+// SimilarIncidentsConfig はインシデント作成時に類似した過去のインシデントを探す設定
+type SimilarIncidentsConfig struct {
+	// true の場合は探さない
+	Disabled bool `mapstructure:"disabled"`
+	// 表示する件数。省略時は 3 件
+	Limit int `mapstructure:"limit" validate:"gte=0"`
+	// 表示するコサイン類似度の下限。省略時は 0.5
+	MinScore float64 `mapstructure:"min_score" validate:"gte=0,lte=1"`
+}
+
+// RedactionConfig は AI に送る前に秘密情報や個人情報を伏せる設定
+type RedactionConfig struct {
+	// true の場合は伏せずに送る
+	Disabled bool `mapstructure:"disabled"`
+	// 使わない組み込みの検出器の名前
+	DisableDetectors []string `mapstructure:"disable_detectors"`
+	// 組み込みの検出器に加えて伏せるパターン
+	Patterns []RedactionPattern `mapstructure:"patterns" validate:"dive"`
+	// ポストモーテムなどの完成した文書で元の値に戻す検出器の名前
+	Restore []string `mapstructure:"restore"`
+}
+
+// RedactionPattern は名前付きの正規表現。キャプチャグループがある場合は最初のグループだけを伏せる
+type RedactionPattern struct {
+	Name  string `mapstructure:"name" validate:"required"`
+	Regex string `mapstructure:"regex" validate:"required"`
+}
+
+// AIProviderConfig は AI プロバイダーへの接続情報
+type AIProviderConfig struct {
+	Type string `mapstructure:"type" validate:"required,oneof=openai azure anthropic openai_compatible"`
+	// API のベース URL。azure の場合はエンドポイント
+	BaseURL string `mapstructure:"base_url"`
+	// API キーを読む環境変数名。省略時は種類ごとの既定の環境変数を使う
+	APIKeyEnv string `mapstructure:"api_key_env"`
+	// azure の API バージョン
+	APIVersion string `mapstructure:"api_version"`
+	// このプロバイダーで既定に使うモデル
+	Model string `mapstructure:"model"`
+}
+
+// AITaskConfig はタスクで使うプロバイダーとモデル。空の項目は既定の設定を使う
+type AITaskConfig struct {
+	Provider string `mapstructure:"provider"`
+	Model    string `mapstructure:"model"`
+}
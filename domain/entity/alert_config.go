@@ -0,0 +1,12 @@
+package entity
+
+type AlertConfig struct {
+	// サービスを特定するラベル名。値がサービス名と一致するものを選ぶ
+	ServiceLabel string `mapstructure:"service_label"`
+	// 緊急度を決めるラベル名
+	SeverityLabel string `mapstructure:"severity_label"`
+	// ラベルの値から緊急度(none/warning/error/critical)への対応表
+	Urgencies map[string]string `mapstructure:"urgencies"`
+	// どのサービスにも一致しなかった場合に使うサービスID
+	DefaultServiceID int `mapstructure:"default_service_id"`
+}
@@ -0,0 +1,15 @@
+package entity
+
+import "time"
+
+// AutoSummarySchedule は進捗サマリを自動で作成する間隔
+type AutoSummarySchedule struct {
+	// 作成する間隔(分)。0 の場合は作成しない
+	IntervalMinutes int `mapstructure:"interval_minutes" validate:"gte=0"`
+	// true の場合はアナウンスチャンネルにも投稿する
+	Announce bool `mapstructure:"announce"`
+}
+
+func (s AutoSummarySchedule) Interval() time.Duration {
+	return time.Duration(s.IntervalMinutes) * time.Minute
+}
@@ -0,0 +1,34 @@
+package entity
+
+// 関係者向けの連絡の読み手
+const (
+	AudienceExecutive   = "executive"
+	AudienceCustomer    = "customer"
+	AudienceEngineering = "engineering"
+)
+
+// Audiences は連絡の下書きを作成する読み手。この順に表示する
+var Audiences = []string{AudienceExecutive, AudienceCustomer, AudienceEngineering}
+
+// CommunicationChannels は読み手ごとの連絡を投稿するチャンネル
+type CommunicationChannels struct {
+	// 経営層向けの状況報告
+	Executive []string `mapstructure:"executive"`
+	// カスタマーサポートが顧客に案内するための障害情報
+	Customer []string `mapstructure:"customer"`
+	// 社内のエンジニア向けの技術的な状況共有
+	Engineering []string `mapstructure:"engineering"`
+}
+
+// Channels は audience の投稿先を返す
+func (c CommunicationChannels) Channels(audience string) []string {
+	switch audience {
+	case AudienceExecutive:
+		return c.Executive
+	case AudienceCustomer:
+		return c.Customer
+	case AudienceEngineering:
+		return c.Engineering
+	}
+	return nil
+}
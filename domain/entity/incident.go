@@ -8,6 +8,18 @@ type LinkedChannel struct {
 	ThreadTS  string `json:"thread_ts,omitempty"` // スレッドの場合のみ設定
 }
 
+// エスカレーションの理由
+const (
+	EscalationNoHandler = "no_handler"
+	EscalationSilent    = "silent"
+)
+
+// Escalation はボットが行ったエスカレーションの記録
+type Escalation struct {
+	Reason      string    `json:"reason" dynamo:"reason"`
+	EscalatedAt time.Time `json:"escalated_at" dynamo:"escalated_at"`
+}
+
 type Incident struct {
 	ChannelID              string          `json:"channel_id" dynamo:"channel_id,hash"`
 	Description            string          `json:"description" dynamo:"description"`
@@ -28,4 +40,26 @@ type Incident struct {
 	LastSummaryAt          time.Time       `json:"last_summary_at" dynamo:"last_summary_at"`
 	LastProcessedMessageTS string          `json:"last_processed_message_ts" dynamo:"last_processed_message_ts"`
 	LinkedChannels         []LinkedChannel `json:"linked_channels" dynamo:"linked_channels"`
+	// 楽観的排他制御のためのバージョン。保存のたびにリポジトリがインクリメントする
+	Version int `json:"version" dynamo:"version"`
+	// アラートから自動で作成された場合のフィンガープリント。同じアラートの重複起票を防ぐ
+	AlertFingerprint string `json:"alert_fingerprint,omitempty" dynamo:"alert_fingerprint,omitempty"`
+	// 最後に投稿したチェックポイントの予定時刻。再起動をまたいで重複や取りこぼしを防ぐ
+	LastCheckpointAt time.Time    `json:"last_checkpoint_at" dynamo:"last_checkpoint_at"`
+	Escalations      []Escalation `json:"escalations" dynamo:"escalations"`
+	// 役割IDごとの担当者のユーザーID
+	Roles map[string][]string `json:"roles" dynamo:"roles"`
+	// ハンドラーの応募、/incident handler、オンコールでハンドラーが決まった時刻
+	// 作成者が仮のハンドラーになっている間はゼロ値
+	HandlerAssignedAt time.Time `json:"handler_assigned_at" dynamo:"handler_assigned_at"`
+}
+
+// LastEscalation は reason のエスカレーションのうち最後のものを返す
+func (i *Incident) LastEscalation(reason string) (Escalation, bool) {
+	for n := len(i.Escalations) - 1; n >= 0; n-- {
+		if i.Escalations[n].Reason == reason {
+			return i.Escalations[n], true
+		}
+	}
+	return Escalation{}, false
 }
@@ -0,0 +1,20 @@
+package entity
+
+import "time"
+
+// 埋め込みベクトルの元にした文章の種類
+const (
+	EmbeddingSourceDescription = "description"
+	EmbeddingSourceSummary     = "summary"
+	EmbeddingSourcePostMortem  = "postmortem"
+)
+
+// IncidentEmbedding はインシデントの文章の埋め込みベクトル。類似したインシデントの検索に使う
+type IncidentEmbedding struct {
+	ChannelID string `json:"channel_id" dynamo:"channel_id,hash"`
+	Source    string `json:"source" dynamo:"source,range"`
+	// ベクトルを計算したモデル。モデルが異なるベクトルは比較できない
+	Model     string    `json:"model" dynamo:"model"`
+	Vector    []float32 `json:"vector" dynamo:"vector"`
+	UpdatedAt time.Time `json:"updated_at" dynamo:"updated_at"`
+}
@@ -0,0 +1,39 @@
+package entity
+
+import "time"
+
+type IncidentEventType string
+
+const (
+	IncidentEventCreated                IncidentEventType = "created"
+	IncidentEventHandlerAssigned        IncidentEventType = "handler_assigned"
+	IncidentEventLevelChanged           IncidentEventType = "level_changed"
+	IncidentEventSummaryEdited          IncidentEventType = "summary_edited"
+	IncidentEventRecovered              IncidentEventType = "recovered"
+	IncidentEventReopened               IncidentEventType = "reopened"
+	IncidentEventLinked                 IncidentEventType = "linked"
+	IncidentEventUnlinked               IncidentEventType = "unlinked"
+	IncidentEventPostMortemCreated      IncidentEventType = "postmortem_created"
+	IncidentEventTimerStopped           IncidentEventType = "timer_stopped"
+	IncidentEventProgressSummaryUpdated IncidentEventType = "progress_summary_updated"
+	IncidentEventClosed                 IncidentEventType = "closed"
+	IncidentEventEscalated              IncidentEventType = "escalated"
+	IncidentEventRoleAssigned           IncidentEventType = "role_assigned"
+	IncidentEventRoleUnassigned         IncidentEventType = "role_unassigned"
+	IncidentEventUrgencyChanged         IncidentEventType = "urgency_changed"
+	// AI が提案したインシデントレベルと緊急度が却下された。同じ提案は繰り返さない
+	IncidentEventSeveritySuggestionRejected IncidentEventType = "severity_suggestion_rejected"
+	// 関係者向けの連絡を投稿した
+	IncidentEventCommunicationPosted IncidentEventType = "communication_posted"
+)
+
+// IncidentEvent はインシデントに対する変更履歴。追記のみで更新はしない
+type IncidentEvent struct {
+	ChannelID   string            `json:"channel_id" dynamo:"channel_id,hash"`
+	EventID     string            `json:"event_id" dynamo:"event_id,range"` // 発生時刻順に並ぶID
+	Type        IncidentEventType `json:"type" dynamo:"type"`
+	ActorUserID string            `json:"actor_user_id" dynamo:"actor_user_id"`
+	OccurredAt  time.Time         `json:"occurred_at" dynamo:"occurred_at"`
+	// 変更前後の値など、イベントごとの付加情報
+	Metadata map[string]string `json:"metadata,omitempty" dynamo:"metadata,omitempty"`
+}
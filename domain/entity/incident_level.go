@@ -4,4 +4,8 @@ type IncidentLevel struct {
 	Description string `mapstructure:"description" validate:"required"`
 	Level       int    `mapstructure:"level" validate:"required,gte=0"`
 	Disabled    bool   `mapstructure:"disabled"`
+	// このレベルのチェックポイントの間隔。未設定の場合は緊急度や全体の設定を使う
+	Timekeeper TimekeeperSchedule `mapstructure:"timekeeper"`
+	// このレベルで進捗サマリを自動で作成する間隔。未設定の場合はサービスの設定を使う
+	AutoSummary AutoSummarySchedule `mapstructure:"auto_summary"`
 }
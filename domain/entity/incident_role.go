@@ -0,0 +1,17 @@
+package entity
+
+// IncidentRole はインシデント対応の役割
+type IncidentRole struct {
+	ID   string `mapstructure:"id" validate:"required"`
+	Name string `mapstructure:"name" validate:"required"`
+	// 複数人で担当できる役割。false の場合、担当すると前任者から引き継ぐ
+	Multiple bool `mapstructure:"multiple"`
+}
+
+// DefaultIncidentRoles は設定ファイルで roles を指定しなかった場合の役割
+var DefaultIncidentRoles = []IncidentRole{
+	{ID: "commander", Name: "コマンダー"},
+	{ID: "comms_lead", Name: "広報担当"},
+	{ID: "scribe", Name: "書記"},
+	{ID: "sme", Name: "専門家", Multiple: true},
+}
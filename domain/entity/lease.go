@@ -0,0 +1,10 @@
+package entity
+
+// Lease はレプリカのうち1台だけが処理を行うためのロック
+// 期限が切れるまでは Holder 以外は取得できない
+type Lease struct {
+	Name   string `json:"name" dynamo:"name,hash"`
+	Holder string `json:"holder" dynamo:"holder"`
+	// 期限(UNIX時間のミリ秒)。DynamoDBのTTLは秒単位のため、TTL属性には使えない
+	ExpiresAt int64 `json:"expires_at" dynamo:"expires_at"`
+}
@@ -0,0 +1,118 @@
+package entity
+
+import (
+	"fmt"
+	"time"
+)
+
+// OnCallTimeLayout はオンコールの開始日時やオーバーライドに使う日時の書式
+const OnCallTimeLayout = "2006-01-02T15:04"
+
+// OnCallSchedule はオンコール担当のローテーション
+type OnCallSchedule struct {
+	ID string `mapstructure:"id" validate:"required"`
+	// ローテーションを計算するタイムゾーン。省略時は UTC
+	Timezone string `mapstructure:"timezone"`
+	// 交代の単位。daily または weekly
+	Rotation string `mapstructure:"rotation" validate:"required,oneof=daily weekly"`
+	// Members の先頭が担当を始める日時。以降 Rotation ごとに次のメンバーへ交代する
+	Start string `mapstructure:"start" validate:"required"`
+	// 担当する順の Slack のユーザー名
+	Members []string `mapstructure:"members" validate:"required,min=1"`
+	// 休暇や交代などで一時的に担当を差し替える
+	Overrides []OnCallOverride `mapstructure:"overrides" validate:"dive"`
+}
+
+// OnCallOverride は Start から End までの間、ローテーションに関係なく Member を担当にする
+type OnCallOverride struct {
+	Member string `mapstructure:"member" validate:"required"`
+	Start  string `mapstructure:"start" validate:"required"`
+	End    string `mapstructure:"end" validate:"required"`
+}
+
+func (s OnCallSchedule) location() (*time.Location, error) {
+	if s.Timezone == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q: %w", s.Timezone, err)
+	}
+	return loc, nil
+}
+
+// Validate はタイムゾーンと日時の書式、オーバーライドの期間を検証する
+func (s OnCallSchedule) Validate() error {
+	loc, err := s.location()
+	if err != nil {
+		return err
+	}
+	if _, err := time.ParseInLocation(OnCallTimeLayout, s.Start, loc); err != nil {
+		return fmt.Errorf("invalid start %q: %w", s.Start, err)
+	}
+	for i, o := range s.Overrides {
+		start, end, err := o.period(loc)
+		if err != nil {
+			return fmt.Errorf("overrides[%d]: %w", i, err)
+		}
+		if !end.After(start) {
+			return fmt.Errorf("overrides[%d]: end must be after start", i)
+		}
+	}
+	return nil
+}
+
+func (o OnCallOverride) period(loc *time.Location) (time.Time, time.Time, error) {
+	start, err := time.ParseInLocation(OnCallTimeLayout, o.Start, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid start %q: %w", o.Start, err)
+	}
+	end, err := time.ParseInLocation(OnCallTimeLayout, o.End, loc)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("invalid end %q: %w", o.End, err)
+	}
+	return start, end, nil
+}
+
+// OnCall は now の時点で担当しているメンバーを返す
+// オーバーライドが優先され、ローテーションの開始前は空文字を返す
+func (s OnCallSchedule) OnCall(now time.Time) (string, error) {
+	loc, err := s.location()
+	if err != nil {
+		return "", err
+	}
+	now = now.In(loc)
+
+	for i, o := range s.Overrides {
+		start, end, err := o.period(loc)
+		if err != nil {
+			return "", fmt.Errorf("overrides[%d]: %w", i, err)
+		}
+		if !now.Before(start) && now.Before(end) {
+			return o.Member, nil
+		}
+	}
+
+	start, err := time.ParseInLocation(OnCallTimeLayout, s.Start, loc)
+	if err != nil {
+		return "", fmt.Errorf("invalid start %q: %w", s.Start, err)
+	}
+	if now.Before(start) || len(s.Members) == 0 {
+		return "", nil
+	}
+
+	// 夏時間で1日が24時間にならない場合があるため、暦の上の日数で数える
+	days := int(now.Sub(start).Hours() / 24)
+	for days > 0 && start.AddDate(0, 0, days).After(now) {
+		days--
+	}
+	for !start.AddDate(0, 0, days+1).After(now) {
+		days++
+	}
+
+	period := 1
+	if s.Rotation == "weekly" {
+		period = 7
+	}
+	return s.Members[(days/period)%len(s.Members)], nil
+}
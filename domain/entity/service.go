@@ -7,4 +7,27 @@ type Service struct {
 	IncidentTeamMembers  []string         `mapstructure:"incident_team_members"`
 	AnnouncementChannels []string         `mapstructure:"announcement_channels"`
 	Confluence           ConfluenceConfig `mapstructure:"confluence"`
+	// アラートのラベルがすべて一致した場合にこのサービスのインシデントとして扱う
+	AlertMatchers map[string]string `mapstructure:"alert_matchers"`
+	Escalation    EscalationPolicy  `mapstructure:"escalation"`
+	// 作成時に招待してハンドラーにするオンコールのスケジュールID
+	OnCallSchedule string `mapstructure:"oncall_schedule"`
+	// このサービスのポストモーテムに使うテンプレートファイルのパス
+	PostmortemTemplate string `mapstructure:"postmortem_template"`
+	// 進捗サマリを自動で作成する間隔。インシデントレベルの設定が優先される
+	AutoSummary AutoSummarySchedule `mapstructure:"auto_summary"`
+	// 関係者向けの連絡を投稿するチャンネル。全体の設定と合わせて投稿する
+	Communication CommunicationChannels `mapstructure:"communication"`
+}
+
+// EscalationPolicy は対応が進んでいないインシデントをエスカレーションする条件
+type EscalationPolicy struct {
+	// ハンドラーが決まらないまま経過したらエスカレーションする時間(分)。0 は無効
+	NoHandlerMinutes int `mapstructure:"no_handler_minutes"`
+	// チャンネルで発言が無いまま経過したらエスカレーションする時間(分)。0 は無効
+	SilentMinutes int `mapstructure:"silent_minutes"`
+	// SilentMinutes を適用する最低のインシデントレベル
+	SilentMinLevel int `mapstructure:"silent_min_level"`
+	// メンションするユーザーグループ(またはユーザー)
+	UserGroups []string `mapstructure:"user_groups"`
 }
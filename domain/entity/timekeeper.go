@@ -0,0 +1,81 @@
+package entity
+
+import (
+	"sort"
+	"time"
+)
+
+// DefaultTimekeeperInterval は設定が無い場合のチェックポイントの間隔
+const DefaultTimekeeperInterval = 15 * time.Minute
+
+// TimekeeperSchedule はチェックポイントを投稿する間隔
+type TimekeeperSchedule struct {
+	// チェックポイントの間隔(分)。0 の場合は未設定として扱う
+	IntervalMinutes int `mapstructure:"interval_minutes" validate:"gte=0"`
+	// 経過時間に応じて間隔を広げる場合に指定する
+	Steps []TimekeeperStep `mapstructure:"steps" validate:"dive"`
+}
+
+// TimekeeperStep は検知から AfterMinutes 経過した後の間隔
+type TimekeeperStep struct {
+	AfterMinutes    int `mapstructure:"after_minutes" validate:"gt=0"`
+	IntervalMinutes int `mapstructure:"interval_minutes" validate:"gt=0"`
+}
+
+func (s TimekeeperSchedule) IsZero() bool {
+	return s.IntervalMinutes == 0 && len(s.Steps) == 0
+}
+
+// Interval は検知から elapsed 経過した時点の間隔を返す
+// 間隔が 0 以下の段階は無視するため、常に正の値を返す
+func (s TimekeeperSchedule) Interval(elapsed time.Duration) time.Duration {
+	interval := DefaultTimekeeperInterval
+	if s.IntervalMinutes > 0 {
+		interval = time.Duration(s.IntervalMinutes) * time.Minute
+	}
+
+	steps := append([]TimekeeperStep(nil), s.Steps...)
+	sort.Slice(steps, func(i, j int) bool { return steps[i].AfterMinutes < steps[j].AfterMinutes })
+	for _, step := range steps {
+		if elapsed < time.Duration(step.AfterMinutes)*time.Minute {
+			break
+		}
+		if step.IntervalMinutes > 0 {
+			interval = time.Duration(step.IntervalMinutes) * time.Minute
+		}
+	}
+	return interval
+}
+
+// Checkpoint は検知から elapsed 経過した時点までの最後のチェックポイントを
+// 検知からの経過時間で返す。elapsed が負の場合は -1 を返す
+func (s TimekeeperSchedule) Checkpoint(elapsed time.Duration) time.Duration {
+	if elapsed < 0 {
+		return -1
+	}
+	var at time.Duration
+	for {
+		next := at + s.Interval(at)
+		if next > elapsed {
+			return at
+		}
+		at = next
+	}
+}
+
+// NextCheckpoint は検知から elapsed 経過した時点より後の最初のチェックポイントを
+// 検知からの経過時間で返す。チェックポイントは検知時点から始まり、その時点の間隔ずつ進む
+func (s TimekeeperSchedule) NextCheckpoint(elapsed time.Duration) time.Duration {
+	var at time.Duration
+	for at <= elapsed {
+		at += s.Interval(at)
+	}
+	return at
+}
+
+// TimekeeperConfig は全体と緊急度ごとのチェックポイントの間隔
+type TimekeeperConfig struct {
+	TimekeeperSchedule `mapstructure:",squash"`
+	// 緊急度(none/warning/error/critical)ごとの間隔
+	Urgencies map[string]TimekeeperSchedule `mapstructure:"urgencies" validate:"dive"`
+}
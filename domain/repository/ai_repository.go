@@ -1,16 +1,19 @@
 package repository
 
 import (
+	"cmp"
 	"context"
+	"encoding/json"
 	"fmt"
+	"maps"
 	"os"
+	"regexp"
+	"slices"
 	"strings"
 	"time"
 
 	"github.com/Songmu/retry"
-	"github.com/openai/openai-go"
-	"github.com/openai/openai-go/azure"
-	"github.com/openai/openai-go/option"
+	"github.com/pyama86/YAS3/domain/entity"
 	"github.com/slack-go/slack"
 )
 
@@ -29,67 +32,223 @@ type AIRepositorier interface {
 	FormatTimeline(rawTimeline string) (string, error)
 	AnalyzeRemainingTasks(description, slackMessages string) (string, error)
 	PrepareMessagesForPostMortem(messages []slack.Message, description string) (string, error)
+	// AnswerQuestion はチャンネルの発言をもとに質問に答える
+	// 回答は根拠にした発言を messages の 1 から始まる番号で [1] のように引用する
+	AnswerQuestion(description, question string, messages []slack.Message) (string, error)
+	// SuggestSeverity は levels と urgencies(値と説明) の中からインシデントレベルと緊急度を提案する
+	SuggestSeverity(description, slackMessages string, levels []entity.IncidentLevel, urgencies map[string]string) (*SeveritySuggestion, error)
+	// DraftCommunication は audience(entity.Audiences のいずれか) 向けの連絡の下書きを作成する
+	DraftCommunication(audience, description, summary, slackMessages string) (string, error)
+	// Embed は文章の埋め込みベクトルと、計算に使ったモデルを返す
+	Embed(texts ...string) ([][]float32, string, error)
+	// Redact は AI に送る文章から秘密情報や個人情報を伏せる
+	Redact(text string) string
+	// Restore は伏せた値のうち、戻してよいものを sources に含まれる元の値に戻す
+	Restore(text string, sources ...string) string
+}
+
+// AI に依頼するタスク。設定の ai.tasks のキーに使う
+const (
+	AITaskSummary        = "summary"
+	AITaskProgress       = "progress"
+	AITaskTitle          = "title"
+	AITaskStatus         = "status"
+	AITaskImpact         = "impact"
+	AITaskRootCause      = "root_cause"
+	AITaskTrigger        = "trigger"
+	AITaskSolution       = "solution"
+	AITaskActionItems    = "action_items"
+	AITaskLessons        = "lessons"
+	AITaskTimeline       = "timeline"
+	AITaskRemainingTasks = "remaining_tasks"
+	// ポストモーテム用に長いチャンネルの発言を要約する
+	AITaskDigest = "digest"
+	// チャンネルの発言をもとに質問に答える
+	AITaskAnswer = "answer"
+	// インシデントレベルと緊急度を提案する
+	AITaskSeverity = "severity"
+	// 関係者向けの連絡の下書きを作成する
+	AITaskCommunication = "communication"
+)
+
+// AITaskEmbedding は類似インシデントの検索に使う埋め込みのタスク
+// 文章を生成するモデルは使えないため、全体やプロバイダーのモデルではなく専用の既定のモデルを使う
+const AITaskEmbedding = "embedding"
+
+const defaultEmbeddingModel = "text-embedding-3-small"
+
+// AITasks は設定できるタスクの一覧
+var AITasks = []string{
+	AITaskSummary, AITaskProgress, AITaskTitle, AITaskStatus, AITaskImpact, AITaskRootCause, AITaskTrigger,
+	AITaskSolution, AITaskActionItems, AITaskLessons, AITaskTimeline, AITaskRemainingTasks, AITaskDigest,
+	AITaskAnswer, AITaskSeverity, AITaskCommunication,
 }
 
 type AIRepository struct {
-	client *openai.Client
-	model  string
+	providers map[string]LLMProvider
+	config    entity.AIConfig
+	redactor  *Redactor
 }
 
-func NewAIRepository() (*AIRepository, error) {
-	if os.Getenv("OPENAI_API_KEY") == "" && os.Getenv("AZURE_OPENAI_KEY") == "" {
-		return nil, nil
+// NewAIRepository は設定に従って AI プロバイダーを初期化する
+// プロバイダーの設定が無く、環境変数も設定されていない場合は nil を返す
+func NewAIRepository(config entity.AIConfig) (*AIRepository, error) {
+	if len(config.Providers) == 0 {
+		config = aiConfigFromEnv(config)
+		if len(config.Providers) == 0 {
+			return nil, nil
+		}
 	}
 
-	var model = "gpt-4"
-	if os.Getenv("OPENAI_MODEL") != "" {
-		model = os.Getenv("OPENAI_MODEL")
+	providers := map[string]LLMProvider{}
+	for name, c := range config.Providers {
+		p, err := newLLMProvider(c)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize AI provider %s: %w", name, err)
+		}
+		providers[name] = p
 	}
-	client, err := newOpenAIClient()
+	redactor, err := NewRedactor(config.Redaction)
 	if err != nil {
-		return nil, fmt.Errorf("failed to initialize OpenAI client: %w", err)
+		return nil, err
 	}
-	return &AIRepository{
-		client: client,
-		model:  model,
-	}, nil
+	h := &AIRepository{providers: providers, config: config, redactor: redactor}
+
+	// 設定の誤りは最初の呼び出しではなく起動時に検出する
+	for _, task := range AITasks {
+		if _, _, err := h.route(task); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
 }
 
-func newOpenAIClient() (*openai.Client, error) {
-	if os.Getenv("AZURE_OPENAI_ENDPOINT") != "" {
-		return newAzureClient()
+func (h *AIRepository) Redact(text string) string {
+	return h.redactor.Redact(text)
+}
+
+func (h *AIRepository) Restore(text string, sources ...string) string {
+	return h.redactor.Restore(text, sources...)
+}
+
+// newTokenCalculator はメッセージの内容を伏せる TokenCalculator を作る
+func (h *AIRepository) newTokenCalculator() (*TokenCalculator, error) {
+	tc, err := NewTokenCalculator()
+	if err != nil {
+		return nil, err
 	}
+	tc.SetRedactor(h.redactor)
+	return tc, nil
+}
 
-	key := os.Getenv("OPENAI_API_KEY")
-	if key == "" {
-		return nil, fmt.Errorf("OPENAI_API_KEY is not set")
+// messageTexts は Restore の元の値に使うメッセージの内容を返す
+func messageTexts(messages []slack.Message) []string {
+	texts := make([]string, 0, len(messages))
+	for _, m := range messages {
+		texts = append(texts, m.Text)
 	}
-	options := []option.RequestOption{
-		option.WithAPIKey(os.Getenv("OPENAI_API_KEY")),
+	return texts
+}
+
+// aiConfigFromEnv は環境変数から OpenAI または Azure OpenAI のプロバイダーを設定する
+func aiConfigFromEnv(config entity.AIConfig) entity.AIConfig {
+	var provider entity.AIProviderConfig
+	switch {
+	case os.Getenv("AZURE_OPENAI_ENDPOINT") != "" && os.Getenv("AZURE_OPENAI_KEY") != "":
+		provider = entity.AIProviderConfig{
+			Type:       entity.AIProviderAzure,
+			BaseURL:    os.Getenv("AZURE_OPENAI_ENDPOINT"),
+			APIVersion: os.Getenv("AZURE_OPENAI_API_VERSION"),
+		}
+	case os.Getenv("OPENAI_API_KEY") != "":
+		provider = entity.AIProviderConfig{Type: entity.AIProviderOpenAI}
+	default:
+		return config
 	}
 
-	c := openai.NewClient(options...)
-	return &c, nil
+	model := "gpt-4"
+	if os.Getenv("OPENAI_MODEL") != "" {
+		model = os.Getenv("OPENAI_MODEL")
+	}
+	config.Provider = "default"
+	config.Providers = map[string]entity.AIProviderConfig{"default": provider}
+	if config.Model == "" {
+		config.Model = model
+	}
+	return config
 }
 
-func newAzureClient() (*openai.Client, error) {
-	key := os.Getenv("AZURE_OPENAI_KEY")
-	if key == "" {
-		return nil, fmt.Errorf("AZURE_OPENAI_KEY is not set")
+// provider はタスクに使うプロバイダーの名前と実装を返す
+func (h *AIRepository) provider(task string) (string, LLMProvider, error) {
+	name := cmp.Or(h.config.Tasks[task].Provider, h.config.Provider)
+	if name == "" && len(h.providers) == 1 {
+		for n := range h.providers {
+			name = n
+		}
+	}
+	p, ok := h.providers[name]
+	if !ok {
+		return "", nil, fmt.Errorf("ai: provider %q for task %s is not configured", name, task)
 	}
-	var azureOpenAIEndpoint = os.Getenv("AZURE_OPENAI_ENDPOINT")
+	return name, p, nil
+}
 
-	var azureOpenAIAPIVersion = "2025-01-01-preview"
+// route はタスクに使うプロバイダーとモデルを返す
+// タスク、プロバイダー、全体の順に設定されているものを使う
+func (h *AIRepository) route(task string) (LLMProvider, string, error) {
+	name, p, err := h.provider(task)
+	if err != nil {
+		return nil, "", err
+	}
+	t := h.config.Tasks[task]
+	model := cmp.Or(t.Model, h.config.Providers[name].Model, h.config.Model)
+	if model == "" {
+		return nil, "", fmt.Errorf("ai: model for task %s is not configured", task)
+	}
+	return p, model, nil
+}
 
-	if os.Getenv("AZURE_OPENAI_API_VERSION") != "" {
-		azureOpenAIAPIVersion = os.Getenv("AZURE_OPENAI_API_VERSION")
+// embeddingRoute は埋め込みに使うプロバイダーとモデルを返す
+func (h *AIRepository) embeddingRoute() (Embedder, string, error) {
+	name, p, err := h.provider(AITaskEmbedding)
+	if err != nil {
+		return nil, "", err
+	}
+	e, ok := p.(Embedder)
+	if !ok {
+		return nil, "", fmt.Errorf("ai: provider %q does not support embeddings", name)
 	}
+	return e, cmp.Or(h.config.Tasks[AITaskEmbedding].Model, defaultEmbeddingModel), nil
+}
+
+// SupportsEmbedding は埋め込みに使えるプロバイダーが設定されているかを返す
+func (h *AIRepository) SupportsEmbedding() bool {
+	_, _, err := h.embeddingRoute()
+	return err == nil
+}
 
-	c := openai.NewClient(
-		azure.WithEndpoint(azureOpenAIEndpoint, azureOpenAIAPIVersion),
-		azure.WithAPIKey(key),
-	)
-	return &c, nil
+func (h *AIRepository) Embed(texts ...string) ([][]float32, string, error) {
+	e, model, err := h.embeddingRoute()
+	if err != nil {
+		return nil, "", err
+	}
+	redacted := make([]string, len(texts))
+	for i, text := range texts {
+		redacted[i] = h.redactor.Redact(text)
+	}
+	var vectors [][]float32
+	err = retry.Retry(3, time.Second*3, func() error {
+		v, err := e.Embed(context.Background(), model, redacted)
+		if err != nil {
+			return err
+		}
+		vectors = v
+		return nil
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return vectors, model, nil
 }
 
 func (h *AIRepository) Summarize(description, slackMessages string) (string, error) {
@@ -107,7 +266,7 @@ func (h *AIRepository) Summarize(description, slackMessages string) (string, err
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskSummary, prompt)
 }
 
 func (h *AIRepository) SummarizeProgress(description, slackMessages string) (string, error) {
@@ -149,13 +308,13 @@ Slack投稿用として3000文字以内で、関係者向けの報告として
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskProgress, prompt)
 }
 
 // 高度な進捗サマリ生成（トークン制限対応・分割処理対応）
 func (h *AIRepository) SummarizeProgressAdvanced(description string, messages []slack.Message, previousSummary string) (string, error) {
 	// トークン計算機を初期化
-	tokenCalc, err := NewTokenCalculator()
+	tokenCalc, err := h.newTokenCalculator()
 	if err != nil {
 		// フォールバック: 従来の方式
 		return h.SummarizeProgress(description, h.formatMessagesSimple(messages))
@@ -172,13 +331,19 @@ func (h *AIRepository) SummarizeProgressAdvanced(description string, messages []
 	// トークン数をチェック
 	totalTokens := tokenCalc.CountMessagesTokens(messages, basePrompt)
 
-	// トークン制限内の場合は一度に処理
+	var summary string
 	if totalTokens <= GetMaxTokens() {
-		return h.processSingleChunk(basePrompt, messages, tokenCalc)
+		// トークン制限内の場合は一度に処理
+		summary, err = h.processSingleChunk(basePrompt, messages, tokenCalc)
+	} else {
+		// トークン制限を超える場合は分割処理
+		summary, err = h.processMultipleChunks(basePrompt, messages, tokenCalc)
 	}
-
-	// トークン制限を超える場合は分割処理
-	return h.processMultipleChunks(basePrompt, messages, tokenCalc)
+	if err != nil {
+		return "", err
+	}
+	// メッセージの内容は伏せて送っているため、戻してよい値は元のメッセージから戻す
+	return h.redactor.Restore(summary, messageTexts(messages)...), nil
 }
 
 // 増分更新用プロンプト作成
@@ -275,7 +440,7 @@ func (h *AIRepository) processSingleChunk(basePrompt string, messages []slack.Me
 	}
 
 	fullPrompt := basePrompt + "\n" + messageText.String()
-	return h.callOpenAIWithRetry(fullPrompt)
+	return h.callWithRetry(AITaskProgress, fullPrompt)
 }
 
 // 複数チャンクでの分割処理
@@ -305,28 +470,29 @@ func (h *AIRepository) processMultipleChunks(basePrompt string, messages []slack
 
 	// 部分サマリを統合
 	mergePrompt := tokenCalc.CreateMergePrompt(partialSummaries)
-	return h.callOpenAIWithRetryWithErrorHandling(mergePrompt)
+	return h.callWithRetryWithErrorHandling(AITaskProgress, mergePrompt)
 }
 
 // メッセージを簡単な文字列に変換（フォールバック用）
 func (h *AIRepository) formatMessagesSimple(messages []slack.Message) string {
 	var builder strings.Builder
 	for _, msg := range messages {
-		builder.WriteString(fmt.Sprintf("%s: %s\n", msg.User, msg.Text))
+		builder.WriteString(fmt.Sprintf("%s: %s\n", msg.User, h.redactor.Redact(msg.Text)))
 	}
 	return builder.String()
 }
 
-// エラーハンドリング強化版のOpenAI呼び出し
-func (h *AIRepository) callOpenAIWithRetryWithErrorHandling(prompt string) (string, error) {
+// エラーハンドリング強化版のAI呼び出し
+func (h *AIRepository) callWithRetryWithErrorHandling(task, prompt string) (string, error) {
+	provider, model, err := h.route(task)
+	if err != nil {
+		return "", err
+	}
+	// 呼び出し元で伏せていない内容も、送る前に伏せる
+	redacted := h.redactor.Redact(prompt)
 	var result string
-	err := retry.Retry(3, time.Second*3, func() error {
-		resp, err := h.client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
-			Messages: []openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			},
-			Model: h.model,
-		})
+	err = retry.Retry(3, time.Second*3, func() error {
+		res, err := provider.Complete(context.Background(), model, redacted)
 		if err != nil {
 			// トークン超過エラーの特別処理
 			if strings.Contains(err.Error(), "token") || strings.Contains(err.Error(), "length") {
@@ -334,16 +500,11 @@ func (h *AIRepository) callOpenAIWithRetryWithErrorHandling(prompt string) (stri
 			}
 			return err
 		}
-
-		if len(resp.Choices) == 0 {
-			return fmt.Errorf("no response from OpenAI")
-		}
-
-		result = resp.Choices[0].Message.Content
+		result = res
 		return nil
 	})
 
-	return result, err
+	return h.redactor.Restore(result, prompt), err
 }
 
 func (h *AIRepository) GenerateTitle(description, slackMessages string) (string, error) {
@@ -361,32 +522,28 @@ func (h *AIRepository) GenerateTitle(description, slackMessages string) (string,
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskTitle, prompt)
 }
 
-// 共通のリトライ機能付きOpenAI API呼び出し
-func (h *AIRepository) callOpenAIWithRetry(prompt string) (string, error) {
+// 共通のリトライ機能付きAI呼び出し
+func (h *AIRepository) callWithRetry(task, prompt string) (string, error) {
+	provider, model, err := h.route(task)
+	if err != nil {
+		return "", err
+	}
+	// 呼び出し元で伏せていない内容も、送る前に伏せる
+	redacted := h.redactor.Redact(prompt)
 	var result string
-	err := retry.Retry(3, time.Second*3, func() error {
-		resp, err := h.client.Chat.Completions.New(context.Background(), openai.ChatCompletionNewParams{
-			Messages: []openai.ChatCompletionMessageParamUnion{
-				openai.UserMessage(prompt),
-			},
-			Model: h.model,
-		})
+	err = retry.Retry(3, time.Second*3, func() error {
+		res, err := provider.Complete(context.Background(), model, redacted)
 		if err != nil {
 			return err
 		}
-
-		if len(resp.Choices) == 0 {
-			return fmt.Errorf("no response from OpenAI")
-		}
-
-		result = resp.Choices[0].Message.Content
+		result = res
 		return nil
 	})
 
-	return result, err
+	return h.redactor.Restore(result, prompt), err
 }
 
 // ステータス生成（解決済み/未解決/クローズ）
@@ -414,7 +571,7 @@ func (h *AIRepository) GenerateStatus(description, slackMessages string) (string
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskStatus, prompt)
 }
 
 // 影響分析生成
@@ -439,7 +596,7 @@ func (h *AIRepository) GenerateImpact(description, slackMessages string) (string
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskImpact, prompt)
 }
 
 // 根本原因分析生成
@@ -463,7 +620,7 @@ func (h *AIRepository) GenerateRootCause(description, slackMessages string) (str
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskRootCause, prompt)
 }
 
 // トリガー分析生成（障害発見の経緯）
@@ -488,7 +645,7 @@ func (h *AIRepository) GenerateTrigger(description, slackMessages string) (strin
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskTrigger, prompt)
 }
 
 // 解決策生成
@@ -513,7 +670,7 @@ func (h *AIRepository) GenerateSolution(description, slackMessages string) (stri
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskSolution, prompt)
 }
 
 // アクションアイテム生成
@@ -539,7 +696,7 @@ func (h *AIRepository) GenerateActionItems(description, slackMessages string) (s
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskActionItems, prompt)
 }
 
 // 学んだ教訓生成（3つのセクション）
@@ -604,17 +761,17 @@ func (h *AIRepository) GenerateLessonsLearned(description, slackMessages string)
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	good, err := h.callOpenAIWithRetry(goodPrompt)
+	good, err := h.callWithRetry(AITaskLessons, goodPrompt)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to generate good lessons: %w", err)
 	}
 
-	bad, err := h.callOpenAIWithRetry(badPrompt)
+	bad, err := h.callWithRetry(AITaskLessons, badPrompt)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to generate bad lessons: %w", err)
 	}
 
-	lucky, err := h.callOpenAIWithRetry(luckyPrompt)
+	lucky, err := h.callWithRetry(AITaskLessons, luckyPrompt)
 	if err != nil {
 		return "", "", "", fmt.Errorf("failed to generate lucky lessons: %w", err)
 	}
@@ -645,7 +802,7 @@ func (h *AIRepository) FormatTimeline(rawTimeline string) (string, error) {
 ## 生のタイムライン
 %s`, rawTimeline)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskTimeline, prompt)
 }
 
 // 残件分析
@@ -673,12 +830,217 @@ func (h *AIRepository) AnalyzeRemainingTasks(description, slackMessages string)
 ## 関連するSlackのメッセージ
 %s`, description, slackMessages)
 
-	return h.callOpenAIWithRetry(prompt)
+	return h.callWithRetry(AITaskRemainingTasks, prompt)
+}
+
+// SeveritySuggestion は AI が提案したインシデントレベルと緊急度
+type SeveritySuggestion struct {
+	Level   int    `json:"level"`
+	Urgency string `json:"urgency"`
+	// 提案の根拠
+	Reason string `json:"reason"`
+}
+
+// jsonObjectPattern は AI の応答からコードブロックなどを除いた JSON オブジェクトを取り出す
+var jsonObjectPattern = regexp.MustCompile(`(?s)\{.*\}`)
+
+// インシデントレベルと緊急度の提案
+func (h *AIRepository) SuggestSeverity(description, slackMessages string, levels []entity.IncidentLevel, urgencies map[string]string) (*SeveritySuggestion, error) {
+	var levelList strings.Builder
+	levelList.WriteString("- 0: サービス影響なし\n")
+	for _, l := range levels {
+		if l.Level != 0 {
+			levelList.WriteString(fmt.Sprintf("- %d: %s\n", l.Level, l.Description))
+		}
+	}
+	var urgencyList strings.Builder
+	for _, key := range slices.Sorted(maps.Keys(urgencies)) {
+		urgencyList.WriteString(fmt.Sprintf("- %s: %s\n", key, urgencies[key]))
+	}
+
+	prompt := fmt.Sprintf(`## 依頼内容
+インシデントの影響範囲と深刻さを判断し、インシデントレベルと緊急度を選択肢の中から1つずつ提案してください。
+あなたには人間が考えた事象の概要と、Slackのメッセージが与えられます。
+
+## フォーマットの指定：
+以下の形式のJSONだけを返却してください。reason には判断の根拠を200文字以内で記載してください。
+{"level": 1, "urgency": "error", "reason": "..."}
+
+## 重要な指示：
+- **Slackメッセージから影響が確認できない場合は、推測で深刻な値を選ばないでください**
+- level と urgency は選択肢の値だけを使ってください
+
+## インシデントレベルの選択肢
+%s
+## 緊急度の選択肢
+%s
+## 人間が考えた事象の概要
+%s
+
+## 関連するSlackのメッセージ
+%s`, levelList.String(), urgencyList.String(), description, slackMessages)
+
+	res, err := h.callWithRetry(AITaskSeverity, prompt)
+	if err != nil {
+		return nil, err
+	}
+	var suggestion SeveritySuggestion
+	if err := json.Unmarshal([]byte(jsonObjectPattern.FindString(res)), &suggestion); err != nil {
+		return nil, fmt.Errorf("failed to parse severity suggestion %q: %w", res, err)
+	}
+	if suggestion.Level != 0 && !slices.ContainsFunc(levels, func(l entity.IncidentLevel) bool { return l.Level == suggestion.Level }) {
+		return nil, fmt.Errorf("unknown incident level suggested: %d", suggestion.Level)
+	}
+	if _, ok := urgencies[suggestion.Urgency]; !ok {
+		return nil, fmt.Errorf("unknown urgency suggested: %s", suggestion.Urgency)
+	}
+	return &suggestion, nil
+}
+
+// communicationTones は読み手ごとの連絡の書き方
+var communicationTones = map[string]string{
+	entity.AudienceExecutive: `経営層向けの状況報告を作成してください。
+- 冒頭の1文で、何が起きていて現在どういう状態かを伝えてください
+- ビジネスや顧客への影響の大きさ、復旧の見通し、判断や支援が必要な事項を優先してください
+- 技術的な詳細や専門用語は省き、300文字以内で簡潔にまとめてください`,
+	entity.AudienceCustomer: `カスタマーサポートが顧客にそのまま案内できる障害情報を作成してください。
+- 丁寧な敬語で、ご不便をおかけしていることへのお詫びを含めてください
+- 発生日時、影響を受けている機能、現在の状況、次回の案内の目安を記載してください
+- 社内のシステム名、担当者名、原因の推測、社内の対応の詳細は含めないでください
+- 確認できていない復旧時刻は約束しないでください`,
+	entity.AudienceEngineering: `社内のエンジニア向けの状況共有を作成してください。
+- 症状、影響を受けているコンポーネント、原因の仮説、実施した対応、次のアクションを記載してください
+- 他チームへの協力依頼や、作業を控えてほしいことがあれば明記してください
+- 箇条書きで簡潔にまとめてください`,
+}
+
+// 関係者向けの連絡の下書き
+func (h *AIRepository) DraftCommunication(audience, description, summary, slackMessages string) (string, error) {
+	tone, ok := communicationTones[audience]
+	if !ok {
+		return "", fmt.Errorf("unknown audience: %s", audience)
+	}
+	prompt := fmt.Sprintf(`## 依頼内容
+%s
+あなたには、人間が考えた事象の概要と、最新の進捗サマリ、Slackのメッセージが与えられます。
+
+## 重要な指示：
+- **Slackメッセージから確認できない事実を推測で書かないでください**
+- 投稿する前に人間が編集するので、連絡の本文だけを返却してください
+- Slackに投稿するため、見出しにはマークダウンの # を使わないでください
+
+## 人間が考えた事象の概要
+%s
+
+## 最新の進捗サマリ
+%s
+
+## 関連するSlackのメッセージ
+%s`, tone, description, summary, slackMessages)
+
+	return h.callWithRetry(AITaskCommunication, prompt)
+}
+
+// 質問への回答（トークン制限対応・分割処理対応）
+func (h *AIRepository) AnswerQuestion(description, question string, messages []slack.Message) (string, error) {
+	tokenCalc, err := h.newTokenCalculator()
+	if err != nil {
+		// フォールバック: 文字数からトークン数を見積もる
+		tokenCalc = &TokenCalculator{}
+		tokenCalc.SetRedactor(h.redactor)
+	}
+
+	// 分割しても引用の番号が変わらないよう、発言の番号は全体の順番で振る
+	numbers := make(map[string]int, len(messages))
+	for i, msg := range messages {
+		numbers[msg.Timestamp] = i + 1
+	}
+	format := func(chunk []slack.Message) string {
+		var builder strings.Builder
+		for _, msg := range chunk {
+			builder.WriteString(fmt.Sprintf("[%d] %s\n", numbers[msg.Timestamp], tokenCalc.FormatMessage(msg)))
+		}
+		return builder.String()
+	}
+
+	basePrompt := h.createAnswerPrompt(description, question)
+	var answer string
+	if tokenCalc.CountMessagesTokens(messages, basePrompt) <= GetMaxTokens() {
+		answer, err = h.callWithRetry(AITaskAnswer, basePrompt+"\n"+format(messages))
+	} else {
+		answer, err = h.answerFromChunks(basePrompt, question, messages, tokenCalc, format)
+	}
+	if err != nil {
+		return "", err
+	}
+	// メッセージの内容は伏せて送っているため、戻してよい値は元のメッセージから戻す
+	return h.redactor.Restore(answer, messageTexts(messages)...), nil
+}
+
+// 回答用プロンプト作成
+func (h *AIRepository) createAnswerPrompt(description, question string) string {
+	return fmt.Sprintf(`## 依頼内容
+インシデント対応チャンネルに途中から参加した人の質問に答えてください。
+あなたには人間が考えた事象の概要と、番号付きのSlackのメッセージが与えられます。
+
+## フォーマットの指定：
+Slack投稿用として1000文字以内で、質問への回答だけを記載してください。
+根拠にしたメッセージは、文末に [3] のようにメッセージの番号で引用してください。
+
+## 重要な指示：
+- **Slackメッセージに明確に記載されていない情報は推測せず、「メッセージからは確認できませんでした」と回答してください**
+- 引用する番号は与えられたメッセージの番号だけを使ってください
+
+## 人間が考えた事象の概要
+%s
+
+## 質問
+%s
+
+## 関連するSlackのメッセージ`, description, question)
+}
+
+// 複数チャンクから質問に関係する情報を抜き出して回答する
+func (h *AIRepository) answerFromChunks(basePrompt, question string, messages []slack.Message, tokenCalc *TokenCalculator, format func([]slack.Message) string) (string, error) {
+	notePrompt := fmt.Sprintf(`## 依頼内容
+以下のSlackのメッセージから、質問に答えるために必要な情報だけを箇条書きで抜き出してください。
+各項目の文末には、根拠にしたメッセージの番号を [3] のように付けてください。
+関係する情報が無い場合は「なし」とだけ返却してください。
+
+## 質問
+%s
+
+## Slackメッセージ`, question)
+
+	// 時系列を保ったまま分割する
+	chunks := tokenCalc.SplitMessages(messages, notePrompt, GetMaxTokens())
+	if len(chunks) == 0 {
+		return "", fmt.Errorf("no messages to process")
+	}
+
+	var notes []string
+	for i, chunk := range chunks {
+		chunkPrompt := fmt.Sprintf("%s\n\n## 部分 %d/%d のメッセージ\n%s", notePrompt, i+1, len(chunks), format(chunk))
+		note, err := h.callWithRetry(AITaskAnswer, chunkPrompt)
+		if err != nil {
+			return "", fmt.Errorf("failed to process chunk %d: %w", i+1, err)
+		}
+		notes = append(notes, note)
+	}
+
+	// 抜き出した情報から回答する
+	var builder strings.Builder
+	builder.WriteString(basePrompt)
+	builder.WriteString("から抜き出した情報\n")
+	for i, note := range notes {
+		builder.WriteString(fmt.Sprintf("### 部分 %d\n%s\n\n", i+1, note))
+	}
+	return h.callWithRetryWithErrorHandling(AITaskAnswer, builder.String())
 }
 
 // ポストモーテム用のメッセージ前処理（トークン制限対応）
 func (h *AIRepository) PrepareMessagesForPostMortem(messages []slack.Message, description string) (string, error) {
-	tokenCalc, err := NewTokenCalculator()
+	tokenCalc, err := h.newTokenCalculator()
 	if err != nil {
 		return h.formatMessagesSimple(messages), nil
 	}
@@ -755,7 +1117,7 @@ func (h *AIRepository) summarizeSingleChunk(basePrompt string, messages []slack.
 	}
 
 	fullPrompt := basePrompt + "\n" + messageText.String()
-	return h.callOpenAIWithRetry(fullPrompt)
+	return h.callWithRetry(AITaskDigest, fullPrompt)
 }
 
 // ポストモーテム用要約の統合
@@ -777,5 +1139,5 @@ func (h *AIRepository) mergePostMortemSummaries(summaries []string) (string, err
 		builder.WriteString(fmt.Sprintf("## 部分要約 %d\n%s\n\n", i+1, summary))
 	}
 
-	return h.callOpenAIWithRetryWithErrorHandling(builder.String())
+	return h.callWithRetryWithErrorHandling(AITaskDigest, builder.String())
 }
@@ -0,0 +1,294 @@
+package repository_test
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+)
+
+// fakeLLM は OpenAI 互換 API と Anthropic の Messages API を模したサーバー
+// 受け取ったモデル名をそのまま応答に含める
+type fakeLLM struct {
+	mu      sync.Mutex
+	models  []string
+	prompts []string
+	header  http.Header
+	// 空でない場合は OpenAI 互換の API の応答をこの内容にする
+	reply string
+}
+
+func (f *fakeLLM) record(r *http.Request) string {
+	var body struct {
+		Model    string `json:"model"`
+		Messages []struct {
+			Content string `json:"content"`
+		} `json:"messages"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.models = append(f.models, body.Model)
+	for _, m := range body.Messages {
+		f.prompts = append(f.prompts, m.Content)
+	}
+	f.header = r.Header.Clone()
+	return body.Model
+}
+
+func (f *fakeLLM) openAIServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		model := f.record(r)
+		content, _ := json.Marshal(cmp.Or(f.reply, "openai:"+model))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","object":"chat.completion","created":0,"model":%q,"choices":[{"index":0,"finish_reason":"stop","message":{"role":"assistant","content":%s}}]}`, model, content)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func (f *fakeLLM) anthropicServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		model := f.record(r)
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"id":"1","type":"message","role":"assistant","content":[{"type":"text","text":"anthropic:%s"}]}`, model)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAIRepository_Providers(t *testing.T) {
+	t.Setenv("TEST_ANTHROPIC_KEY", "secret")
+	local, anthropic := &fakeLLM{}, &fakeLLM{}
+
+	ai, err := repository.NewAIRepository(entity.AIConfig{
+		Provider: "local",
+		Model:    "small",
+		Providers: map[string]entity.AIProviderConfig{
+			"local":  {Type: entity.AIProviderOpenAICompatible, BaseURL: local.openAIServer(t).URL + "/v1"},
+			"claude": {Type: entity.AIProviderAnthropic, BaseURL: anthropic.anthropicServer(t).URL, APIKeyEnv: "TEST_ANTHROPIC_KEY", Model: "large"},
+		},
+		Tasks: map[string]entity.AITaskConfig{
+			repository.AITaskTitle:     {Model: "tiny"},
+			repository.AITaskRootCause: {Provider: "claude"},
+		},
+	})
+	require.NoError(t, err)
+
+	title, err := ai.GenerateTitle("APIが落ちた", "")
+	require.NoError(t, err)
+	assert.Equal(t, "openai:tiny", title)
+
+	summary, err := ai.Summarize("APIが落ちた", "")
+	require.NoError(t, err)
+	assert.Equal(t, "openai:small", summary)
+
+	rootCause, err := ai.GenerateRootCause("APIが落ちた", "")
+	require.NoError(t, err)
+	assert.Equal(t, "anthropic:large", rootCause)
+	assert.Equal(t, "secret", anthropic.header.Get("x-api-key"))
+	assert.NotEmpty(t, anthropic.header.Get("anthropic-version"))
+
+	assert.Equal(t, []string{"tiny", "small"}, local.models)
+	assert.Equal(t, []string{"large"}, anthropic.models)
+}
+
+func TestNewAIRepository(t *testing.T) {
+	t.Setenv("OPENAI_API_KEY", "")
+	t.Setenv("AZURE_OPENAI_KEY", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	ai, err := repository.NewAIRepository(entity.AIConfig{})
+	require.NoError(t, err)
+	assert.Nil(t, ai, "設定が無ければ AI を使わない")
+
+	_, err = repository.NewAIRepository(entity.AIConfig{
+		Providers: map[string]entity.AIProviderConfig{"claude": {Type: entity.AIProviderAnthropic, Model: "large"}},
+	})
+	assert.ErrorContains(t, err, "ANTHROPIC_API_KEY is not set")
+
+	_, err = repository.NewAIRepository(entity.AIConfig{
+		Providers: map[string]entity.AIProviderConfig{"local": {Type: entity.AIProviderOpenAICompatible, BaseURL: "http://localhost:11434/v1"}},
+	})
+	assert.ErrorContains(t, err, "model for task")
+
+	_, err = repository.NewAIRepository(entity.AIConfig{
+		Model:     "small",
+		Providers: map[string]entity.AIProviderConfig{"local": {Type: entity.AIProviderOpenAICompatible, BaseURL: "http://localhost:11434/v1"}},
+		Tasks:     map[string]entity.AITaskConfig{repository.AITaskTitle: {Provider: "missing"}},
+	})
+	assert.ErrorContains(t, err, `provider "missing"`)
+
+	t.Setenv("OPENAI_API_KEY", "sk-test")
+	ai, err = repository.NewAIRepository(entity.AIConfig{})
+	require.NoError(t, err)
+	assert.NotNil(t, ai, "環境変数の OpenAI を使う")
+}
+
+func TestAIRepository_AnswerQuestion(t *testing.T) {
+	local := &fakeLLM{}
+	ai, err := repository.NewAIRepository(entity.AIConfig{
+		Provider:  "local",
+		Model:     "small",
+		Providers: map[string]entity.AIProviderConfig{"local": {Type: entity.AIProviderOpenAICompatible, BaseURL: local.openAIServer(t).URL + "/v1"}},
+	})
+	require.NoError(t, err)
+
+	messages := []slack.Message{
+		{Msg: slack.Msg{User: "U1", Text: "5xxが増えています", Timestamp: "100.000001"}},
+		{Msg: slack.Msg{User: "U2", Text: "DBを再起動しました", Timestamp: "200.000002"}},
+		{Msg: slack.Msg{User: "U3", Text: "ロールバックを検討中", Timestamp: "300.000003"}},
+	}
+
+	t.Run("発言に番号を振って一度に送る", func(t *testing.T) {
+		local.prompts = nil
+		answer, err := ai.AnswerQuestion("APIが落ちた", "何を試しましたか？", messages)
+		require.NoError(t, err)
+		assert.Equal(t, "openai:small", answer)
+		require.Len(t, local.prompts, 1)
+		assert.Contains(t, local.prompts[0], "何を試しましたか？")
+		assert.Contains(t, local.prompts[0], "[2] ")
+		assert.Contains(t, local.prompts[0], "DBを再起動しました")
+	})
+
+	t.Run("トークン制限を超える場合は分割しても同じ番号を使う", func(t *testing.T) {
+		t.Setenv("MAX_TOKENS", "110")
+		local.prompts = nil
+		_, err := ai.AnswerQuestion("APIが落ちた", "何を試しましたか？", messages)
+		require.NoError(t, err)
+		require.Greater(t, len(local.prompts), 2)
+
+		chunks, final := local.prompts[:len(local.prompts)-1], local.prompts[len(local.prompts)-1]
+		var joined string
+		for _, p := range chunks {
+			joined += p
+		}
+		assert.Contains(t, joined, "[1] ")
+		assert.Contains(t, joined, "[3] ")
+		assert.Contains(t, final, "抜き出した情報")
+	})
+}
+
+func TestAIRepository_Embed(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "secret")
+	var inputs []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/embeddings", r.URL.Path)
+		var body struct {
+			Model string   `json:"model"`
+			Input []string `json:"input"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		assert.Equal(t, "text-embedding-3-small", body.Model, "モデルを指定しない場合は埋め込み用の既定のモデルを使う")
+		inputs = body.Input
+		w.Header().Set("Content-Type", "application/json")
+		// 順序が入れ替わっていても index の順に返す
+		fmt.Fprint(w, `{"object":"list","model":"text-embedding-3-small","data":[{"object":"embedding","index":1,"embedding":[0,1]},{"object":"embedding","index":0,"embedding":[1,0]}],"usage":{"prompt_tokens":1,"total_tokens":1}}`)
+	}))
+	t.Cleanup(srv.Close)
+
+	ai, err := repository.NewAIRepository(entity.AIConfig{
+		Provider: "local",
+		Model:    "chat",
+		Providers: map[string]entity.AIProviderConfig{
+			"local":  {Type: entity.AIProviderOpenAICompatible, BaseURL: srv.URL + "/v1"},
+			"claude": {Type: entity.AIProviderAnthropic, Model: "large"},
+		},
+	})
+	require.NoError(t, err)
+	assert.True(t, ai.SupportsEmbedding())
+
+	vectors, model, err := ai.Embed("DBが落ちた alice@example.com", "APIが遅い")
+	require.NoError(t, err)
+	assert.Equal(t, "text-embedding-3-small", model)
+	assert.Equal(t, [][]float32{{1, 0}, {0, 1}}, vectors)
+	require.Len(t, inputs, 2)
+	assert.NotContains(t, inputs[0], "alice@example.com", "埋め込みでも伏せて送る")
+
+	ai, err = repository.NewAIRepository(entity.AIConfig{
+		Provider:  "claude",
+		Providers: map[string]entity.AIProviderConfig{"claude": {Type: entity.AIProviderAnthropic, Model: "large"}},
+	})
+	require.NoError(t, err)
+	assert.False(t, ai.SupportsEmbedding(), "Anthropic は埋め込みに対応していない")
+}
+
+func TestAIRepository_SuggestSeverity(t *testing.T) {
+	levels := []entity.IncidentLevel{{Level: 1, Description: "一部に影響"}, {Level: 2, Description: "全面停止"}}
+	urgencies := map[string]string{"warning": "調査する", "critical": "緊急"}
+
+	tests := []struct {
+		name    string
+		reply   string
+		want    *repository.SeveritySuggestion
+		wantErr bool
+	}{
+		{
+			name:  "コードブロックで囲まれていても読み取る",
+			reply: "```json\n{\"level\": 2, \"urgency\": \"critical\", \"reason\": \"全面停止\"}\n```",
+			want:  &repository.SeveritySuggestion{Level: 2, Urgency: "critical", Reason: "全面停止"},
+		},
+		{
+			name:  "レベル0は常に選べる",
+			reply: `{"level": 0, "urgency": "warning", "reason": "影響なし"}`,
+			want:  &repository.SeveritySuggestion{Level: 0, Urgency: "warning", Reason: "影響なし"},
+		},
+		{name: "選択肢にないレベル", reply: `{"level": 5, "urgency": "critical", "reason": ""}`, wantErr: true},
+		{name: "選択肢にない緊急度", reply: `{"level": 1, "urgency": "panic", "reason": ""}`, wantErr: true},
+		{name: "JSONではない", reply: "レベル2です", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			llm := &fakeLLM{reply: tt.reply}
+			ai, err := repository.NewAIRepository(entity.AIConfig{
+				Provider:  "local",
+				Model:     "small",
+				Providers: map[string]entity.AIProviderConfig{"local": {Type: entity.AIProviderOpenAICompatible, BaseURL: llm.openAIServer(t).URL + "/v1"}},
+			})
+			require.NoError(t, err)
+
+			got, err := ai.SuggestSeverity("APIが落ちた", "U1: 500が返る", levels, urgencies)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+			assert.Contains(t, llm.prompts[0], "- 2: 全面停止")
+			assert.Contains(t, llm.prompts[0], "- critical: 緊急")
+		})
+	}
+}
+
+func TestAIRepository_DraftCommunication(t *testing.T) {
+	llm := &fakeLLM{reply: "ご不便をおかけしております"}
+	ai, err := repository.NewAIRepository(entity.AIConfig{
+		Provider:  "local",
+		Model:     "small",
+		Providers: map[string]entity.AIProviderConfig{"local": {Type: entity.AIProviderOpenAICompatible, BaseURL: llm.openAIServer(t).URL + "/v1"}},
+	})
+	require.NoError(t, err)
+
+	got, err := ai.DraftCommunication(entity.AudienceCustomer, "APIが落ちた", "ロールバック中", "U1: 500が返る")
+	require.NoError(t, err)
+	assert.Equal(t, "ご不便をおかけしております", got)
+	require.Len(t, llm.prompts, 1)
+	assert.Contains(t, llm.prompts[0], "丁寧な敬語")
+	assert.Contains(t, llm.prompts[0], "ロールバック中")
+
+	_, err = ai.DraftCommunication("press", "APIが落ちた", "", "")
+	assert.Error(t, err, "読み手が不明な場合は AI を呼ばない")
+	assert.Len(t, llm.prompts, 1)
+}
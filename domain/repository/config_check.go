@@ -0,0 +1,227 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/slack-go/slack"
+)
+
+// Validate は構造体の検証では検出できない設定の誤りを返す
+func (c *Config) Validate() []error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	schedules := map[string]bool{}
+	for _, s := range c.OnCallScheduleList {
+		if schedules[s.ID] {
+			errs = append(errs, fmt.Errorf("oncall_schedules: duplicate id %q", s.ID))
+		}
+		schedules[s.ID] = true
+		if err := s.Validate(); err != nil {
+			errs = append(errs, fmt.Errorf("oncall_schedules[%s]: %w", s.ID, err))
+		}
+	}
+
+	serviceIDs := map[int]bool{}
+	for _, s := range c.ServiceList {
+		if serviceIDs[s.ID] {
+			errs = append(errs, fmt.Errorf("services: duplicate id %d (%s)", s.ID, s.Name))
+		}
+		serviceIDs[s.ID] = true
+
+		if s.OnCallSchedule != "" && !schedules[s.OnCallSchedule] {
+			errs = append(errs, fmt.Errorf("services[%d]: oncall_schedule %q does not match any schedule", s.ID, s.OnCallSchedule))
+		}
+
+		if s.Confluence.Domain != "" {
+			if s.Confluence.Space == "" {
+				errs = append(errs, fmt.Errorf("services[%d]: confluence.space is required when confluence.domain is set", s.ID))
+			}
+			if s.Confluence.AncestorID == "" {
+				errs = append(errs, fmt.Errorf("services[%d]: confluence.ancestor_id is required when confluence.domain is set", s.ID))
+			}
+		}
+	}
+
+	levels := map[int]bool{}
+	for _, l := range c.IncidentLevelList {
+		if levels[l.Level] {
+			errs = append(errs, fmt.Errorf("incident_levels: duplicate level %d", l.Level))
+		}
+		levels[l.Level] = true
+	}
+
+	roles := map[string]bool{}
+	for _, r := range c.RoleList {
+		if roles[r.ID] {
+			errs = append(errs, fmt.Errorf("roles: duplicate id %q", r.ID))
+		}
+		roles[r.ID] = true
+	}
+
+	if d := c.DefaultConfluence; d.Domain != "" {
+		if d.Space == "" {
+			errs = append(errs, fmt.Errorf("default_confluence: space is required when domain is set"))
+		}
+		if d.AncestorID == "" {
+			errs = append(errs, fmt.Errorf("default_confluence: ancestor_id is required when domain is set"))
+		}
+	}
+
+	errs = append(errs, c.validateAI()...)
+
+	if id := c.Alert.DefaultServiceID; id != 0 && !serviceIDs[id] {
+		errs = append(errs, fmt.Errorf("alert: default_service_id %d does not match any service", id))
+	}
+	return errs
+}
+
+// validateAI は AI のタスクやプロバイダーの参照先が存在するかを確認する
+func (c *Config) validateAI() []error {
+	var errs []error
+	checkProvider := func(where, name string) {
+		if _, ok := c.AI.Providers[name]; name != "" && !ok {
+			errs = append(errs, fmt.Errorf("%s: provider %q is not defined in ai.providers", where, name))
+		}
+	}
+	checkProvider("ai", c.AI.Provider)
+	if c.AI.Provider == "" && len(c.AI.Providers) > 1 {
+		errs = append(errs, fmt.Errorf("ai: provider is required when multiple providers are defined"))
+	}
+	for task, t := range c.AI.Tasks {
+		if !slices.Contains(AITasks, task) && task != AITaskEmbedding {
+			errs = append(errs, fmt.Errorf("ai.tasks: unknown task %q", task))
+			continue
+		}
+		checkProvider("ai.tasks."+task, t.Provider)
+	}
+
+	r := c.AI.Redaction
+	detectors := map[string]bool{}
+	for _, p := range builtinRedactionPatterns {
+		detectors[p.Name] = true
+	}
+	for _, name := range r.DisableDetectors {
+		if !detectors[name] {
+			errs = append(errs, fmt.Errorf("ai.redaction: unknown detector %q in disable_detectors", name))
+		}
+	}
+	for i, p := range r.Patterns {
+		if _, err := regexp.Compile(p.Regex); err != nil {
+			errs = append(errs, fmt.Errorf("ai.redaction.patterns[%d]: %w", i, err))
+		}
+		detectors[p.Name] = true
+	}
+	for _, name := range r.Restore {
+		switch {
+		case slices.Contains(secretDetectors, name):
+			errs = append(errs, fmt.Errorf("ai.redaction: %q is a secret and cannot be restored", name))
+		case !detectors[name]:
+			errs = append(errs, fmt.Errorf("ai.redaction: unknown detector %q in restore", name))
+		}
+	}
+	return errs
+}
+
+// Lint は動作はするが意図していない可能性が高い設定を返す
+func (c *Config) Lint() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var warnings []string
+	names := map[string]int{}
+	for _, s := range c.ServiceList {
+		if s.Disabled {
+			continue
+		}
+		if len(s.IncidentTeamMembers) == 0 && s.OnCallSchedule == "" {
+			warnings = append(warnings, fmt.Sprintf("services[%d]: incident_team_members is empty, nobody will be invited", s.ID))
+		}
+		if len(s.AnnouncementChannels) == 0 && len(c.GlobalAnnouncementChannels) == 0 {
+			warnings = append(warnings, fmt.Sprintf("services[%d]: no announcement channels, incidents will not be announced", s.ID))
+		}
+		if e := s.Escalation; (e.NoHandlerMinutes > 0 || e.SilentMinutes > 0) && len(e.UserGroups) == 0 {
+			warnings = append(warnings, fmt.Sprintf("services[%d]: escalation.user_groups is empty, escalation is disabled", s.ID))
+		}
+		// アラートのラベルはサービス名で照合するため、大文字小文字違いの重複は区別できない
+		key := strings.ToLower(s.Name)
+		if id, ok := names[key]; ok {
+			warnings = append(warnings, fmt.Sprintf("services[%d]: name %q is also used by services[%d]", s.ID, s.Name, id))
+		}
+		names[key] = s.ID
+	}
+	return warnings
+}
+
+// slackResolver は ValidateSlack が名前解決に使う SlackRepositoryer の一部
+type slackResolver interface {
+	GetMemberIDs(name string) ([]string, error)
+	GetChannelByName(name string) (*slack.Channel, error)
+}
+
+// ValidateSlack は incident_team_members、オンコールの担当者、アナウンスチャンネル、関係者向けの連絡の投稿先が Slack 上に存在するかを確認する
+func (c *Config) ValidateSlack(_ context.Context, s slackResolver) []error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var errs []error
+	checkChannel := func(where, name string) {
+		ch, err := s.GetChannelByName(name)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: failed to resolve channel %q: %w", where, name, err))
+			return
+		}
+		if ch == nil {
+			errs = append(errs, fmt.Errorf("%s: channel %q not found", where, name))
+		}
+	}
+
+	checkMember := func(where, field, name string) {
+		if _, err := s.GetMemberIDs(name); err != nil {
+			if err == ErrSlackNotFound {
+				errs = append(errs, fmt.Errorf("%s: %s %q not found", where, field, name))
+				return
+			}
+			errs = append(errs, fmt.Errorf("%s: failed to resolve member %q: %w", where, name, err))
+		}
+	}
+
+	for _, name := range c.GlobalAnnouncementChannels {
+		checkChannel("global_announcement_channels", name)
+	}
+	for _, audience := range entity.Audiences {
+		for _, name := range c.Communication.Channels(audience) {
+			checkChannel("communication."+audience, name)
+		}
+	}
+	for _, schedule := range c.OnCallScheduleList {
+		where := fmt.Sprintf("oncall_schedules[%s]", schedule.ID)
+		for _, member := range schedule.Members {
+			checkMember(where, "members", member)
+		}
+		for _, o := range schedule.Overrides {
+			checkMember(where, "overrides.member", o.Member)
+		}
+	}
+	for _, svc := range c.ServiceList {
+		where := fmt.Sprintf("services[%d]", svc.ID)
+		for _, member := range svc.IncidentTeamMembers {
+			checkMember(where, "incident_team_members", member)
+		}
+		for _, name := range svc.AnnouncementChannels {
+			checkChannel(where+".announcement_channels", name)
+		}
+		for _, audience := range entity.Audiences {
+			for _, name := range svc.Communication.Channels(audience) {
+				checkChannel(where+".communication."+audience, name)
+			}
+		}
+	}
+	return errs
+}
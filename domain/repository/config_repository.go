@@ -2,39 +2,97 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
+	"path/filepath"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/fsnotify/fsnotify"
 	"github.com/go-playground/validator/v10"
 	"github.com/pyama86/YAS3/domain/entity"
 	"github.com/spf13/viper"
 )
 
 func NewConfigRepository(path string) (*Config, error) {
-	viper.SetConfigFile(path)
+	v := viper.New()
+	v.SetConfigFile(path)
 
-	viper.AutomaticEnv()
+	v.AutomaticEnv()
 
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 
-	err := viper.ReadInConfig()
+	err := v.ReadInConfig()
 	if err != nil {
 		return nil, fmt.Errorf("read config error: %w", err)
 	}
 
+	c, err := decodeConfig(v)
+	if err != nil {
+		return nil, err
+	}
+	c.v = v
+	return c, nil
+}
+
+func decodeConfig(v *viper.Viper) (*Config, error) {
 	var c Config
-	err = viper.Unmarshal(&c)
+	err := v.Unmarshal(&c)
 	if err != nil {
 		return nil, fmt.Errorf("unmarshal config error: %w", err)
 	}
+	if c.OnCallFile != "" {
+		schedules, err := readOnCallFile(configRelativePath(v, c.OnCallFile))
+		if err != nil {
+			return nil, err
+		}
+		c.OnCallScheduleList = append(c.OnCallScheduleList, schedules...)
+	}
 	valid := validator.New()
-	if err = valid.Struct(c); err != nil {
+	if err = valid.Struct(&c); err != nil {
 		return nil, fmt.Errorf("validate config error: %w", err)
 	}
-
+	// 一覧の要素は dive を付けると level の required など既存のタグまで検証されるため、間隔の設定だけを個別に検証する
+	for _, s := range c.ServiceList {
+		if err = valid.Struct(s.AutoSummary); err != nil {
+			return nil, fmt.Errorf("validate config error: services[%d].auto_summary: %w", s.ID, err)
+		}
+	}
+	for _, l := range c.IncidentLevelList {
+		if err = valid.Struct(l.Timekeeper); err != nil {
+			return nil, fmt.Errorf("validate config error: incident_levels[%d].timekeeper: %w", l.Level, err)
+		}
+		if err = valid.Struct(l.AutoSummary); err != nil {
+			return nil, fmt.Errorf("validate config error: incident_levels[%d].auto_summary: %w", l.Level, err)
+		}
+	}
 	return &c, nil
 }
 
+// configRelativePath は設定ファイルに書かれた相対パス(oncall_file やポストモーテムのテンプレートなど)を、設定ファイルのディレクトリからのパスとして解決する
+func configRelativePath(v *viper.Viper, path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(v.ConfigFileUsed()), path)
+}
+
+// readOnCallFile は別ファイルに書かれたオンコールのスケジュールを読み込む
+func readOnCallFile(path string) ([]entity.OnCallSchedule, error) {
+	v := viper.New()
+	v.SetConfigFile(path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read oncall file error: %w", err)
+	}
+	var schedules []entity.OnCallSchedule
+	if err := v.UnmarshalKey("oncall_schedules", &schedules); err != nil {
+		return nil, fmt.Errorf("unmarshal oncall file error: %w", err)
+	}
+	return schedules, nil
+}
+
 type Config struct {
 	ServiceList                []entity.Service        `mapstructure:"services" validate:"required"`
 	GlobalAnnouncementChannels []string                `mapstructure:"global_announcement_channels"`
@@ -42,9 +100,77 @@ type Config struct {
 	IncidentLevelList          []entity.IncidentLevel  `mapstructure:"incident_levels" validate:"required"`
 	DefaultConfluence          entity.ConfluenceConfig `mapstructure:"default_confluence"`
 	NotificationType           string                  `mapstructure:"notification_type" validate:"omitempty,oneof=none here channel"`
+	Alert                      entity.AlertConfig      `mapstructure:"alert"`
+	Timekeeper                 entity.TimekeeperConfig `mapstructure:"timekeeper"`
+	RoleList                   []entity.IncidentRole   `mapstructure:"roles" validate:"dive"`
+	OnCallScheduleList         []entity.OnCallSchedule `mapstructure:"oncall_schedules" validate:"dive"`
+	// oncall_schedules を別ファイルで管理する場合のパス。相対パスは設定ファイルのディレクトリから解決する
+	OnCallFile string `mapstructure:"oncall_file"`
+	// ポストモーテムのテンプレートファイルのパス。サービスごとの設定が優先される
+	PostmortemTemplate string `mapstructure:"postmortem_template"`
+	// 関係者向けの連絡を投稿するチャンネル。サービスごとの設定と合わせて投稿する
+	Communication entity.CommunicationChannels `mapstructure:"communication"`
+	// AI の呼び出し先。起動時にのみ読み込む
+	AI entity.AIConfig `mapstructure:"ai"`
+
+	v  *viper.Viper
+	mu sync.RWMutex
+}
+
+// Watch は設定ファイルの変更を監視して Reload する
+// 不正な設定はログに出して、それまでの設定を使い続ける
+func (c *Config) Watch() {
+	reload := func(e fsnotify.Event) {
+		if err := c.Reload(); err != nil {
+			slog.Error("Failed to reload config", slog.String("file", e.Name), slog.Any("error", err))
+			return
+		}
+		slog.Info("Config reloaded", slog.String("file", e.Name))
+	}
+	c.v.OnConfigChange(reload)
+	c.v.WatchConfig()
+
+	// オンコールの交代やオーバーライドは頻繁に更新されるため、別ファイルも監視する
+	if c.OnCallFile != "" {
+		w := viper.New()
+		w.SetConfigFile(configRelativePath(c.v, c.OnCallFile))
+		w.OnConfigChange(reload)
+		w.WatchConfig()
+	}
+}
+
+// Reload は設定ファイルを読み直し、構造体の検証と Validate に通った場合のみ
+// サービス、インシデントレベル、全体告知チャンネル、チェックポイントの間隔、役割、オンコールのスケジュール、
+// ポストモーテムのテンプレート、関係者向けの連絡の投稿先を差し替える
+func (c *Config) Reload() error {
+	if err := c.v.ReadInConfig(); err != nil {
+		return fmt.Errorf("read config error: %w", err)
+	}
+	next, err := decodeConfig(c.v)
+	if err != nil {
+		return err
+	}
+	// yas3 config validate で不正とされる設定も反映しない
+	if errs := next.Validate(); len(errs) > 0 {
+		return fmt.Errorf("validate config error: %w", errors.Join(errs...))
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.ServiceList = next.ServiceList
+	c.IncidentLevelList = next.IncidentLevelList
+	c.GlobalAnnouncementChannels = next.GlobalAnnouncementChannels
+	c.Timekeeper = next.Timekeeper
+	c.RoleList = next.RoleList
+	c.OnCallScheduleList = next.OnCallScheduleList
+	c.PostmortemTemplate = next.PostmortemTemplate
+	c.Communication = next.Communication
+	return nil
 }
 
 func (c *Config) Services(_ context.Context) ([]entity.Service, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	var services []entity.Service
 	for _, service := range c.ServiceList {
 		if service.Disabled {
@@ -56,6 +182,8 @@ func (c *Config) Services(_ context.Context) ([]entity.Service, error) {
 }
 
 func (c *Config) ServiceByID(_ context.Context, id int) (*entity.Service, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	for _, service := range c.ServiceList {
 		if service.ID == id {
 			return &service, nil
@@ -65,6 +193,8 @@ func (c *Config) ServiceByID(_ context.Context, id int) (*entity.Service, error)
 }
 
 func (c *Config) IncidentLevels(_ context.Context) []entity.IncidentLevel {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	var levels []entity.IncidentLevel
 	for _, level := range c.IncidentLevelList {
 		if level.Disabled {
@@ -77,6 +207,8 @@ func (c *Config) IncidentLevels(_ context.Context) []entity.IncidentLevel {
 
 // インシデントレベルをIDで取得
 func (c *Config) IncidentLevelByLevel(_ context.Context, id int) (*entity.IncidentLevel, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	if id == 0 {
 		return &entity.IncidentLevel{
 			Level:       0,
@@ -91,10 +223,94 @@ func (c *Config) IncidentLevelByLevel(_ context.Context, id int) (*entity.Incide
 	return nil, fmt.Errorf("incident level not found")
 }
 
+// TimekeeperSchedule はインシデントのレベルと緊急度に応じたチェックポイントの間隔を返す
+// レベル、緊急度、全体の順に設定されているものを使う
+func (c *Config) TimekeeperSchedule(_ context.Context, level int, urgency string) entity.TimekeeperSchedule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range c.IncidentLevelList {
+		if l.Level == level && !l.Timekeeper.IsZero() {
+			return l.Timekeeper
+		}
+	}
+	if s, ok := c.Timekeeper.Urgencies[urgency]; ok && !s.IsZero() {
+		return s
+	}
+	return c.Timekeeper.TimekeeperSchedule
+}
+
+// AutoSummarySchedule はインシデントのサービスとレベルに応じた進捗サマリの自動作成の間隔を返す
+// レベル、サービスの順に設定されているものを使う
+func (c *Config) AutoSummarySchedule(_ context.Context, serviceID, level int) entity.AutoSummarySchedule {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range c.IncidentLevelList {
+		if l.Level == level && l.AutoSummary.IntervalMinutes > 0 {
+			return l.AutoSummary
+		}
+	}
+	for _, s := range c.ServiceList {
+		if s.ID == serviceID {
+			return s.AutoSummary
+		}
+	}
+	return entity.AutoSummarySchedule{}
+}
+
+// IncidentRoles はインシデント対応の役割を返す。設定が無い場合はデフォルトの役割を返す
+func (c *Config) IncidentRoles(_ context.Context) []entity.IncidentRole {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	if len(c.RoleList) == 0 {
+		return entity.DefaultIncidentRoles
+	}
+	return c.RoleList
+}
+
+// OnCall はサービスのオンコールのスケジュールで now の時点の担当者を返す
+// スケジュールが設定されていない場合は空文字を返す
+func (c *Config) OnCall(_ context.Context, service *entity.Service, now time.Time) (string, error) {
+	if service.OnCallSchedule == "" {
+		return "", nil
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, s := range c.OnCallScheduleList {
+		if s.ID == service.OnCallSchedule {
+			return s.OnCall(now)
+		}
+	}
+	return "", fmt.Errorf("oncall schedule %q not found", service.OnCallSchedule)
+}
+
+// PostmortemTemplatePath はポストモーテムのテンプレートファイルのパスを返す
+// サービス、全体の順に設定されているものを使い、どちらも無い場合は空文字を返す
+func (c *Config) PostmortemTemplatePath(service *entity.Service) string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	path := c.PostmortemTemplate
+	if service != nil && service.PostmortemTemplate != "" {
+		path = service.PostmortemTemplate
+	}
+	if path == "" {
+		return ""
+	}
+	return configRelativePath(c.v, path)
+}
+
 func (c *Config) GetGlobalAnnouncementChannels(_ context.Context) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.GlobalAnnouncementChannels
 }
 
+// GetCommunicationChannels は全体で設定された関係者向けの連絡の投稿先を返す
+func (c *Config) GetCommunicationChannels(_ context.Context) entity.CommunicationChannels {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.Communication
+}
+
 // GetNotificationType は設定された通知タイプを返す (none/here/channel)
 // 設定されていない場合は "here" をデフォルトとして返す
 func (c *Config) GetNotificationType() string {
@@ -0,0 +1,459 @@
+package repository_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+)
+
+func writeConfig(t *testing.T, path, body string) {
+	t.Helper()
+	require.NoError(t, os.WriteFile(path, []byte(body), 0o600))
+}
+
+func TestConfig_Reload(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "yas3.toml")
+	writeConfig(t, path, `
+global_announcement_channels = ["all"]
+
+[[services]]
+id = 1
+name = "api"
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+`)
+
+	c, err := repository.NewConfigRepository(path)
+	require.NoError(t, err)
+
+	writeConfig(t, path, `
+global_announcement_channels = ["all", "ops"]
+
+[[services]]
+id = 1
+name = "api"
+
+[[services]]
+id = 2
+name = "web"
+incident_team_members = ["team-web"]
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+`)
+	require.NoError(t, c.Reload())
+
+	services, err := c.Services(ctx)
+	require.NoError(t, err)
+	assert.Len(t, services, 2)
+	service, err := c.ServiceByID(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team-web"}, service.IncidentTeamMembers)
+	assert.Equal(t, []string{"all", "ops"}, c.GetGlobalAnnouncementChannels(ctx))
+
+	// 検証に失敗した設定は反映しない
+	writeConfig(t, path, `
+[[services]]
+id = 3
+name = "batch"
+`)
+	assert.Error(t, c.Reload())
+
+	services, err = c.Services(ctx)
+	require.NoError(t, err)
+	assert.Len(t, services, 2)
+	assert.Len(t, c.IncidentLevels(ctx), 1)
+
+	// config validate で不正とされる設定も反映しない
+	writeConfig(t, path, `
+[[services]]
+id = 1
+name = "api"
+
+[[services]]
+id = 1
+name = "duplicated"
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+`)
+	assert.ErrorContains(t, c.Reload(), "duplicate id 1")
+
+	services, err = c.Services(ctx)
+	require.NoError(t, err)
+	assert.Len(t, services, 2)
+	assert.Equal(t, "web", services[1].Name)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yas3.toml")
+	writeConfig(t, path, `
+[alert]
+default_service_id = 9
+
+[ai]
+provider = "local"
+model = "small"
+[ai.providers.local]
+type = "openai_compatible"
+base_url = "http://localhost:11434/v1"
+[ai.tasks.title]
+provider = "claude"
+[ai.tasks.typo]
+model = "large"
+[ai.redaction]
+disable_detectors = ["passport"]
+restore = ["email", "jwt", "employee_id"]
+[[ai.redaction.patterns]]
+name = "ticket"
+regex = "TICKET-("
+
+[[services]]
+id = 1
+name = "api"
+confluence = { domain = "example" }
+
+[[services]]
+id = 1
+name = "API"
+oncall_schedule = "api"
+
+[[oncall_schedules]]
+id = "web"
+timezone = "Mars/Olympus"
+rotation = "daily"
+start = "2025-01-01T00:00"
+members = ["alice"]
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+
+[[incident_levels]]
+level = 1
+description = "全ユーザーに影響"
+`)
+
+	c, err := repository.NewConfigRepository(path)
+	require.NoError(t, err)
+
+	var msgs []string
+	for _, e := range c.Validate() {
+		msgs = append(msgs, e.Error())
+	}
+	assert.ElementsMatch(t, []string{
+		"services: duplicate id 1 (API)",
+		`services[1]: oncall_schedule "api" does not match any schedule`,
+		`oncall_schedules[web]: invalid timezone "Mars/Olympus": unknown time zone Mars/Olympus`,
+		"services[1]: confluence.space is required when confluence.domain is set",
+		"services[1]: confluence.ancestor_id is required when confluence.domain is set",
+		"incident_levels: duplicate level 1",
+		"alert: default_service_id 9 does not match any service",
+		`ai.tasks.title: provider "claude" is not defined in ai.providers`,
+		`ai.tasks: unknown task "typo"`,
+		`ai.redaction: unknown detector "passport" in disable_detectors`,
+		"ai.redaction.patterns[0]: error parsing regexp: missing closing ): `TICKET-(`",
+		`ai.redaction: "jwt" is a secret and cannot be restored`,
+		`ai.redaction: unknown detector "employee_id" in restore`,
+	}, msgs)
+
+	assert.ElementsMatch(t, []string{
+		"services[1]: incident_team_members is empty, nobody will be invited",
+		"services[1]: no announcement channels, incidents will not be announced",
+		"services[1]: no announcement channels, incidents will not be announced",
+		`services[1]: name "API" is also used by services[1]`,
+	}, c.Lint())
+}
+
+type fakeSlackResolver struct {
+	members  map[string]bool
+	channels map[string]bool
+}
+
+func (f *fakeSlackResolver) GetMemberIDs(name string) ([]string, error) {
+	if !f.members[name] {
+		return nil, repository.ErrSlackNotFound
+	}
+	return []string{"U" + name}, nil
+}
+
+func (f *fakeSlackResolver) GetChannelByName(name string) (*slack.Channel, error) {
+	if !f.channels[name] {
+		return nil, nil
+	}
+	return &slack.Channel{}, nil
+}
+
+func TestConfig_ValidateSlack(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yas3.toml")
+	writeConfig(t, path, `
+global_announcement_channels = ["all", "typo"]
+
+[communication]
+executive = ["exec"]
+
+[[services]]
+id = 1
+name = "api"
+incident_team_members = ["team-api", "nobody"]
+announcement_channels = ["api-alerts"]
+[services.communication]
+customer = ["cs-api"]
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+`)
+
+	c, err := repository.NewConfigRepository(path)
+	require.NoError(t, err)
+
+	errs := c.ValidateSlack(context.Background(), &fakeSlackResolver{
+		members:  map[string]bool{"team-api": true},
+		channels: map[string]bool{"all": true, "api-alerts": true},
+	})
+	var msgs []string
+	for _, e := range errs {
+		msgs = append(msgs, e.Error())
+	}
+	assert.Equal(t, []string{
+		`global_announcement_channels: channel "typo" not found`,
+		`communication.executive: channel "exec" not found`,
+		`services[1]: incident_team_members "nobody" not found`,
+		`services[1].communication.customer: channel "cs-api" not found`,
+	}, msgs)
+}
+
+func TestConfig_TimekeeperSchedule(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "yas3.toml")
+	writeConfig(t, path, `
+[timekeeper]
+interval_minutes = 20
+[timekeeper.urgencies.warning]
+interval_minutes = 30
+
+[[services]]
+id = 1
+name = "api"
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+
+[[incident_levels]]
+level = 3
+description = "全ユーザーに影響"
+[incident_levels.timekeeper]
+interval_minutes = 5
+steps = [{ after_minutes = 60, interval_minutes = 15 }]
+`)
+
+	c, err := repository.NewConfigRepository(path)
+	require.NoError(t, err)
+
+	// レベルの設定が緊急度より優先される
+	s := c.TimekeeperSchedule(ctx, 3, "warning")
+	assert.Equal(t, 5*time.Minute, s.Interval(0))
+	assert.Equal(t, 15*time.Minute, s.Interval(60*time.Minute))
+	assert.Equal(t, 5*time.Minute, s.NextCheckpoint(0))
+	assert.Equal(t, 60*time.Minute, s.NextCheckpoint(57*time.Minute))
+	assert.Equal(t, 75*time.Minute, s.NextCheckpoint(60*time.Minute))
+
+	assert.Equal(t, 30*time.Minute, c.TimekeeperSchedule(ctx, 1, "warning").NextCheckpoint(0))
+	assert.Equal(t, 20*time.Minute, c.TimekeeperSchedule(ctx, 1, "critical").NextCheckpoint(0))
+
+	var empty repository.Config
+	assert.Equal(t, 30*time.Minute, empty.TimekeeperSchedule(ctx, 1, "").NextCheckpoint(15*time.Minute))
+
+	// 間隔が 0 の段階は無視して、それまでの間隔で進む
+	zero := entity.TimekeeperSchedule{IntervalMinutes: 10, Steps: []entity.TimekeeperStep{{AfterMinutes: 60, IntervalMinutes: 0}}}
+	assert.Equal(t, 10*time.Minute, zero.Interval(90*time.Minute))
+	assert.Equal(t, 100*time.Minute, zero.NextCheckpoint(90*time.Minute))
+	assert.Equal(t, 90*time.Minute, zero.Checkpoint(90*time.Minute))
+}
+
+func TestConfig_TimekeeperScheduleInvalid(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "yas3.toml")
+	writeConfig(t, path, `
+[[services]]
+id = 1
+name = "api"
+
+[[incident_levels]]
+level = 3
+description = "全ユーザーに影響"
+[incident_levels.timekeeper]
+steps = [{ after_minutes = 60, interval_minutes = 0 }]
+`)
+
+	_, err := repository.NewConfigRepository(path)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "incident_levels[3].timekeeper")
+}
+
+func TestConfig_AutoSummarySchedule(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "yas3.toml")
+	writeConfig(t, path, `
+[[services]]
+id = 1
+name = "api"
+[services.auto_summary]
+interval_minutes = 60
+
+[[services]]
+id = 2
+name = "batch"
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+
+[[incident_levels]]
+level = 3
+description = "全ユーザーに影響"
+[incident_levels.auto_summary]
+interval_minutes = 30
+announce = true
+`)
+
+	c, err := repository.NewConfigRepository(path)
+	require.NoError(t, err)
+
+	// レベルの設定がサービスより優先される
+	assert.Equal(t, entity.AutoSummarySchedule{IntervalMinutes: 30, Announce: true}, c.AutoSummarySchedule(ctx, 1, 3))
+	assert.Equal(t, entity.AutoSummarySchedule{IntervalMinutes: 60}, c.AutoSummarySchedule(ctx, 1, 1))
+	assert.Equal(t, 30*time.Minute, c.AutoSummarySchedule(ctx, 2, 3).Interval())
+	assert.Zero(t, c.AutoSummarySchedule(ctx, 2, 1).IntervalMinutes, "設定しない場合は作成しない")
+
+	for _, tt := range []struct {
+		name, body, want string
+	}{
+		{
+			name: "サービスの間隔が負",
+			body: `
+[[services]]
+id = 1
+name = "api"
+auto_summary = { interval_minutes = -1 }
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+`,
+			want: "services[1].auto_summary",
+		},
+		{
+			name: "レベルの間隔が負",
+			body: `
+[[services]]
+id = 1
+name = "api"
+
+[[incident_levels]]
+level = 3
+description = "全ユーザーに影響"
+auto_summary = { interval_minutes = -30 }
+`,
+			want: "incident_levels[3].auto_summary",
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			invalid := filepath.Join(t.TempDir(), "yas3.toml")
+			writeConfig(t, invalid, tt.body)
+			_, err := repository.NewConfigRepository(invalid)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tt.want)
+		})
+	}
+}
+
+func TestConfig_OnCall(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	writeConfig(t, filepath.Join(dir, "oncall.toml"), `
+[[oncall_schedules]]
+id = "api-primary"
+timezone = "Asia/Tokyo"
+rotation = "weekly"
+start = "2025-01-06T10:00"
+members = ["alice", "bob", "carol"]
+overrides = [{ member = "dave", start = "2025-01-20T10:00", end = "2025-01-21T10:00" }]
+`)
+	path := filepath.Join(dir, "yas3.toml")
+	writeConfig(t, path, `
+oncall_file = "oncall.toml"
+
+[[oncall_schedules]]
+id = "web"
+rotation = "daily"
+start = "2025-01-01T00:00"
+members = ["erin", "frank"]
+
+[[services]]
+id = 1
+name = "api"
+oncall_schedule = "api-primary"
+
+[[services]]
+id = 2
+name = "web"
+oncall_schedule = "web"
+
+[[services]]
+id = 3
+name = "batch"
+
+[[incident_levels]]
+level = 1
+description = "一部ユーザーに影響"
+`)
+
+	c, err := repository.NewConfigRepository(path)
+	require.NoError(t, err)
+	assert.Empty(t, c.Validate())
+
+	jst := time.FixedZone("JST", 9*60*60)
+	api, _ := c.ServiceByID(ctx, 1)
+	for _, tt := range []struct {
+		now  time.Time
+		want string
+	}{
+		{time.Date(2025, 1, 6, 9, 59, 0, 0, jst), ""},
+		{time.Date(2025, 1, 6, 10, 0, 0, 0, jst), "alice"},
+		{time.Date(2025, 1, 13, 9, 59, 0, 0, jst), "alice"},
+		{time.Date(2025, 1, 13, 10, 0, 0, 0, jst), "bob"},
+		{time.Date(2025, 1, 20, 12, 0, 0, 0, jst), "dave"},
+		{time.Date(2025, 1, 21, 10, 0, 0, 0, jst), "carol"},
+		{time.Date(2025, 1, 27, 10, 0, 0, 0, jst), "alice"},
+	} {
+		got, err := c.OnCall(ctx, api, tt.now)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, got, tt.now)
+	}
+
+	web, _ := c.ServiceByID(ctx, 2)
+	got, err := c.OnCall(ctx, web, time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC))
+	require.NoError(t, err)
+	assert.Equal(t, "frank", got)
+
+	batch, _ := c.ServiceByID(ctx, 3)
+	got, err = c.OnCall(ctx, batch, time.Now())
+	require.NoError(t, err)
+	assert.Empty(t, got)
+}
@@ -0,0 +1,158 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+)
+
+const (
+	DBDriverDynamoDB = "dynamodb"
+)
+
+// DBRepositoryer は永続化層の実装(DynamoDB/SQL)が満たすインターフェース
+type DBRepositoryer interface {
+	IncidentRepositoryer
+	IncidentEventRepositoryer
+	LeaseRepositoryer
+	IncidentEmbeddingRepositoryer
+	Migrator
+}
+
+// LeaseRepositoryer はレプリカ間で排他するためのリースを管理する
+type LeaseRepositoryer interface {
+	// name のリースを holder として ttl の間取得する。保持済みの場合は延長する
+	// 他の holder が期限内のリースを保持している場合は false を返す
+	AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error)
+	// holder が保持している場合のみ解放する
+	ReleaseLease(ctx context.Context, name, holder string) error
+}
+
+// IncidentEmbeddingRepositoryer はインシデントの文章の埋め込みベクトルを管理する
+type IncidentEmbeddingRepositoryer interface {
+	// 同じチャンネルと種類のベクトルは上書きする
+	SaveIncidentEmbedding(ctx context.Context, embedding *entity.IncidentEmbedding) error
+	// すべてのベクトルを返す。総当たりで検索するため全件を読み込む
+	IncidentEmbeddings(ctx context.Context) ([]entity.IncidentEmbedding, error)
+}
+
+// Migrator は既存のスキーマを最新の構成に移行する
+type Migrator interface {
+	Migrate(ctx context.Context) error
+}
+
+// NewDBRepository は環境変数 DB_DRIVER に応じた永続化層を返す
+// DB_DRIVER: dynamodb(デフォルト) / sqlite / postgres
+// DB_DSN: SQLの接続文字列(sqliteの場合は省略時 yas3.db)
+func NewDBRepository() (DBRepositoryer, error) {
+	switch driver := os.Getenv("DB_DRIVER"); driver {
+	case "", DBDriverDynamoDB:
+		r, err := NewDynamoDBRepository()
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	case SQLDriverSQLite, SQLDriverPostgres:
+		r, err := NewSQLRepository(driver, os.Getenv("DB_DSN"))
+		if err != nil {
+			return nil, err
+		}
+		return r, nil
+	default:
+		return nil, fmt.Errorf("unsupported DB_DRIVER: %s", driver)
+	}
+}
+
+// 未設定の発生時刻とイベントIDを補完する
+// イベントIDは発生時刻の固定幅ナノ秒にランダムな接尾辞を付けたもので、文字列順が発生順になる
+func prepareIncidentEvent(event *entity.IncidentEvent) error {
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now()
+	}
+	if event.EventID == "" {
+		b := make([]byte, 4)
+		if _, err := rand.Read(b); err != nil {
+			return fmt.Errorf("failed to generate event id: %w", err)
+		}
+		event.EventID = fmt.Sprintf("%020d-%s", event.OccurredAt.UnixNano(), hex.EncodeToString(b))
+	}
+	return nil
+}
+
+// incidentCursor は一覧の続きの位置。最後に返したインシデントの検知時刻とチャンネルIDを指す
+type incidentCursor struct {
+	startedAt time.Time
+	channelID string
+}
+
+// after は incident が一覧でカーソルより後に並ぶ場合に true を返す
+func (c incidentCursor) after(incident *entity.Incident) bool {
+	if incident.StartedAt.Equal(c.startedAt) {
+		return incident.ChannelID > c.channelID
+	}
+	return incident.StartedAt.Before(c.startedAt)
+}
+
+// カーソルは最後に返したインシデントの検知時刻とチャンネルIDを元にした不透明な文字列
+func encodeIncidentCursor(incident *entity.Incident) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%s", incident.StartedAt.UnixNano(), incident.ChannelID)))
+}
+
+// カーソルが空の場合は nil を返す
+func decodeIncidentCursor(cursor string) (*incidentCursor, error) {
+	if cursor == "" {
+		return nil, nil
+	}
+	b, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	nanos, channelID, ok := strings.Cut(string(b), ":")
+	if !ok || channelID == "" {
+		return nil, ErrInvalidCursor
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &incidentCursor{startedAt: time.Unix(0, n), channelID: channelID}, nil
+}
+
+// SortIncidents はインシデントを一覧の順(検知の新しい順、同時刻はチャンネルIDの昇順)に並べる
+func SortIncidents(incidents []entity.Incident) {
+	slices.SortFunc(incidents, func(a, b entity.Incident) int {
+		if c := b.StartedAt.Compare(a.StartedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ChannelID, b.ChannelID)
+	})
+}
+
+// PageIncidents はインシデントを一覧の順に並べ、cursor の続きから最大 limit 件と次のカーソルを返す
+// Incidents と同じカーソルを使うため、一覧をまとめて取得できる場合はこれでページングする。incidents は変更しない
+func PageIncidents(incidents []entity.Incident, cursor string, limit int) ([]entity.Incident, string, error) {
+	c, err := decodeIncidentCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+	incidents = slices.Clone(incidents)
+	SortIncidents(incidents)
+	if c != nil {
+		incidents = slices.DeleteFunc(incidents, func(i entity.Incident) bool { return !c.after(&i) })
+	}
+	if len(incidents) <= limit {
+		return incidents, "", nil
+	}
+	incidents = incidents[:limit]
+	return incidents, encodeIncidentCursor(&incidents[limit-1]), nil
+}
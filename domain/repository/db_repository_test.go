@@ -0,0 +1,297 @@
+package repository_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+)
+
+// DB_DRIVER が未指定の場合はインメモリのSQLiteでテストする
+func newTestDBRepository(t *testing.T) repository.DBRepositoryer {
+	t.Helper()
+	if os.Getenv("DB_DRIVER") == "" {
+		r, err := repository.NewSQLRepository(repository.SQLDriverSQLite, "file::memory:")
+		require.NoError(t, err)
+		t.Cleanup(func() { r.Close() })
+		return r
+	}
+	r, err := repository.NewDBRepository()
+	require.NoError(t, err)
+	return r
+}
+
+// 同じテーブルを共有するバックエンドでも衝突しないチャンネルIDを払い出す
+func testChannelID(name string) string {
+	return fmt.Sprintf("T%s-%d", name, time.Now().UnixNano())
+}
+
+func TestDBRepository_SaveAndFind(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	loc, err := time.LoadLocation("Asia/Tokyo")
+	require.NoError(t, err)
+	startedAt := time.Date(2025, 3, 1, 10, 0, 0, 123456789, loc)
+
+	channelID := testChannelID("save")
+	incident := &entity.Incident{
+		ChannelID:     channelID,
+		Description:   "APIが応答しない",
+		Urgency:       "critical",
+		Level:         2,
+		ServiceID:     1,
+		HandlerUserID: "UHANDLER",
+		CreatedUserID: "UCREATED",
+		StartedAt:     startedAt,
+		// オンコールでハンドラーが決まった場合は作成と同時に設定される
+		HandlerAssignedAt: startedAt,
+		LinkedChannels: []entity.LinkedChannel{
+			{ChannelID: "CLINK"},
+			{ChannelID: "CTHREAD", ThreadTS: "1234.5678"},
+		},
+	}
+	require.NoError(t, r.SaveIncident(ctx, incident))
+
+	got, err := r.FindIncidentByChannel(ctx, channelID)
+	require.NoError(t, err)
+	require.NotNil(t, got)
+	assert.Equal(t, incident.Description, got.Description)
+	assert.Equal(t, incident.Urgency, got.Urgency)
+	assert.Equal(t, incident.Level, got.Level)
+	assert.Equal(t, incident.HandlerUserID, got.HandlerUserID)
+	assert.True(t, startedAt.Equal(got.StartedAt))
+	assert.True(t, startedAt.Equal(got.HandlerAssignedAt))
+	assert.True(t, got.RecoveredAt.IsZero())
+	assert.True(t, got.ClosedAt.IsZero())
+	assert.Equal(t, incident.LinkedChannels, got.LinkedChannels)
+
+	// 上書き保存
+	got.RecoveredAt = startedAt.Add(time.Hour)
+	got.RecoveredUserID = "URECOVERED"
+	got.DisableTimer = true
+	got.LinkedChannels = got.LinkedChannels[:1]
+	require.NoError(t, r.SaveIncident(ctx, got))
+
+	updated, err := r.FindIncidentByChannel(ctx, channelID)
+	require.NoError(t, err)
+	require.NotNil(t, updated)
+	assert.True(t, got.RecoveredAt.Equal(updated.RecoveredAt))
+	assert.Equal(t, "URECOVERED", updated.RecoveredUserID)
+	assert.True(t, updated.DisableTimer)
+	assert.Len(t, updated.LinkedChannels, 1)
+}
+
+func TestDBRepository_FindNotFound(t *testing.T) {
+	r := newTestDBRepository(t)
+
+	got, err := r.FindIncidentByChannel(context.Background(), testChannelID("notfound"))
+	require.NoError(t, err)
+	assert.Nil(t, got)
+}
+
+func TestDBRepository_ActiveIncidents(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	activeID := testChannelID("active")
+	closedID := testChannelID("closed")
+	require.NoError(t, r.SaveIncident(ctx, &entity.Incident{ChannelID: activeID, StartedAt: time.Now()}))
+	require.NoError(t, r.SaveIncident(ctx, &entity.Incident{ChannelID: closedID, StartedAt: time.Now(), ClosedAt: time.Now()}))
+
+	incidents, err := r.ActiveIncidents(ctx)
+	require.NoError(t, err)
+
+	ids := map[string]bool{}
+	for _, i := range incidents {
+		ids[i.ChannelID] = true
+	}
+	assert.True(t, ids[activeID])
+	assert.False(t, ids[closedID])
+}
+
+func TestDBRepository_IncidentEvents(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	channelID := testChannelID("events")
+	base := time.Now()
+	events := []*entity.IncidentEvent{
+		{ChannelID: channelID, Type: entity.IncidentEventCreated, ActorUserID: "UCREATED", OccurredAt: base},
+		{ChannelID: channelID, Type: entity.IncidentEventLevelChanged, ActorUserID: "UHANDLER", OccurredAt: base.Add(time.Minute), Metadata: map[string]string{"from": "1", "to": "3"}},
+		{ChannelID: channelID, Type: entity.IncidentEventRecovered, ActorUserID: "UHANDLER", OccurredAt: base.Add(2 * time.Minute)},
+	}
+	// 保存順に依らず発生順で返ること
+	for _, i := range []int{2, 0, 1} {
+		require.NoError(t, r.SaveIncidentEvent(ctx, events[i]))
+		assert.NotEmpty(t, events[i].EventID)
+	}
+	require.NoError(t, r.SaveIncidentEvent(ctx, &entity.IncidentEvent{ChannelID: testChannelID("other"), Type: entity.IncidentEventCreated}))
+
+	got, err := r.IncidentEvents(ctx, channelID)
+	require.NoError(t, err)
+	require.Len(t, got, 3)
+	assert.Equal(t, entity.IncidentEventCreated, got[0].Type)
+	assert.Equal(t, entity.IncidentEventLevelChanged, got[1].Type)
+	assert.Equal(t, map[string]string{"from": "1", "to": "3"}, got[1].Metadata)
+	assert.Equal(t, "UHANDLER", got[1].ActorUserID)
+	assert.True(t, events[1].OccurredAt.Equal(got[1].OccurredAt))
+	assert.Equal(t, entity.IncidentEventRecovered, got[2].Type)
+}
+
+func TestDBRepository_SaveIncidentConflict(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	channelID := testChannelID("conflict")
+	incident := &entity.Incident{ChannelID: channelID, StartedAt: time.Now()}
+	require.NoError(t, r.SaveIncident(ctx, incident))
+	assert.Equal(t, 1, incident.Version)
+
+	// 同じ版を読み込んだ2つの更新
+	first, err := r.FindIncidentByChannel(ctx, channelID)
+	require.NoError(t, err)
+	second, err := r.FindIncidentByChannel(ctx, channelID)
+	require.NoError(t, err)
+
+	first.Level = 3
+	require.NoError(t, r.SaveIncident(ctx, first))
+	assert.Equal(t, 2, first.Version)
+
+	second.RecoveredUserID = "URECOVERED"
+	err = r.SaveIncident(ctx, second)
+	assert.ErrorIs(t, err, repository.ErrIncidentConflict)
+	assert.Equal(t, 1, second.Version)
+
+	// 新規作成として同じチャンネルを保存しようとしても上書きしない
+	err = r.SaveIncident(ctx, &entity.Incident{ChannelID: channelID})
+	assert.ErrorIs(t, err, repository.ErrIncidentConflict)
+
+	got, err := r.FindIncidentByChannel(ctx, channelID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, got.Level)
+	assert.Empty(t, got.RecoveredUserID)
+	assert.Equal(t, 2, got.Version)
+}
+
+func TestDBRepository_Incidents(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	// 2件ずつ同じ検知時刻にして、同時刻の並びも確かめる
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	created := map[string]bool{}
+	ids := make([]string, 5)
+	for i := 0; i < 5; i++ {
+		incident := &entity.Incident{
+			ChannelID: testChannelID(fmt.Sprintf("list%d", i)),
+			StartedAt: base.Add(time.Duration(i/2) * time.Hour),
+		}
+		if i%2 == 0 {
+			incident.ClosedAt = time.Now()
+		}
+		require.NoError(t, r.SaveIncident(ctx, incident))
+		created[incident.ChannelID] = false
+		ids[i] = incident.ChannelID
+	}
+
+	// クローズ済みを含めてすべてのページを辿ると、重複なく新しい順に取得できる
+	var order []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 1000)
+		incidents, next, err := r.Incidents(ctx, cursor, 2)
+		require.NoError(t, err)
+		assert.LessOrEqual(t, len(incidents), 2)
+		for _, incident := range incidents {
+			if seen, ok := created[incident.ChannelID]; ok {
+				assert.False(t, seen, "duplicated: %s", incident.ChannelID)
+				created[incident.ChannelID] = true
+				order = append(order, incident.ChannelID)
+			}
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	for channelID, seen := range created {
+		assert.True(t, seen, "missing: %s", channelID)
+	}
+	assert.Equal(t, []string{ids[4], ids[2], ids[3], ids[0], ids[1]}, order)
+
+	_, _, err := r.Incidents(ctx, "!!invalid!!", 2)
+	assert.ErrorIs(t, err, repository.ErrInvalidCursor)
+}
+
+func TestDBRepository_Lease(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	name := testChannelID("lease")
+	ok, err := r.AcquireLease(ctx, name, "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 期限内は他のholderは取得できず、保持しているholderは延長できる
+	ok, err = r.AcquireLease(ctx, name, "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+	ok, err = r.AcquireLease(ctx, name, "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 保持していないholderは解放できない
+	require.NoError(t, r.ReleaseLease(ctx, name, "b"))
+	ok, err = r.AcquireLease(ctx, name, "b", time.Minute)
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	require.NoError(t, r.ReleaseLease(ctx, name, "a"))
+	ok, err = r.AcquireLease(ctx, name, "b", -time.Second)
+	require.NoError(t, err)
+	assert.True(t, ok)
+
+	// 期限切れのリースは他のholderが取得できる
+	ok, err = r.AcquireLease(ctx, name, "a", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, ok)
+}
+
+func TestDBRepository_IncidentEmbeddings(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+
+	channelID := testChannelID("embedding")
+	updatedAt := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	require.NoError(t, r.SaveIncidentEmbedding(ctx, &entity.IncidentEmbedding{
+		ChannelID: channelID, Source: entity.EmbeddingSourceDescription, Model: "small", Vector: []float32{0.1, 0.2}, UpdatedAt: updatedAt,
+	}))
+	require.NoError(t, r.SaveIncidentEmbedding(ctx, &entity.IncidentEmbedding{
+		ChannelID: channelID, Source: entity.EmbeddingSourceSummary, Model: "small", Vector: []float32{0.3, 0.4}, UpdatedAt: updatedAt,
+	}))
+	// 同じチャンネルと種類は上書きする
+	require.NoError(t, r.SaveIncidentEmbedding(ctx, &entity.IncidentEmbedding{
+		ChannelID: channelID, Source: entity.EmbeddingSourceDescription, Model: "large", Vector: []float32{0.5, 0.6, 0.7}, UpdatedAt: updatedAt,
+	}))
+
+	embeddings, err := r.IncidentEmbeddings(ctx)
+	require.NoError(t, err)
+	got := map[string]entity.IncidentEmbedding{}
+	for _, e := range embeddings {
+		if e.ChannelID == channelID {
+			got[e.Source] = e
+		}
+	}
+	require.Len(t, got, 2)
+	assert.Equal(t, "large", got[entity.EmbeddingSourceDescription].Model)
+	assert.Equal(t, []float32{0.5, 0.6, 0.7}, got[entity.EmbeddingSourceDescription].Vector)
+	assert.True(t, updatedAt.Equal(got[entity.EmbeddingSourceSummary].UpdatedAt))
+}
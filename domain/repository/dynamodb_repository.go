@@ -2,24 +2,63 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/smithy-go"
 	"github.com/guregu/dynamo/v2"
 	"github.com/pyama86/YAS3/domain/entity"
 )
 
-var incidentsTable = "incidents"
+var (
+	incidentsTable      = "incidents"
+	incidentEventsTable = "incident_events"
+	leasesTable         = "leases"
+	embeddingsTable     = "incident_embeddings"
+)
+
+const (
+	// 未クローズのインシデントにのみ active 属性を持たせ、スパースなGSIで引けるようにする
+	activeIncidentsIndex = "active-started_at-index"
+	activeAttribute      = "active"
+	activeFlag           = "1"
+)
 
 func init() {
 	if os.Getenv("DYNAMO_INCIDENTS_TABLE") != "" {
 		incidentsTable = os.Getenv("DYNAMO_INCIDENTS_TABLE")
 	}
+	if os.Getenv("DYNAMO_INCIDENT_EVENTS_TABLE") != "" {
+		incidentEventsTable = os.Getenv("DYNAMO_INCIDENT_EVENTS_TABLE")
+	}
+	if os.Getenv("DYNAMO_LEASES_TABLE") != "" {
+		leasesTable = os.Getenv("DYNAMO_LEASES_TABLE")
+	}
+	if os.Getenv("DYNAMO_INCIDENT_EMBEDDINGS_TABLE") != "" {
+		embeddingsTable = os.Getenv("DYNAMO_INCIDENT_EMBEDDINGS_TABLE")
+	}
+}
+
+// DynamoDB上のインシデントの表現
+type dynamoIncident struct {
+	entity.Incident
+	Active string `dynamo:"active,omitempty"`
+}
+
+func newDynamoIncident(incident *entity.Incident) dynamoIncident {
+	item := dynamoIncident{Incident: *incident}
+	if incident.ClosedAt.IsZero() {
+		item.Active = activeFlag
+	}
+	return item
 }
 
 func NewDynamoDBRepository() (*DynamoDBRepository, error) {
@@ -37,7 +76,19 @@ func NewDynamoDBRepository() (*DynamoDBRepository, error) {
 		},
 		)
 
-		err = setupDdbSchema(db)
+		err = setupDdbSchema(db, incidentsTable, entity.Incident{}, activeIncidentsIndexSchema(dynamo.Throughput{Read: 10, Write: 10}))
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup schema: %v", err)
+		}
+		err = setupDdbSchema(db, incidentEventsTable, entity.IncidentEvent{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup schema: %v", err)
+		}
+		err = setupDdbSchema(db, leasesTable, entity.Lease{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to setup schema: %v", err)
+		}
+		err = setupDdbSchema(db, embeddingsTable, entity.IncidentEmbedding{})
 		if err != nil {
 			return nil, fmt.Errorf("failed to setup schema: %v", err)
 		}
@@ -49,16 +100,39 @@ func NewDynamoDBRepository() (*DynamoDBRepository, error) {
 		db = dynamo.New(cfg)
 	}
 
-	return &DynamoDBRepository{db: db}, nil
+	r := &DynamoDBRepository{db: db, table: incidentsTable, eventsTable: incidentEventsTable, leasesTable: leasesTable, embeddingsTable: embeddingsTable}
+	if os.Getenv("DYNAMO_LOCAL") != "" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		defer cancel()
+		if err := r.Migrate(ctx); err != nil {
+			return nil, fmt.Errorf("failed to migrate schema: %v", err)
+		}
+	}
+	return r, nil
+}
+
+func activeIncidentsIndexSchema(throughput dynamo.Throughput) dynamo.Index {
+	return dynamo.Index{
+		Name:           activeIncidentsIndex,
+		HashKey:        activeAttribute,
+		HashKeyType:    dynamo.StringType,
+		RangeKey:       "started_at",
+		RangeKeyType:   dynamo.StringType,
+		ProjectionType: dynamo.AllProjection,
+		Throughput:     throughput,
+	}
 }
 
-func setupDdbSchema(db *dynamo.DB) error {
-	t := db.Table(incidentsTable)
+func setupDdbSchema(db *dynamo.DB, table string, from any, indexes ...dynamo.Index) error {
+	t := db.Table(table)
 	_, err := t.Describe().Run(context.TODO())
 	if err != nil {
 
-		input := db.CreateTable(incidentsTable, entity.Incident{}).
+		input := db.CreateTable(table, from).
 			Provision(10, 10)
+		for _, index := range indexes {
+			input = input.Index(index)
+		}
 		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 		defer cancel()
 
@@ -68,12 +142,134 @@ func setupDdbSchema(db *dynamo.DB) error {
 }
 
 type DynamoDBRepository struct {
-	db *dynamo.DB
+	db              *dynamo.DB
+	table           string
+	eventsTable     string
+	leasesTable     string
+	embeddingsTable string
+}
+
+// Migrate は既存テーブルを最新の構成に移行する。何度実行しても問題ない
+func (r *DynamoDBRepository) Migrate(ctx context.Context) error {
+	if err := r.migrateEventsTable(ctx); err != nil {
+		return err
+	}
+	if err := r.migrateLeasesTable(ctx); err != nil {
+		return err
+	}
+	if err := r.migrateEmbeddingsTable(ctx); err != nil {
+		return err
+	}
+	return r.migrateActiveIndex(ctx)
+}
+
+// イベントログのテーブルが無ければ作成する
+func (r *DynamoDBRepository) migrateEventsTable(ctx context.Context) error {
+	if _, err := r.db.Table(r.eventsTable).Describe().Run(ctx); err == nil {
+		return nil
+	}
+	slog.Info("creating table", slog.String("table", r.eventsTable))
+	if err := r.db.CreateTable(r.eventsTable, entity.IncidentEvent{}).OnDemand(true).Run(ctx); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", r.eventsTable, err)
+	}
+	return nil
+}
+
+// リース用のテーブルが無ければ作成する
+func (r *DynamoDBRepository) migrateLeasesTable(ctx context.Context) error {
+	if _, err := r.db.Table(r.leasesTable).Describe().Run(ctx); err == nil {
+		return nil
+	}
+	slog.Info("creating table", slog.String("table", r.leasesTable))
+	if err := r.db.CreateTable(r.leasesTable, entity.Lease{}).OnDemand(true).Run(ctx); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", r.leasesTable, err)
+	}
+	return nil
+}
+
+// 埋め込みベクトル用のテーブルが無ければ作成する
+func (r *DynamoDBRepository) migrateEmbeddingsTable(ctx context.Context) error {
+	if _, err := r.db.Table(r.embeddingsTable).Describe().Run(ctx); err == nil {
+		return nil
+	}
+	slog.Info("creating table", slog.String("table", r.embeddingsTable))
+	if err := r.db.CreateTable(r.embeddingsTable, entity.IncidentEmbedding{}).OnDemand(true).Run(ctx); err != nil {
+		return fmt.Errorf("failed to create table %s: %w", r.embeddingsTable, err)
+	}
+	return nil
+}
+
+// アクティブインシデント用のGSIを追加し、未クローズのインシデントにactive属性を付与する
+func (r *DynamoDBRepository) migrateActiveIndex(ctx context.Context) error {
+	t := r.db.Table(r.table)
+	desc, err := t.Describe().Run(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to describe table %s: %w", r.table, err)
+	}
+
+	hasIndex := false
+	for _, idx := range desc.GSI {
+		if idx.Name == activeIncidentsIndex {
+			hasIndex = true
+			break
+		}
+	}
+
+	if !hasIndex {
+		slog.Info("creating index", slog.String("table", r.table), slog.String("index", activeIncidentsIndex))
+		var throughput dynamo.Throughput
+		if !desc.OnDemand {
+			throughput = dynamo.Throughput{Read: desc.Throughput.Read, Write: desc.Throughput.Write}
+		}
+		if _, err := t.UpdateTable().CreateIndex(activeIncidentsIndexSchema(throughput)).Run(ctx); err != nil {
+			return fmt.Errorf("failed to create index %s: %w", activeIncidentsIndex, err)
+		}
+	}
+
+	// 既存の未クローズインシデントにactive属性を付与する
+	var incidents []entity.Incident
+	err = t.Scan().
+		Filter("'closed_at' = ? AND attribute_not_exists('active')", time.Time{}).
+		All(ctx, &incidents)
+	if err != nil {
+		return fmt.Errorf("failed to scan incidents for backfill: %w", err)
+	}
+	for _, incident := range incidents {
+		err := t.Update("channel_id", incident.ChannelID).
+			Set(activeAttribute, activeFlag).
+			If("'closed_at' = ?", time.Time{}).
+			Run(ctx)
+		if err != nil && !dynamo.IsCondCheckFailed(err) {
+			return fmt.Errorf("failed to backfill %s: %w", incident.ChannelID, err)
+		}
+	}
+	slog.Info("backfilled active incidents", slog.String("table", r.table), slog.Int("count", len(incidents)))
+
+	return r.waitIndexActive(ctx)
+}
+
+func (r *DynamoDBRepository) waitIndexActive(ctx context.Context) error {
+	for {
+		desc, err := r.db.Table(r.table).Describe().Run(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to describe table %s: %w", r.table, err)
+		}
+		for _, idx := range desc.GSI {
+			if idx.Name == activeIncidentsIndex && idx.Status == dynamo.ActiveStatus && !idx.Backfilling {
+				return nil
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
 }
 
 func (r *DynamoDBRepository) FindIncidentByChannel(ctx context.Context, channel string) (*entity.Incident, error) {
 	incident := &entity.Incident{}
-	err := r.db.Table(incidentsTable).Get("channel_id", channel).One(ctx, incident)
+	err := r.db.Table(r.table).Get("channel_id", channel).One(ctx, incident)
 	if err != nil {
 		if err == dynamo.ErrNotFound {
 			return nil, nil
@@ -84,16 +280,119 @@ func (r *DynamoDBRepository) FindIncidentByChannel(ctx context.Context, channel
 }
 
 func (r *DynamoDBRepository) SaveIncident(ctx context.Context, incident *entity.Incident) error {
-	return r.db.Table(incidentsTable).Put(incident).Run(ctx)
+	item := newDynamoIncident(incident)
+	item.Version = incident.Version + 1
+
+	put := r.db.Table(r.table).Put(item)
+	if incident.Version == 0 {
+		// 新規作成、またはversion属性を持たない既存データ
+		put = put.If("attribute_not_exists('version') OR 'version' = ?", 0)
+	} else {
+		put = put.If("'version' = ?", incident.Version)
+	}
+	if err := put.Run(ctx); err != nil {
+		if dynamo.IsCondCheckFailed(err) {
+			return ErrIncidentConflict
+		}
+		return err
+	}
+	incident.Version = item.Version
+	return nil
 }
 
-// closed_atが0のものを取得
+// active属性のGSIからクローズされていないものを取得
 func (r *DynamoDBRepository) ActiveIncidents(ctx context.Context) ([]entity.Incident, error) {
+	var incidents []entity.Incident
+	err := r.db.Table(r.table).Get(activeAttribute, activeFlag).Index(activeIncidentsIndex).All(ctx, &incidents)
+	if err != nil {
+		if isMissingIndexError(err) {
+			slog.Warn("active incidents index is not available, falling back to scan. run `yas3 migrate`", slog.String("table", r.table))
+			return r.scanActiveIncidents(ctx)
+		}
+		return nil, err
+	}
+	return incidents, nil
+}
+
+// closed_atが0のものをテーブル全体から取得
+func (r *DynamoDBRepository) scanActiveIncidents(ctx context.Context) ([]entity.Incident, error) {
 	var incidents []entity.Incident
 	t := time.Time{}
-	err := r.db.Table(incidentsTable).Scan().Filter("'closed_at' = ?", t).All(ctx, &incidents)
+	err := r.db.Table(r.table).Scan().Filter("'closed_at' = ?", t).All(ctx, &incidents)
 	if err != nil {
 		return nil, err
 	}
 	return incidents, nil
 }
+
+// テーブル全体をスキャンし、検知の新しい順に並べてからカーソルの続きを切り出す
+// 全体の順序を持つインデックスが無いため、件数に比例して読み込む
+func (r *DynamoDBRepository) Incidents(ctx context.Context, cursor string, limit int) ([]entity.Incident, string, error) {
+	if _, err := decodeIncidentCursor(cursor); err != nil {
+		return nil, "", err
+	}
+	var incidents []entity.Incident
+	if err := r.db.Table(r.table).Scan().All(ctx, &incidents); err != nil {
+		return nil, "", err
+	}
+	return PageIncidents(incidents, cursor, limit)
+}
+
+func isMissingIndexError(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) &&
+		apiErr.ErrorCode() == "ValidationException" &&
+		strings.Contains(strings.ToLower(apiErr.ErrorMessage()), "index")
+}
+
+func (r *DynamoDBRepository) SaveIncidentEvent(ctx context.Context, event *entity.IncidentEvent) error {
+	if err := prepareIncidentEvent(event); err != nil {
+		return err
+	}
+	return r.db.Table(r.eventsTable).Put(event).If("attribute_not_exists('event_id')").Run(ctx)
+}
+
+func (r *DynamoDBRepository) IncidentEvents(ctx context.Context, channel string) ([]entity.IncidentEvent, error) {
+	var events []entity.IncidentEvent
+	err := r.db.Table(r.eventsTable).Get("channel_id", channel).Order(dynamo.Ascending).All(ctx, &events)
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *DynamoDBRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	lease := entity.Lease{Name: name, Holder: holder, ExpiresAt: now.Add(ttl).UnixMilli()}
+	// 自分が保持しているか、期限が切れている場合のみ上書きする
+	err := r.db.Table(r.leasesTable).Put(lease).
+		If("attribute_not_exists('name') OR 'holder' = ? OR 'expires_at' < ?", holder, now.UnixMilli()).
+		Run(ctx)
+	if err != nil {
+		if dynamo.IsCondCheckFailed(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *DynamoDBRepository) ReleaseLease(ctx context.Context, name, holder string) error {
+	err := r.db.Table(r.leasesTable).Delete("name", name).If("'holder' = ?", holder).Run(ctx)
+	if err != nil && !dynamo.IsCondCheckFailed(err) {
+		return err
+	}
+	return nil
+}
+
+func (r *DynamoDBRepository) SaveIncidentEmbedding(ctx context.Context, embedding *entity.IncidentEmbedding) error {
+	return r.db.Table(r.embeddingsTable).Put(embedding).Run(ctx)
+}
+
+func (r *DynamoDBRepository) IncidentEmbeddings(ctx context.Context) ([]entity.IncidentEmbedding, error) {
+	var embeddings []entity.IncidentEmbedding
+	if err := r.db.Table(r.embeddingsTable).Scan().All(ctx, &embeddings); err != nil {
+		return nil, err
+	}
+	return embeddings, nil
+}
@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+)
+
+// dynamodb-local に対してGSIのQueryと従来のScanを比較する
+// DYNAMO_LOCAL=1 go test -run '^$' -bench ActiveIncidents ./domain/repository/
+func BenchmarkActiveIncidents(b *testing.B) {
+	if os.Getenv("DYNAMO_LOCAL") == "" {
+		b.Skip("DYNAMO_LOCAL is not set")
+	}
+
+	ctx := context.Background()
+	r, err := NewDynamoDBRepository()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	const closed, active = 2000, 10
+	prefix := fmt.Sprintf("BENCH-%d", time.Now().UnixNano())
+	now := time.Now()
+	for i := 0; i < closed+active; i++ {
+		incident := &entity.Incident{
+			ChannelID: fmt.Sprintf("%s-%d", prefix, i),
+			StartedAt: now.Add(-time.Duration(i) * time.Minute),
+		}
+		if i >= active {
+			incident.ClosedAt = now
+		}
+		if err := r.SaveIncident(ctx, incident); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+	b.Run("Query", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.ActiveIncidents(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	b.Run("Scan", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			if _, err := r.scanActiveIncidents(ctx); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+)
+
+// incidentIndexRefreshInterval は他のレプリカが保存したベクトルを読み込み直す間隔
+const incidentIndexRefreshInterval = 5 * time.Minute
+
+// SimilarIncident は検索で見つかったインシデントと類似度
+type SimilarIncident struct {
+	ChannelID string
+	// コサイン類似度。インシデントの文章のうち最も近いものの値
+	Score float64
+	// 最も近かった文章の種類
+	Source string
+}
+
+// IncidentIndex はインシデントの埋め込みベクトルをメモリに持ち、コサイン類似度で総当たりに検索する
+// ベクトルは repository に保存し、起動時と一定間隔で読み込み直す
+type IncidentIndex struct {
+	mu         sync.RWMutex
+	repository IncidentEmbeddingRepositoryer
+	embeddings map[string]map[string]entity.IncidentEmbedding
+	loadedAt   time.Time
+	now        func() time.Time
+}
+
+func NewIncidentIndex(ctx context.Context, repository IncidentEmbeddingRepositoryer) (*IncidentIndex, error) {
+	x := &IncidentIndex{repository: repository, now: time.Now}
+	if err := x.load(ctx); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+func (x *IncidentIndex) load(ctx context.Context) error {
+	embeddings, err := x.repository.IncidentEmbeddings(ctx)
+	if err != nil {
+		return err
+	}
+	m := map[string]map[string]entity.IncidentEmbedding{}
+	for _, e := range embeddings {
+		if m[e.ChannelID] == nil {
+			m[e.ChannelID] = map[string]entity.IncidentEmbedding{}
+		}
+		m[e.ChannelID][e.Source] = e
+	}
+
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	x.embeddings = m
+	x.loadedAt = x.now()
+	return nil
+}
+
+func (x *IncidentIndex) refresh(ctx context.Context) error {
+	x.mu.RLock()
+	fresh := x.now().Sub(x.loadedAt) < incidentIndexRefreshInterval
+	x.mu.RUnlock()
+	if fresh {
+		return nil
+	}
+	return x.load(ctx)
+}
+
+// Save はベクトルを保存し、検索の対象に加える
+func (x *IncidentIndex) Save(ctx context.Context, embedding *entity.IncidentEmbedding) error {
+	if err := x.repository.SaveIncidentEmbedding(ctx, embedding); err != nil {
+		return err
+	}
+	x.mu.Lock()
+	defer x.mu.Unlock()
+	if x.embeddings[embedding.ChannelID] == nil {
+		x.embeddings[embedding.ChannelID] = map[string]entity.IncidentEmbedding{}
+	}
+	x.embeddings[embedding.ChannelID][embedding.Source] = *embedding
+	return nil
+}
+
+// incidentEmbedder は文章の埋め込みベクトルと、計算に使ったモデルを返す。AIRepositorier の一部
+type incidentEmbedder interface {
+	Embed(texts ...string) ([][]float32, string, error)
+}
+
+// backfillPageSize は Backfill で一度に読み込むインシデントの件数
+const backfillPageSize = 100
+
+// Backfill は復旧済みのインシデントの事象内容と最新の進捗サマリのうち、ベクトルが無いものを計算して保存し、保存した件数を返す
+// 検索を導入する前に復旧したインシデントも検索の対象にするため、yas3 migrate から呼ぶ
+func (x *IncidentIndex) Backfill(ctx context.Context, incidents IncidentRepositoryer, embedder incidentEmbedder) (int, error) {
+	saved := 0
+	cursor := ""
+	for {
+		page, next, err := incidents.Incidents(ctx, cursor, backfillPageSize)
+		if err != nil {
+			return saved, fmt.Errorf("failed to list incidents: %w", err)
+		}
+		for _, incident := range page {
+			if incident.RecoveredAt.IsZero() {
+				continue
+			}
+			for _, s := range []struct{ source, text string }{
+				{entity.EmbeddingSourceDescription, incident.Description},
+				{entity.EmbeddingSourceSummary, incident.LastSummary},
+			} {
+				if strings.TrimSpace(s.text) == "" || x.has(incident.ChannelID, s.source) {
+					continue
+				}
+				vectors, model, err := embedder.Embed(s.text)
+				if err != nil {
+					return saved, fmt.Errorf("failed to embed %s of %s: %w", s.source, incident.ChannelID, err)
+				}
+				err = x.Save(ctx, &entity.IncidentEmbedding{
+					ChannelID: incident.ChannelID,
+					Source:    s.source,
+					Model:     model,
+					Vector:    vectors[0],
+					UpdatedAt: x.now(),
+				})
+				if err != nil {
+					return saved, fmt.Errorf("failed to save %s of %s: %w", s.source, incident.ChannelID, err)
+				}
+				saved++
+			}
+		}
+		if next == "" {
+			return saved, nil
+		}
+		cursor = next
+	}
+}
+
+func (x *IncidentIndex) has(channelID, source string) bool {
+	x.mu.RLock()
+	defer x.mu.RUnlock()
+	_, ok := x.embeddings[channelID][source]
+	return ok
+}
+
+// Search は vector に近いインシデントを類似度の高い順に最大 limit 件返す
+// model が異なるベクトルと、include が false を返すインシデントは対象にしない
+func (x *IncidentIndex) Search(ctx context.Context, vector []float32, model string, limit int, include func(channelID string) bool) ([]SimilarIncident, error) {
+	if err := x.refresh(ctx); err != nil {
+		return nil, err
+	}
+
+	x.mu.RLock()
+	var results []SimilarIncident
+	for channelID, sources := range x.embeddings {
+		best := SimilarIncident{ChannelID: channelID, Score: math.Inf(-1)}
+		for source, e := range sources {
+			if e.Model != model || len(e.Vector) != len(vector) {
+				continue
+			}
+			if score := cosineSimilarity(vector, e.Vector); score > best.Score {
+				best.Score, best.Source = score, source
+			}
+		}
+		if best.Source != "" {
+			results = append(results, best)
+		}
+	}
+	x.mu.RUnlock()
+
+	slices.SortFunc(results, func(a, b SimilarIncident) int {
+		return cmp.Compare(b.Score, a.Score)
+	})
+
+	// 類似度の高い順に、対象にするインシデントだけを limit 件まで選ぶ
+	var found []SimilarIncident
+	for _, r := range results {
+		if len(found) >= limit {
+			break
+		}
+		if include == nil || include(r.ChannelID) {
+			found = append(found, r)
+		}
+	}
+	return found, nil
+}
+
+func cosineSimilarity(a, b []float32) float64 {
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
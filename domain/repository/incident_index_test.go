@@ -0,0 +1,110 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+)
+
+func TestIncidentIndex_Search(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+	a, b, c := testChannelID("a"), testChannelID("b"), testChannelID("c")
+	for _, e := range []entity.IncidentEmbedding{
+		{ChannelID: a, Source: entity.EmbeddingSourceDescription, Model: "m", Vector: []float32{1, 0}},
+		{ChannelID: b, Source: entity.EmbeddingSourceDescription, Model: "m", Vector: []float32{0, 1}},
+		// インシデントごとに最も近い文章の類似度を使う
+		{ChannelID: b, Source: entity.EmbeddingSourcePostMortem, Model: "m", Vector: []float32{1, 1}},
+		{ChannelID: c, Source: entity.EmbeddingSourceDescription, Model: "other", Vector: []float32{1, 0}},
+	} {
+		require.NoError(t, r.SaveIncidentEmbedding(ctx, &e))
+	}
+
+	index, err := repository.NewIncidentIndex(ctx, r)
+	require.NoError(t, err)
+
+	own := func(id string) bool { return id == a || id == b || id == c }
+	found, err := index.Search(ctx, []float32{1, 0}, "m", 5, own)
+	require.NoError(t, err)
+	require.Len(t, found, 2, "モデルが異なるベクトルは比較しない")
+	assert.Equal(t, a, found[0].ChannelID)
+	assert.InDelta(t, 1.0, found[0].Score, 1e-6)
+	assert.Equal(t, b, found[1].ChannelID)
+	assert.Equal(t, entity.EmbeddingSourcePostMortem, found[1].Source)
+	assert.InDelta(t, 0.7071, found[1].Score, 1e-3)
+
+	found, err = index.Search(ctx, []float32{1, 0}, "m", 1, func(id string) bool { return id == b })
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, b, found[0].ChannelID)
+
+	// 保存したベクトルはすぐに検索の対象になる
+	d := testChannelID("d")
+	require.NoError(t, index.Save(ctx, &entity.IncidentEmbedding{ChannelID: d, Source: entity.EmbeddingSourceSummary, Model: "m", Vector: []float32{0, 1}}))
+	found, err = index.Search(ctx, []float32{0, 1}, "m", 1, func(id string) bool { return id == d })
+	require.NoError(t, err)
+	require.Len(t, found, 1)
+	assert.Equal(t, d, found[0].ChannelID)
+}
+
+// countingEmbedder は文章ごとの呼び出し回数を数え、決まったベクトルを返す
+type countingEmbedder map[string]int
+
+func (e countingEmbedder) Embed(texts ...string) ([][]float32, string, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		e[text]++
+		vectors[i] = []float32{1, 0}
+	}
+	return vectors, "m", nil
+}
+
+func TestIncidentIndex_Backfill(t *testing.T) {
+	ctx := context.Background()
+	r := newTestDBRepository(t)
+	recovered, embedded, active := testChannelID("recovered"), testChannelID("embedded"), testChannelID("active")
+	now := time.Now()
+	for _, i := range []entity.Incident{
+		{ChannelID: recovered, Description: "APIが落ちた", LastSummary: "ロールバックした", StartedAt: now, RecoveredAt: now},
+		{ChannelID: embedded, Description: "バッチが遅い", StartedAt: now, RecoveredAt: now},
+		{ChannelID: active, Description: "DBが遅い", StartedAt: now},
+	} {
+		require.NoError(t, r.SaveIncident(ctx, &i))
+	}
+	require.NoError(t, r.SaveIncidentEmbedding(ctx, &entity.IncidentEmbedding{ChannelID: embedded, Source: entity.EmbeddingSourceDescription, Model: "old", Vector: []float32{0, 1}}))
+
+	index, err := repository.NewIncidentIndex(ctx, r)
+	require.NoError(t, err)
+	embedder := countingEmbedder{}
+	_, err = index.Backfill(ctx, r, embedder)
+	require.NoError(t, err)
+
+	got := map[string]string{}
+	embeddings, err := r.IncidentEmbeddings(ctx)
+	require.NoError(t, err)
+	for _, e := range embeddings {
+		if e.ChannelID == recovered || e.ChannelID == embedded || e.ChannelID == active {
+			got[e.ChannelID+"/"+e.Source] = e.Model
+		}
+	}
+	assert.Equal(t, map[string]string{
+		recovered + "/" + entity.EmbeddingSourceDescription: "m",
+		recovered + "/" + entity.EmbeddingSourceSummary:     "m",
+		// 計算済みのベクトルはそのまま使う
+		embedded + "/" + entity.EmbeddingSourceDescription: "old",
+	}, got, "未復旧のインシデントは対象にしない")
+
+	// 保存したベクトルは計算し直さない
+	_, err = index.Backfill(ctx, r, embedder)
+	require.NoError(t, err)
+	assert.Equal(t, 1, embedder["APIが落ちた"])
+	assert.Equal(t, 1, embedder["ロールバックした"])
+	assert.Zero(t, embedder["バッチが遅い"])
+	assert.Zero(t, embedder["DBが遅い"])
+}
@@ -0,0 +1,223 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/azure"
+	"github.com/openai/openai-go/option"
+	"github.com/pyama86/YAS3/domain/entity"
+)
+
+// LLMProvider はプロンプトを送り、応答のテキストを返す
+type LLMProvider interface {
+	Complete(ctx context.Context, model, prompt string) (string, error)
+}
+
+// Embedder は文章の埋め込みベクトルを返す。埋め込みに対応したプロバイダーだけが実装する
+type Embedder interface {
+	Embed(ctx context.Context, model string, texts []string) ([][]float32, error)
+}
+
+const (
+	defaultAzureAPIVersion  = "2025-01-01-preview"
+	defaultAnthropicBaseURL = "https://api.anthropic.com"
+	anthropicAPIVersion     = "2023-06-01"
+	// Anthropic は応答の最大トークン数の指定が必須
+	anthropicMaxTokens = 4096
+)
+
+// defaultAPIKeyEnv は api_key_env を省略した場合に API キーを読む環境変数
+var defaultAPIKeyEnv = map[string]string{
+	entity.AIProviderOpenAI:    "OPENAI_API_KEY",
+	entity.AIProviderAzure:     "AZURE_OPENAI_KEY",
+	entity.AIProviderAnthropic: "ANTHROPIC_API_KEY",
+}
+
+func newLLMProvider(c entity.AIProviderConfig) (LLMProvider, error) {
+	keyEnv := c.APIKeyEnv
+	if keyEnv == "" {
+		keyEnv = defaultAPIKeyEnv[c.Type]
+	}
+	var key string
+	if keyEnv != "" {
+		key = os.Getenv(keyEnv)
+	}
+
+	switch c.Type {
+	case entity.AIProviderOpenAI:
+		if key == "" {
+			return nil, fmt.Errorf("%s is not set", keyEnv)
+		}
+		options := []option.RequestOption{option.WithAPIKey(key)}
+		if c.BaseURL != "" {
+			options = append(options, option.WithBaseURL(c.BaseURL))
+		}
+		return newOpenAIProvider(options...), nil
+	case entity.AIProviderAzure:
+		if key == "" {
+			return nil, fmt.Errorf("%s is not set", keyEnv)
+		}
+		if c.BaseURL == "" {
+			return nil, fmt.Errorf("base_url is required for azure")
+		}
+		version := c.APIVersion
+		if version == "" {
+			version = defaultAzureAPIVersion
+		}
+		return newOpenAIProvider(azure.WithEndpoint(c.BaseURL, version), azure.WithAPIKey(key)), nil
+	case entity.AIProviderOpenAICompatible:
+		// Ollama や vLLM などはキーが不要な場合がある
+		if c.BaseURL == "" {
+			return nil, fmt.Errorf("base_url is required for openai_compatible")
+		}
+		return newOpenAIProvider(option.WithBaseURL(c.BaseURL), option.WithAPIKey(key)), nil
+	case entity.AIProviderAnthropic:
+		if key == "" {
+			return nil, fmt.Errorf("%s is not set", keyEnv)
+		}
+		baseURL := c.BaseURL
+		if baseURL == "" {
+			baseURL = defaultAnthropicBaseURL
+		}
+		return &anthropicProvider{baseURL: strings.TrimSuffix(baseURL, "/"), apiKey: key, client: http.DefaultClient}, nil
+	}
+	return nil, fmt.Errorf("unknown provider type %q", c.Type)
+}
+
+// openAIProvider は OpenAI の Chat Completions API と互換の API を呼び出す
+type openAIProvider struct {
+	client openai.Client
+}
+
+func newOpenAIProvider(options ...option.RequestOption) *openAIProvider {
+	return &openAIProvider{client: openai.NewClient(options...)}
+}
+
+func (p *openAIProvider) Complete(ctx context.Context, model, prompt string) (string, error) {
+	resp, err := p.client.Chat.Completions.New(ctx, openai.ChatCompletionNewParams{
+		Messages: []openai.ChatCompletionMessageParamUnion{
+			openai.UserMessage(prompt),
+		},
+		Model: model,
+	})
+	if err != nil {
+		return "", err
+	}
+	if len(resp.Choices) == 0 {
+		return "", fmt.Errorf("no response from OpenAI")
+	}
+	return resp.Choices[0].Message.Content, nil
+}
+
+func (p *openAIProvider) Embed(ctx context.Context, model string, texts []string) ([][]float32, error) {
+	resp, err := p.client.Embeddings.New(ctx, openai.EmbeddingNewParams{
+		Input:          openai.EmbeddingNewParamsInputUnion{OfArrayOfStrings: texts},
+		Model:          model,
+		EncodingFormat: openai.EmbeddingNewParamsEncodingFormatFloat,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(texts) {
+		return nil, fmt.Errorf("got %d embeddings for %d texts", len(resp.Data), len(texts))
+	}
+	vectors := make([][]float32, len(texts))
+	for _, d := range resp.Data {
+		if d.Index < 0 || int(d.Index) >= len(texts) {
+			return nil, fmt.Errorf("unexpected embedding index %d", d.Index)
+		}
+		v := make([]float32, len(d.Embedding))
+		for i, f := range d.Embedding {
+			v[i] = float32(f)
+		}
+		vectors[d.Index] = v
+	}
+	return vectors, nil
+}
+
+// anthropicProvider は Anthropic の Messages API を呼び出す
+type anthropicProvider struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+type anthropicMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type anthropicRequest struct {
+	Model     string             `json:"model"`
+	MaxTokens int                `json:"max_tokens"`
+	Messages  []anthropicMessage `json:"messages"`
+}
+
+type anthropicResponse struct {
+	Content []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	} `json:"content"`
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+func (p *anthropicProvider) Complete(ctx context.Context, model, prompt string) (string, error) {
+	body, err := json.Marshal(anthropicRequest{
+		Model:     model,
+		MaxTokens: anthropicMaxTokens,
+		Messages:  []anthropicMessage{{Role: "user", Content: prompt}},
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.baseURL+"/v1/messages", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("content-type", "application/json")
+	req.Header.Set("x-api-key", p.apiKey)
+	req.Header.Set("anthropic-version", anthropicAPIVersion)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+
+	var res anthropicResponse
+	if err := json.Unmarshal(b, &res); err != nil {
+		return "", fmt.Errorf("anthropic: %s: %s", resp.Status, b)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if res.Error != nil {
+			return "", fmt.Errorf("anthropic: %s: %s: %s", resp.Status, res.Error.Type, res.Error.Message)
+		}
+		return "", fmt.Errorf("anthropic: %s", resp.Status)
+	}
+
+	var text strings.Builder
+	for _, c := range res.Content {
+		if c.Type == "text" {
+			text.WriteString(c.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("no response from Anthropic")
+	}
+	return text.String(), nil
+}
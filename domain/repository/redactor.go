@@ -0,0 +1,152 @@
+package repository
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+)
+
+// 組み込みの検出器の名前
+const (
+	RedactAWSAccessKey = "aws_access_key"
+	RedactAWSSecretKey = "aws_secret_key"
+	RedactJWT          = "jwt"
+	RedactBearerToken  = "bearer_token"
+	RedactEmail        = "email"
+	RedactPhone        = "phone"
+	RedactIP           = "ip"
+)
+
+// secretDetectors は元に戻すと漏洩になるため、restore に指定できない
+var secretDetectors = []string{RedactAWSAccessKey, RedactAWSSecretKey, RedactJWT, RedactBearerToken}
+
+// builtinRedactionPatterns は組み込みの検出器。上から順に適用する
+var builtinRedactionPatterns = []entity.RedactionPattern{
+	{Name: RedactJWT, Regex: `\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`},
+	{Name: RedactBearerToken, Regex: `(?i)\bbearer\s+([A-Za-z0-9\-._~+/]{8,}=*)`},
+	{Name: RedactAWSAccessKey, Regex: `\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`},
+	{Name: RedactAWSSecretKey, Regex: `(?i)aws_secret_access_key["']?\s*[=:]\s*["']?([A-Za-z0-9/+=]{40})`},
+	{Name: RedactEmail, Regex: `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`},
+	{Name: RedactIP, Regex: `\b(?:(?:25[0-5]|2[0-4]\d|1?\d?\d)\.){3}(?:25[0-5]|2[0-4]\d|1?\d?\d)\b|\b(?:[0-9A-Fa-f]{1,4}:){7}[0-9A-Fa-f]{1,4}\b`},
+	{Name: RedactPhone, Regex: `\+\d{1,3}[-\s]?\d{1,4}[-\s]?\d{1,4}[-\s]?\d{3,4}\b|\b0\d{1,4}-\d{1,4}-\d{3,4}\b|\b0[789]0\d{8}\b`},
+}
+
+type redactionRule struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// Redactor は AI に送る前に秘密情報や個人情報をプレースホルダーに置き換える
+// 同じ値は同じプレースホルダーになるため、元の文章があれば Restore で戻せる
+type Redactor struct {
+	rules   []redactionRule
+	restore map[string]bool
+	// プレースホルダーから元の値を推測されないよう、プロセスごとの鍵でハッシュする
+	key []byte
+}
+
+// NewRedactor は設定から Redactor を作る。無効にされている場合は nil を返す
+// nil の Redactor は何も置き換えない
+func NewRedactor(c entity.RedactionConfig) (*Redactor, error) {
+	if c.Disabled {
+		return nil, nil
+	}
+
+	r := &Redactor{restore: map[string]bool{}, key: make([]byte, 32)}
+	if _, err := rand.Read(r.key); err != nil {
+		return nil, fmt.Errorf("failed to generate redaction key: %w", err)
+	}
+
+	for _, p := range builtinRedactionPatterns {
+		if slices.Contains(c.DisableDetectors, p.Name) {
+			continue
+		}
+		r.rules = append(r.rules, redactionRule{name: p.Name, re: regexp.MustCompile(p.Regex)})
+	}
+	for _, p := range c.Patterns {
+		re, err := regexp.Compile(p.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("redaction pattern %s: %w", p.Name, err)
+		}
+		r.rules = append(r.rules, redactionRule{name: p.Name, re: re})
+	}
+	for _, name := range c.Restore {
+		if slices.Contains(secretDetectors, name) {
+			return nil, fmt.Errorf("redaction: %s cannot be restored", name)
+		}
+		r.restore[name] = true
+	}
+	return r, nil
+}
+
+func (r *Redactor) placeholder(name, value string) string {
+	mac := hmac.New(sha256.New, r.key)
+	mac.Write([]byte(name + "\x00" + value))
+	return fmt.Sprintf("[%s_%s]", strings.ToUpper(name), hex.EncodeToString(mac.Sum(nil))[:8])
+}
+
+// replace は text の中で rule に一致した値ごとに f を呼び、その戻り値に置き換えた文字列を返す
+func (rule redactionRule) replace(text string, f func(value string) string) string {
+	matches := rule.re.FindAllStringSubmatchIndex(text, -1)
+	if len(matches) == 0 {
+		return text
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		// キャプチャグループがある場合は最初のグループだけを対象にする
+		if len(m) >= 4 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		b.WriteString(text[last:start])
+		b.WriteString(f(text[start:end]))
+		last = end
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
+
+// Redact は検出した値をプレースホルダーに置き換える
+func (r *Redactor) Redact(text string) string {
+	if r == nil {
+		return text
+	}
+	for _, rule := range r.rules {
+		text = rule.replace(text, func(value string) string {
+			return r.placeholder(rule.name, value)
+		})
+	}
+	return text
+}
+
+// Restore は restore に指定した検出器のプレースホルダーを、sources に含まれる元の値に戻す
+// 秘密情報のプレースホルダーはそのまま残る
+func (r *Redactor) Restore(text string, sources ...string) string {
+	if r == nil || len(r.restore) == 0 {
+		return text
+	}
+	var pairs []string
+	for _, rule := range r.rules {
+		if !r.restore[rule.name] {
+			continue
+		}
+		for _, src := range sources {
+			rule.replace(src, func(value string) string {
+				pairs = append(pairs, r.placeholder(rule.name, value), value)
+				return value
+			})
+		}
+	}
+	if len(pairs) == 0 {
+		return text
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
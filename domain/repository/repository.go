@@ -2,14 +2,34 @@ package repository
 
 import (
 	"context"
+	"errors"
 
 	"github.com/pyama86/YAS3/domain/entity"
 )
 
+var (
+	// ErrIncidentConflict は読み込み後に他の更新が行われていたため保存できなかったことを表す
+	ErrIncidentConflict = errors.New("incident was updated concurrently")
+	// ErrInvalidCursor はページングのカーソルが解釈できないことを表す
+	ErrInvalidCursor = errors.New("invalid cursor")
+)
+
 type IncidentRepositoryer interface {
 	FindIncidentByChannel(context.Context, string) (*entity.Incident, error)
+	// 読み込み時のVersionと保存先のVersionが異なる場合は ErrIncidentConflict を返す
+	// 保存に成功するとVersionがインクリメントされる
 	SaveIncident(context.Context, *entity.Incident) error
 	ActiveIncidents(context.Context) ([]entity.Incident, error)
+	// クローズ済みを含むインシデントを検知の新しい順(同時刻はチャンネルIDの昇順)に最大limit件返す
+	// cursorには前回返されたカーソルを渡し、続きが無い場合は空のカーソルを返す
+	// カーソルは最後に返したインシデントの位置を指すため、ページの間に増減があっても重複や抜けが起きない
+	Incidents(ctx context.Context, cursor string, limit int) ([]entity.Incident, string, error)
+}
+
+type IncidentEventRepositoryer interface {
+	SaveIncidentEvent(context.Context, *entity.IncidentEvent) error
+	// 発生順に返す
+	IncidentEvents(context.Context, string) ([]entity.IncidentEvent, error)
 }
 
 type ServiceRepositoryer interface {
@@ -24,6 +44,7 @@ type IncidentLevelRepositoryer interface {
 
 type Repository interface {
 	IncidentRepositoryer
+	IncidentEventRepositoryer
 	ServiceRepositoryer
 	IncidentLevelRepositoryer
 	SlackRepositoryer
@@ -31,6 +52,7 @@ type Repository interface {
 
 type RepositoryFacade struct {
 	IncidentRepositoryer
+	IncidentEventRepositoryer
 	ServiceRepositoryer
 	IncidentLevelRepositoryer
 	SlackRepositoryer
@@ -42,12 +64,14 @@ type PostMortemRepositoryer interface {
 
 func NewRepository(
 	incidentRepository IncidentRepositoryer,
+	incidentEventRepository IncidentEventRepositoryer,
 	serviceRepository ServiceRepositoryer,
 	incidentLevelRepository IncidentLevelRepositoryer,
 	slackRepository SlackRepositoryer,
 ) Repository {
 	return RepositoryFacade{
 		IncidentRepositoryer:      incidentRepository,
+		IncidentEventRepositoryer: incidentEventRepository,
 		ServiceRepositoryer:       serviceRepository,
 		IncidentLevelRepositoryer: incidentLevelRepository,
 		SlackRepositoryer:         slackRepository,
@@ -22,6 +22,7 @@ type SlackRepositoryer interface {
 	GetChannelByName(name string) (*slack.Channel, error)
 	GetChannelByID(channelID string) (*slack.Channel, error)
 	PostMessage(channelID string, opts ...slack.MsgOption) (string, string, error)
+	PostEphemeral(channelID, userID string, opts ...slack.MsgOption) (string, error)
 	UpdateMessage(channelID, ts string, opts ...slack.MsgOption)
 	DeleteMessage(channelID, ts string)
 	OpenView(triggerID string, view slack.ModalViewRequest) error
@@ -32,6 +33,7 @@ type SlackRepositoryer interface {
 	GetChannelHistory(channelID, oldest, latest string, limit int) ([]slack.Message, error)
 	GetThreadReplies(channelID, threadTS string) ([]slack.Message, error)
 	GetChannelMessagesAfter(channelID, afterTS string) ([]slack.Message, error)
+	HasMessagesSince(channelID, oldest string) (bool, error)
 	GetAllChannelMessages(channelID string) ([]slack.Message, error)
 	GetUserPreferredName(user *slack.User) string
 	UploadFile(workspackeURL, userID, channelID, filename, title, content string) (string, error)
@@ -272,6 +274,16 @@ func (h *SlackRepository) PostMessage(channelID string, opts ...slack.MsgOption)
 	return channel, ts, resultErr
 }
 
+// PostEphemeral は指定したユーザーにだけ見えるメッセージを投稿する
+func (h *SlackRepository) PostEphemeral(channelID, userID string, opts ...slack.MsgOption) (string, error) {
+	ts, err := h.client.PostEphemeral(channelID, userID, opts...)
+	if err != nil {
+		slog.Error("Failed to PostEphemeral", slog.Any("channelID", channelID), slog.Any("userID", userID), slog.Any("err", err))
+		return "", err
+	}
+	return ts, nil
+}
+
 func (h *SlackRepository) UpdateMessage(channelID, ts string, opts ...slack.MsgOption) {
 	go func() {
 		err := retry.Retry(10, 3*time.Second, func() error {
@@ -575,6 +587,49 @@ func (h *SlackRepository) GetChannelMessagesAfter(channelID, afterTS string) ([]
 	return allMessages, nil
 }
 
+// oldest 以降にチャンネルかスレッドでボット以外の発言があるかを返す
+// ボットの投稿へのスレッドの返信も数えるため、ボットを含む直近の履歴の latest_reply を確認し、新しい返信があるスレッドだけを取得する
+func (h *SlackRepository) HasMessagesSince(channelID, oldest string) (bool, error) {
+	var resp *slack.GetConversationHistoryResponse
+	err := retry.Retry(3, time.Second*3, func() error {
+		var err error
+		resp, err = h.client.GetConversationHistory(&slack.GetConversationHistoryParameters{
+			ChannelID: channelID,
+			Limit:     200,
+		})
+		if err != nil {
+			slog.Warn("HasMessagesSince", slog.Any("channelID", channelID), slog.Any("err", err))
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to get channel history: %w", err)
+	}
+
+	for _, msg := range resp.Messages {
+		isBot := msg.BotID != "" || msg.SubType == "bot_message"
+		isSystem := msg.SubType == "channel_join" || msg.SubType == "channel_leave"
+		if msg.Timestamp >= oldest && !isBot && !isSystem {
+			return true, nil
+		}
+		if msg.LatestReply == "" || msg.LatestReply < oldest {
+			continue
+		}
+		// GetThreadReplies はボットの返信を除外する
+		replies, err := h.GetThreadReplies(channelID, msg.Timestamp)
+		if err != nil {
+			return false, err
+		}
+		for _, reply := range replies {
+			if reply.Timestamp != msg.Timestamp && reply.Timestamp >= oldest {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
 // チャンネル内の全メッセージを取得（スレッド・メンション変換込み）
 func (h *SlackRepository) GetAllChannelMessages(channelID string) ([]slack.Message, error) {
 	var allMessages []slack.Message
@@ -0,0 +1,596 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	_ "github.com/lib/pq"
+	"github.com/pyama86/YAS3/domain/entity"
+	_ "modernc.org/sqlite"
+)
+
+const (
+	SQLDriverSQLite   = "sqlite"
+	SQLDriverPostgres = "postgres"
+
+	defaultSQLiteDSN = "yas3.db"
+
+	// 文字列比較で時系列順に並ぶよう、UTC に揃えて固定幅で保存する。書き込みは sqlTime を通すこと
+	sqlTimeLayout = "2006-01-02T15:04:05.000000000Z07:00"
+)
+
+// スキーマのマイグレーション。適用済みのものは schema_migrations に記録される
+// 既存の要素は変更せず、末尾に追加していくこと
+var sqlMigrations = []string{
+	`CREATE TABLE IF NOT EXISTS incidents (
+		channel_id TEXT PRIMARY KEY,
+		description TEXT NOT NULL DEFAULT '',
+		urgency_level TEXT NOT NULL DEFAULT '',
+		level INTEGER NOT NULL DEFAULT 0,
+		service_id INTEGER NOT NULL DEFAULT 0,
+		handler_user_id TEXT NOT NULL DEFAULT '',
+		created_user_id TEXT NOT NULL DEFAULT '',
+		recovered_user_id TEXT NOT NULL DEFAULT '',
+		disable_timer BOOLEAN NOT NULL DEFAULT FALSE,
+		post_mortem_url TEXT NOT NULL DEFAULT '',
+		reopened_at TEXT,
+		reopened_user_id TEXT NOT NULL DEFAULT '',
+		started_at TEXT,
+		recovered_at TEXT,
+		closed_at TEXT,
+		last_summary TEXT NOT NULL DEFAULT '',
+		last_summary_at TEXT,
+		last_processed_message_ts TEXT NOT NULL DEFAULT '',
+		linked_channels TEXT NOT NULL DEFAULT '[]'
+	)`,
+	`CREATE INDEX IF NOT EXISTS incidents_closed_at_idx ON incidents (closed_at)`,
+	`CREATE TABLE IF NOT EXISTS incident_events (
+		channel_id TEXT NOT NULL,
+		event_id TEXT NOT NULL,
+		type TEXT NOT NULL,
+		actor_user_id TEXT NOT NULL DEFAULT '',
+		occurred_at TEXT NOT NULL,
+		metadata TEXT NOT NULL DEFAULT '{}',
+		PRIMARY KEY (channel_id, event_id)
+	)`,
+	`ALTER TABLE incidents ADD COLUMN version INTEGER NOT NULL DEFAULT 0`,
+	`ALTER TABLE incidents ADD COLUMN alert_fingerprint TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE incidents ADD COLUMN last_checkpoint_at TEXT`,
+	`CREATE TABLE IF NOT EXISTS leases (
+		name TEXT PRIMARY KEY,
+		holder TEXT NOT NULL,
+		expires_at BIGINT NOT NULL
+	)`,
+	`ALTER TABLE incidents ADD COLUMN escalations TEXT NOT NULL DEFAULT '[]'`,
+	`ALTER TABLE incidents ADD COLUMN roles TEXT NOT NULL DEFAULT '{}'`,
+	`CREATE TABLE IF NOT EXISTS incident_embeddings (
+		channel_id TEXT NOT NULL,
+		source TEXT NOT NULL,
+		model TEXT NOT NULL,
+		vector TEXT NOT NULL,
+		updated_at TEXT NOT NULL,
+		PRIMARY KEY (channel_id, source)
+	)`,
+	`CREATE INDEX IF NOT EXISTS incidents_started_at_idx ON incidents (started_at DESC, channel_id)`,
+	`ALTER TABLE incidents ADD COLUMN handler_assigned_at TEXT`,
+}
+
+var incidentColumns = []string{
+	"channel_id",
+	"description",
+	"urgency_level",
+	"level",
+	"service_id",
+	"handler_user_id",
+	"created_user_id",
+	"recovered_user_id",
+	"disable_timer",
+	"post_mortem_url",
+	"reopened_at",
+	"reopened_user_id",
+	"started_at",
+	"recovered_at",
+	"closed_at",
+	"last_summary",
+	"last_summary_at",
+	"last_processed_message_ts",
+	"linked_channels",
+	"version",
+	"alert_fingerprint",
+	"last_checkpoint_at",
+	"escalations",
+	"roles",
+	"handler_assigned_at",
+}
+
+type SQLRepository struct {
+	db     *sql.DB
+	driver string
+}
+
+// NewSQLRepository は PostgreSQL または SQLite をバックエンドとするリポジトリを作成し、マイグレーションを適用する
+func NewSQLRepository(driver, dsn string) (*SQLRepository, error) {
+	switch driver {
+	case SQLDriverSQLite:
+		if dsn == "" {
+			dsn = defaultSQLiteDSN
+		}
+	case SQLDriverPostgres:
+		if dsn == "" {
+			return nil, fmt.Errorf("DB_DSN is required for %s", driver)
+		}
+	default:
+		return nil, fmt.Errorf("unsupported sql driver: %s", driver)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	if driver == SQLDriverSQLite {
+		// SQLiteは書き込みが直列化されるため、接続を1本に絞ってロック競合を避ける
+		db.SetMaxOpenConns(1)
+	}
+
+	r := &SQLRepository{db: db, driver: driver}
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := r.migrate(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate schema: %w", err)
+	}
+	return r, nil
+}
+
+func (r *SQLRepository) Close() error {
+	return r.db.Close()
+}
+
+// Migrate は未適用のマイグレーションを適用する
+func (r *SQLRepository) Migrate(ctx context.Context) error {
+	return r.migrate(ctx)
+}
+
+func (r *SQLRepository) migrate(ctx context.Context) error {
+	_, err := r.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		applied_at TEXT NOT NULL
+	)`)
+	if err != nil {
+		return err
+	}
+
+	var current int
+	if err := r.db.QueryRowContext(ctx, `SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return err
+	}
+
+	for i := current; i < len(sqlMigrations); i++ {
+		version := i + 1
+		tx, err := r.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, sqlMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if _, err := tx.ExecContext(ctx, r.rebind(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`), version, sqlTime(time.Now())); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("migration %d: %w", version, err)
+		}
+	}
+	return nil
+}
+
+// プレースホルダを ? で記述し、PostgreSQLの場合は $1, $2... に変換する
+func (r *SQLRepository) rebind(query string) string {
+	if r.driver != SQLDriverPostgres {
+		return query
+	}
+	var b strings.Builder
+	n := 0
+	for _, c := range query {
+		if c == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+func (r *SQLRepository) FindIncidentByChannel(ctx context.Context, channel string) (*entity.Incident, error) {
+	query := fmt.Sprintf("SELECT %s FROM incidents WHERE channel_id = ?", strings.Join(incidentColumns, ", "))
+	incident, err := scanIncident(r.db.QueryRowContext(ctx, r.rebind(query), channel))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return incident, nil
+}
+
+func (r *SQLRepository) SaveIncident(ctx context.Context, incident *entity.Incident) error {
+	values, err := incidentValues(incident)
+	if err != nil {
+		return err
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(incidentColumns)), ", ")
+	updates := make([]string, 0, len(incidentColumns)-1)
+	for _, c := range incidentColumns[1:] {
+		updates = append(updates, fmt.Sprintf("%s = excluded.%s", c, c))
+	}
+	// 読み込み時からversionが変わっていなければ更新する
+	query := fmt.Sprintf(
+		"INSERT INTO incidents (%s) VALUES (%s) ON CONFLICT (channel_id) DO UPDATE SET %s WHERE incidents.version = ?",
+		strings.Join(incidentColumns, ", "),
+		placeholders,
+		strings.Join(updates, ", "),
+	)
+	result, err := r.db.ExecContext(ctx, r.rebind(query), append(values, incident.Version)...)
+	if err != nil {
+		return err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrIncidentConflict
+	}
+	incident.Version++
+	return nil
+}
+
+// closed_atが未設定のものを取得
+func (r *SQLRepository) ActiveIncidents(ctx context.Context) ([]entity.Incident, error) {
+	query := fmt.Sprintf("SELECT %s FROM incidents WHERE closed_at IS NULL", strings.Join(incidentColumns, ", "))
+	rows, err := r.db.QueryContext(ctx, r.rebind(query))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var incidents []entity.Incident
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, err
+		}
+		incidents = append(incidents, *incident)
+	}
+	return incidents, rows.Err()
+}
+
+// 検知の新しい順にカーソルの続きから取得する
+func (r *SQLRepository) Incidents(ctx context.Context, cursor string, limit int) ([]entity.Incident, string, error) {
+	after, err := decodeIncidentCursor(cursor)
+	if err != nil {
+		return nil, "", err
+	}
+
+	// 続きがあるかを判定するために1件多く取得する
+	where, args := "", []any{}
+	if after != nil {
+		startedAt := sqlTime(after.startedAt)
+		where = "WHERE started_at < ? OR (started_at = ? AND channel_id > ?)"
+		args = append(args, startedAt, startedAt, after.channelID)
+	}
+	query := fmt.Sprintf("SELECT %s FROM incidents %s ORDER BY started_at DESC, channel_id LIMIT ?", strings.Join(incidentColumns, ", "), where)
+	rows, err := r.db.QueryContext(ctx, r.rebind(query), append(args, limit+1)...)
+	if err != nil {
+		return nil, "", err
+	}
+	defer rows.Close()
+
+	var incidents []entity.Incident
+	for rows.Next() {
+		incident, err := scanIncident(rows)
+		if err != nil {
+			return nil, "", err
+		}
+		incidents = append(incidents, *incident)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	if len(incidents) <= limit {
+		return incidents, "", nil
+	}
+	incidents = incidents[:limit]
+	return incidents, encodeIncidentCursor(&incidents[limit-1]), nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanIncident(row rowScanner) (*entity.Incident, error) {
+	var (
+		incident                                                                                         entity.Incident
+		reopenedAt, startedAt, recoveredAt, closedAt, lastSummaryAt, lastCheckpointAt, handlerAssignedAt sql.NullString
+		linkedChannels, escalations, roles                                                               string
+	)
+	err := row.Scan(
+		&incident.ChannelID,
+		&incident.Description,
+		&incident.Urgency,
+		&incident.Level,
+		&incident.ServiceID,
+		&incident.HandlerUserID,
+		&incident.CreatedUserID,
+		&incident.RecoveredUserID,
+		&incident.DisableTimer,
+		&incident.PostMortemURL,
+		&reopenedAt,
+		&incident.ReopenedUserID,
+		&startedAt,
+		&recoveredAt,
+		&closedAt,
+		&incident.LastSummary,
+		&lastSummaryAt,
+		&incident.LastProcessedMessageTS,
+		&linkedChannels,
+		&incident.Version,
+		&incident.AlertFingerprint,
+		&lastCheckpointAt,
+		&escalations,
+		&roles,
+		&handlerAssignedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, t := range []struct {
+		src sql.NullString
+		dst *time.Time
+	}{
+		{reopenedAt, &incident.ReopenedAt},
+		{startedAt, &incident.StartedAt},
+		{recoveredAt, &incident.RecoveredAt},
+		{closedAt, &incident.ClosedAt},
+		{lastSummaryAt, &incident.LastSummaryAt},
+		{lastCheckpointAt, &incident.LastCheckpointAt},
+		{handlerAssignedAt, &incident.HandlerAssignedAt},
+	} {
+		if *t.dst, err = parseSQLTime(t.src); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := json.Unmarshal([]byte(linkedChannels), &incident.LinkedChannels); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal linked_channels: %w", err)
+	}
+	if err := json.Unmarshal([]byte(escalations), &incident.Escalations); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal escalations: %w", err)
+	}
+	if err := json.Unmarshal([]byte(roles), &incident.Roles); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal roles: %w", err)
+	}
+	return &incident, nil
+}
+
+// incidentColumns と同じ順序で値を返す。versionは保存後の値になる
+func incidentValues(incident *entity.Incident) ([]any, error) {
+	linkedChannels := incident.LinkedChannels
+	if linkedChannels == nil {
+		linkedChannels = []entity.LinkedChannel{}
+	}
+	linked, err := json.Marshal(linkedChannels)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal linked_channels: %w", err)
+	}
+	escalations := incident.Escalations
+	if escalations == nil {
+		escalations = []entity.Escalation{}
+	}
+	escalated, err := json.Marshal(escalations)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal escalations: %w", err)
+	}
+	roles := incident.Roles
+	if roles == nil {
+		roles = map[string][]string{}
+	}
+	assigned, err := json.Marshal(roles)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal roles: %w", err)
+	}
+
+	return []any{
+		incident.ChannelID,
+		incident.Description,
+		incident.Urgency,
+		incident.Level,
+		incident.ServiceID,
+		incident.HandlerUserID,
+		incident.CreatedUserID,
+		incident.RecoveredUserID,
+		incident.DisableTimer,
+		incident.PostMortemURL,
+		formatSQLTime(incident.ReopenedAt),
+		incident.ReopenedUserID,
+		formatSQLTime(incident.StartedAt),
+		formatSQLTime(incident.RecoveredAt),
+		formatSQLTime(incident.ClosedAt),
+		incident.LastSummary,
+		formatSQLTime(incident.LastSummaryAt),
+		incident.LastProcessedMessageTS,
+		string(linked),
+		incident.Version + 1,
+		incident.AlertFingerprint,
+		formatSQLTime(incident.LastCheckpointAt),
+		string(escalated),
+		string(assigned),
+		formatSQLTime(incident.HandlerAssignedAt),
+	}, nil
+}
+
+// sqlTime は t を UTC の sqlTimeLayout で書式化する。タイムゾーンが混ざると文字列の順序が時刻の順序にならない
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqlTimeLayout)
+}
+
+// ゼロ値はNULLとして保存する
+func formatSQLTime(t time.Time) sql.NullString {
+	if t.IsZero() {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: sqlTime(t), Valid: true}
+}
+
+func parseSQLTime(s sql.NullString) (time.Time, error) {
+	if !s.Valid || s.String == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(sqlTimeLayout, s.String)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed to parse time %q: %w", s.String, err)
+	}
+	return t, nil
+}
+
+func (r *SQLRepository) SaveIncidentEvent(ctx context.Context, event *entity.IncidentEvent) error {
+	if err := prepareIncidentEvent(event); err != nil {
+		return err
+	}
+	metadata := event.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	b, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("failed to marshal metadata: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, r.rebind(
+		`INSERT INTO incident_events (channel_id, event_id, type, actor_user_id, occurred_at, metadata) VALUES (?, ?, ?, ?, ?, ?)`),
+		event.ChannelID,
+		event.EventID,
+		string(event.Type),
+		event.ActorUserID,
+		sqlTime(event.OccurredAt),
+		string(b),
+	)
+	return err
+}
+
+func (r *SQLRepository) IncidentEvents(ctx context.Context, channel string) ([]entity.IncidentEvent, error) {
+	rows, err := r.db.QueryContext(ctx, r.rebind(
+		`SELECT channel_id, event_id, type, actor_user_id, occurred_at, metadata FROM incident_events WHERE channel_id = ? ORDER BY event_id`),
+		channel,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var events []entity.IncidentEvent
+	for rows.Next() {
+		var (
+			event                           entity.IncidentEvent
+			eventType, occurredAt, metadata string
+		)
+		if err := rows.Scan(&event.ChannelID, &event.EventID, &eventType, &event.ActorUserID, &occurredAt, &metadata); err != nil {
+			return nil, err
+		}
+		event.Type = entity.IncidentEventType(eventType)
+		if event.OccurredAt, err = parseSQLTime(sql.NullString{String: occurredAt, Valid: true}); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(metadata), &event.Metadata); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal metadata: %w", err)
+		}
+		if len(event.Metadata) == 0 {
+			event.Metadata = nil
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *SQLRepository) AcquireLease(ctx context.Context, name, holder string, ttl time.Duration) (bool, error) {
+	now := time.Now()
+	// 自分が保持しているか、期限が切れている場合のみ上書きする
+	result, err := r.db.ExecContext(ctx, r.rebind(
+		`INSERT INTO leases (name, holder, expires_at) VALUES (?, ?, ?)
+		ON CONFLICT (name) DO UPDATE SET holder = excluded.holder, expires_at = excluded.expires_at
+		WHERE leases.holder = excluded.holder OR leases.expires_at < ?`),
+		name,
+		holder,
+		now.Add(ttl).UnixMilli(),
+		now.UnixMilli(),
+	)
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func (r *SQLRepository) ReleaseLease(ctx context.Context, name, holder string) error {
+	_, err := r.db.ExecContext(ctx, r.rebind(`DELETE FROM leases WHERE name = ? AND holder = ?`), name, holder)
+	return err
+}
+
+func (r *SQLRepository) SaveIncidentEmbedding(ctx context.Context, embedding *entity.IncidentEmbedding) error {
+	vector, err := json.Marshal(embedding.Vector)
+	if err != nil {
+		return fmt.Errorf("failed to marshal vector: %w", err)
+	}
+	_, err = r.db.ExecContext(ctx, r.rebind(
+		`INSERT INTO incident_embeddings (channel_id, source, model, vector, updated_at) VALUES (?, ?, ?, ?, ?)
+		ON CONFLICT (channel_id, source) DO UPDATE SET model = excluded.model, vector = excluded.vector, updated_at = excluded.updated_at`),
+		embedding.ChannelID,
+		embedding.Source,
+		embedding.Model,
+		string(vector),
+		sqlTime(embedding.UpdatedAt),
+	)
+	return err
+}
+
+func (r *SQLRepository) IncidentEmbeddings(ctx context.Context) ([]entity.IncidentEmbedding, error) {
+	rows, err := r.db.QueryContext(ctx, `SELECT channel_id, source, model, vector, updated_at FROM incident_embeddings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var embeddings []entity.IncidentEmbedding
+	for rows.Next() {
+		var (
+			embedding         entity.IncidentEmbedding
+			vector, updatedAt string
+		)
+		if err := rows.Scan(&embedding.ChannelID, &embedding.Source, &embedding.Model, &vector, &updatedAt); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(vector), &embedding.Vector); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal vector: %w", err)
+		}
+		if embedding.UpdatedAt, err = parseSQLTime(sql.NullString{String: updatedAt, Valid: true}); err != nil {
+			return nil, err
+		}
+		embeddings = append(embeddings, embedding)
+	}
+	return embeddings, rows.Err()
+}
@@ -30,7 +30,8 @@ func GetMaxTokens() int {
 
 // トークン計算ユーティリティ
 type TokenCalculator struct {
-	encoder *tiktoken.Tiktoken
+	encoder  *tiktoken.Tiktoken
+	redactor *Redactor
 }
 
 // 新しいトークン計算機を作成
@@ -62,10 +63,11 @@ func (tc *TokenCalculator) FormatMessage(msg slack.Message) string {
 	t := time.Unix(int64(timestamp), 0)
 
 	// メッセージの基本形式: "2006-01-02 15:04:05 ユーザー名: メッセージ内容"
+	// 内容は AI に送るため、秘密情報や個人情報を伏せる
 	text := fmt.Sprintf("%s %s: %s",
 		t.Format("2006-01-02 15:04:05"),
 		msg.User,
-		msg.Text)
+		tc.redactor.Redact(msg.Text))
 
 	// スレッドの場合は分かりやすくする
 	if msg.ThreadTimestamp != "" && msg.ThreadTimestamp != msg.Timestamp {
@@ -75,6 +77,11 @@ func (tc *TokenCalculator) FormatMessage(msg slack.Message) string {
 	return text
 }
 
+// SetRedactor は FormatMessage でメッセージの内容を伏せる Redactor を設定する
+func (tc *TokenCalculator) SetRedactor(r *Redactor) {
+	tc.redactor = r
+}
+
 // メッセージリストのトータルトークン数を計算
 func (tc *TokenCalculator) CountMessagesTokens(messages []slack.Message, basePrompt string) int {
 	var allText strings.Builder
@@ -0,0 +1,411 @@
+package handler
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+const (
+	AlertStatusFiring   = "firing"
+	AlertStatusResolved = "resolved"
+
+	defaultAlertServiceLabel  = "service"
+	defaultAlertSeverityLabel = "severity"
+	// 対応表に無い緊急度のアラートは調査が必要なものとして扱う
+	defaultAlertUrgency = "warning"
+)
+
+// Alert は監視システムから受け取ったアラートを共通の形式にしたもの
+type Alert struct {
+	Fingerprint  string
+	Status       string
+	Labels       map[string]string
+	Annotations  map[string]string
+	StartsAt     time.Time
+	GeneratorURL string
+}
+
+// Title はインシデントの事象内容に使うアラートの概要を返す
+func (a *Alert) Title() string {
+	for _, v := range []string{a.Annotations["summary"], a.Annotations["description"], a.Labels["alertname"]} {
+		if v != "" {
+			return v
+		}
+	}
+	return "アラートを受信しました"
+}
+
+// AlertHandlerer はアラートを処理する
+type AlertHandlerer interface {
+	HandleAlert(alert *Alert) error
+}
+
+// Alertmanager の webhook_config が送信するペイロード
+type alertmanagerPayload struct {
+	Status string `json:"status"`
+	Alerts []struct {
+		Status       string            `json:"status"`
+		Labels       map[string]string `json:"labels"`
+		Annotations  map[string]string `json:"annotations"`
+		StartsAt     time.Time         `json:"startsAt"`
+		GeneratorURL string            `json:"generatorURL"`
+		Fingerprint  string            `json:"fingerprint"`
+	} `json:"alerts"`
+}
+
+// Alertmanager 以外の監視システムから送信するためのペイロード
+type genericAlertPayload struct {
+	Fingerprint string            `json:"fingerprint"`
+	Status      string            `json:"status"`
+	Title       string            `json:"title"`
+	Description string            `json:"description"`
+	URL         string            `json:"url"`
+	Labels      map[string]string `json:"labels"`
+}
+
+// RegisterAlertRoutes はアラートを受け付けるエンドポイントを mux に登録する
+// リクエストは Authorization: Bearer <token> で認証する
+func RegisterAlertRoutes(mux *http.ServeMux, token string, alertHandler AlertHandlerer) {
+	a := &alertHTTPHandler{token: token, alertHandler: alertHandler}
+	mux.HandleFunc("POST /alerts/alertmanager", a.handleAlertmanager)
+	mux.HandleFunc("POST /alerts/generic", a.handleGeneric)
+}
+
+type alertHTTPHandler struct {
+	token        string
+	alertHandler AlertHandlerer
+}
+
+func (a *alertHTTPHandler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if !bearerAuthorized(r, a.token) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return false
+	}
+	return true
+}
+
+func (a *alertHTTPHandler) handleAlertmanager(w http.ResponseWriter, r *http.Request) {
+	if !a.authorize(w, r) {
+		return
+	}
+
+	var payload alertmanagerPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSlackRequestBody)).Decode(&payload); err != nil {
+		slog.Warn("failed to decode alertmanager payload", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	alerts := make([]*Alert, 0, len(payload.Alerts))
+	for _, v := range payload.Alerts {
+		alert := &Alert{
+			Fingerprint:  v.Fingerprint,
+			Status:       v.Status,
+			Labels:       v.Labels,
+			Annotations:  v.Annotations,
+			StartsAt:     v.StartsAt,
+			GeneratorURL: v.GeneratorURL,
+		}
+		if alert.Status == "" {
+			alert.Status = payload.Status
+		}
+		alerts = append(alerts, alert)
+	}
+	a.dispatch(w, alerts)
+}
+
+func (a *alertHTTPHandler) handleGeneric(w http.ResponseWriter, r *http.Request) {
+	if !a.authorize(w, r) {
+		return
+	}
+
+	var payload genericAlertPayload
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxSlackRequestBody)).Decode(&payload); err != nil {
+		slog.Warn("failed to decode alert payload", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if payload.Title == "" && len(payload.Labels) == 0 {
+		http.Error(w, "title or labels is required", http.StatusBadRequest)
+		return
+	}
+
+	alert := &Alert{
+		Fingerprint: payload.Fingerprint,
+		Status:      payload.Status,
+		Labels:      payload.Labels,
+		Annotations: map[string]string{
+			"summary":     payload.Title,
+			"description": payload.Description,
+		},
+		StartsAt:     timeNow(),
+		GeneratorURL: payload.URL,
+	}
+	if alert.Status == "" {
+		alert.Status = AlertStatusFiring
+	}
+	a.dispatch(w, []*Alert{alert})
+}
+
+// 作成に失敗した場合は送信元に再送してもらうため、処理を待ってから応答する
+func (a *alertHTTPHandler) dispatch(w http.ResponseWriter, alerts []*Alert) {
+	failed := 0
+	for _, alert := range alerts {
+		if alert.Labels == nil {
+			alert.Labels = map[string]string{}
+		}
+		if alert.Annotations == nil {
+			alert.Annotations = map[string]string{}
+		}
+		if alert.Fingerprint == "" {
+			alert.Fingerprint = alertFingerprint(alert)
+		}
+		if alert.Status != AlertStatusFiring && alert.Status != AlertStatusResolved {
+			slog.Warn("unknown alert status", slog.String("status", alert.Status), slog.String("fingerprint", alert.Fingerprint))
+			continue
+		}
+		if err := a.alertHandler.HandleAlert(alert); err != nil {
+			slog.Error("Failed to handle alert", slog.String("fingerprint", alert.Fingerprint), slog.Any("err", err))
+			failed++
+		}
+	}
+	if failed > 0 {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// フィンガープリントが送られてこない場合はラベルから決める
+func alertFingerprint(alert *Alert) string {
+	keys := make([]string, 0, len(alert.Labels))
+	for k := range alert.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	h := sha256.New()
+	for _, k := range keys {
+		_, _ = io.WriteString(h, k+"\x00"+alert.Labels[k]+"\x00")
+	}
+	if len(keys) == 0 {
+		_, _ = io.WriteString(h, alert.Annotations["summary"])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// alertClaimTTL はアラートからインシデントを作成する権利を保持する時間
+// 未クローズのインシデントの一覧(DynamoDB では GSI)に作成したインシデントが反映されるまで、同じアラートで作成しないようにする
+const alertClaimTTL = 10 * time.Minute
+
+// SetAlertLeases はアラートのフィンガープリントをレプリカ間で排他するリースを設定する
+// 設定しない場合はこのプロセスの中でだけ排他する
+func (h *CallbackHandler) SetAlertLeases(leases repository.LeaseRepositoryer) {
+	h.alertLeases = leases
+}
+
+// claimAlert はフィンガープリントのインシデントを作成する権利を取得し、取得できた場合は解放する関数を返す
+// 他のレプリカや直前の処理が作成中または作成した直後の場合は nil を返す
+func (h *CallbackHandler) claimAlert(fingerprint string) (func(), error) {
+	if h.alertLeases == nil {
+		return func() {}, nil
+	}
+	// 同じプロセスの繰り返しの発火も排他するため、取得のたびに別の保持者にする
+	holder, err := leaseHolderID()
+	if err != nil {
+		return nil, err
+	}
+	name := "alert-" + fingerprint
+	ok, err := h.alertLeases.AcquireLease(h.ctx, name, holder, alertClaimTTL)
+	if err != nil {
+		// yas3 migrate の前でテーブルが無い場合なども、アラートを取りこぼさないようプロセス内の排他だけで作成する
+		slog.Warn("failed to acquire alert lease, falling back to in-process deduplication", slog.String("fingerprint", fingerprint), slog.Any("err", err))
+		return func() {}, nil
+	}
+	if !ok {
+		return nil, nil
+	}
+	return func() {
+		if err := h.alertLeases.ReleaseLease(h.ctx, name, holder); err != nil {
+			slog.Error("Failed to release alert lease", slog.String("fingerprint", fingerprint), slog.Any("err", err))
+		}
+	}, nil
+}
+
+// HandleAlert は発火したアラートからインシデントを作成し、解消したアラートを既存のインシデントチャンネルに通知する
+// 同じフィンガープリントの未クローズのインシデントがあれば新たに作成しない
+// 作成する前にフィンガープリントのリースを取得し、一覧に反映される前の再発火や他のレプリカに届いた同じアラートでも重複して作成しない
+func (h *CallbackHandler) HandleAlert(alert *Alert) error {
+	// 同時に届いた同じアラートで重複してチャンネルを作らないよう直列に処理する
+	h.alertMu.Lock()
+	defer h.alertMu.Unlock()
+
+	slog.Info("HandleAlert", slog.String("fingerprint", alert.Fingerprint), slog.String("status", alert.Status))
+
+	incident, err := h.findIncidentByAlertFingerprint(alert.Fingerprint)
+	if err != nil {
+		return err
+	}
+
+	if alert.Status == AlertStatusResolved {
+		if incident == nil {
+			slog.Info("no incident for resolved alert", slog.String("fingerprint", alert.Fingerprint))
+			return nil
+		}
+		_, _, err := h.repository.PostMessage(incident.ChannelID, slack.MsgOptionBlocks(blocks.AlertResolved(alert.Title(), alert.GeneratorURL)...))
+		if err != nil {
+			return fmt.Errorf("failed to PostMessage: %w", err)
+		}
+		return nil
+	}
+
+	if incident != nil {
+		// 復旧宣言後に再度発火した場合のみ知らせる。対応中の繰り返しの通知は無視する
+		if !incident.RecoveredAt.IsZero() {
+			_, _, err := h.repository.PostMessage(incident.ChannelID, slack.MsgOptionBlocks(blocks.AlertRefired(alert.Title(), alert.GeneratorURL)...))
+			if err != nil {
+				return fmt.Errorf("failed to PostMessage: %w", err)
+			}
+		}
+		return nil
+	}
+
+	release, err := h.claimAlert(alert.Fingerprint)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		slog.Info("incident for alert is already being created", slog.String("fingerprint", alert.Fingerprint))
+		return nil
+	}
+
+	service, err := h.alertService(alert)
+	if err != nil {
+		release()
+		return err
+	}
+
+	incident, err = h.createIncident(&incidentRequest{
+		service:          service,
+		description:      alert.Title(),
+		urgency:          h.alertUrgency(alert),
+		alertFingerprint: alert.Fingerprint,
+	})
+	if err != nil {
+		// 作成に失敗した場合は次の発火で作り直せるよう解放する
+		release()
+		return fmt.Errorf("failed to createIncident: %w", err)
+	}
+	// 作成した場合は一覧に反映されるまで解放せず、期限切れに任せる
+
+	_, _, err = h.repository.PostMessage(incident.ChannelID, slack.MsgOptionBlocks(blocks.AlertFired(alert.Title(), alert.Annotations["description"], alert.Labels, alert.GeneratorURL)...))
+	if err != nil {
+		slog.Error("Failed to post alert detail", slog.Any("err", err))
+	}
+	return nil
+}
+
+func (h *CallbackHandler) findIncidentByAlertFingerprint(fingerprint string) (*entity.Incident, error) {
+	incidents, err := h.repository.ActiveIncidents(h.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to ActiveIncidents: %w", err)
+	}
+	for _, incident := range incidents {
+		if incident.AlertFingerprint == fingerprint {
+			return &incident, nil
+		}
+	}
+	return nil, nil
+}
+
+// アラートのラベルからサービスを決める
+// alert_matchers がすべて一致するサービス、サービス名のラベルが一致するサービス、default_service_id の順に探す
+func (h *CallbackHandler) alertService(alert *Alert) (*entity.Service, error) {
+	services, err := h.repository.Services(h.ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to Services: %w", err)
+	}
+
+	labels := make(map[string]string, len(alert.Labels))
+	for k, v := range alert.Labels {
+		// 設定ファイルのキーは小文字になるため合わせる
+		labels[strings.ToLower(k)] = v
+	}
+	for _, service := range services {
+		if len(service.AlertMatchers) == 0 {
+			continue
+		}
+		matched := true
+		for k, v := range service.AlertMatchers {
+			if labels[strings.ToLower(k)] != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return &service, nil
+		}
+	}
+
+	label := defaultAlertServiceLabel
+	defaultServiceID := 0
+	if h.config != nil {
+		if h.config.Alert.ServiceLabel != "" {
+			label = h.config.Alert.ServiceLabel
+		}
+		defaultServiceID = h.config.Alert.DefaultServiceID
+	}
+	if name := labels[strings.ToLower(label)]; name != "" {
+		for _, service := range services {
+			if service.Name == name {
+				return &service, nil
+			}
+		}
+	}
+
+	if defaultServiceID != 0 {
+		service, err := h.repository.ServiceByID(h.ctx, defaultServiceID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to ServiceByID: %w", err)
+		}
+		return service, nil
+	}
+	return nil, fmt.Errorf("no service matched for alert %s", alert.Fingerprint)
+}
+
+// アラートのラベルから緊急度を決める
+func (h *CallbackHandler) alertUrgency(alert *Alert) string {
+	label := defaultAlertSeverityLabel
+	var urgencies map[string]string
+	if h.config != nil {
+		if h.config.Alert.SeverityLabel != "" {
+			label = h.config.Alert.SeverityLabel
+		}
+		urgencies = h.config.Alert.Urgencies
+	}
+
+	severity := strings.ToLower(alert.Labels[label])
+	if urgency, ok := urgencies[severity]; ok {
+		if _, ok := blocks.UrgencyMap[urgency]; ok {
+			return urgency
+		}
+		slog.Warn("invalid urgency in alert config", slog.String("severity", severity), slog.String("urgency", urgency))
+	}
+	if _, ok := blocks.UrgencyMap[severity]; ok {
+		return severity
+	}
+	return defaultAlertUrgency
+}
@@ -0,0 +1,259 @@
+package handler_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+type mockAlertHandler struct {
+	alerts []*handler.Alert
+}
+
+func (m *mockAlertHandler) HandleAlert(alert *handler.Alert) error {
+	m.alerts = append(m.alerts, alert)
+	return nil
+}
+
+func TestAlertRoutes(t *testing.T) {
+	newServer := func() (*http.ServeMux, *mockAlertHandler) {
+		mux := http.NewServeMux()
+		ah := &mockAlertHandler{}
+		handler.RegisterAlertRoutes(mux, "secret", ah)
+		return mux, ah
+	}
+	post := func(mux *http.ServeMux, path, token, body string) int {
+		req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	t.Run("トークンが不正な場合は401", func(t *testing.T) {
+		mux, ah := newServer()
+		assert.Equal(t, http.StatusUnauthorized, post(mux, "/alerts/generic", "wrong", `{"title":"x"}`))
+		assert.Equal(t, http.StatusUnauthorized, post(mux, "/alerts/alertmanager", "", `{}`))
+		assert.Empty(t, ah.alerts)
+	})
+
+	t.Run("Alertmanager のアラートを個別に処理する", func(t *testing.T) {
+		mux, ah := newServer()
+		body := `{
+			"version": "4",
+			"status": "firing",
+			"alerts": [
+				{"status":"firing","labels":{"alertname":"HighLatency","service":"api"},"annotations":{"summary":"レイテンシが悪化"},"fingerprint":"abc123","generatorURL":"http://prom/graph"},
+				{"status":"resolved","labels":{"alertname":"DiskFull","service":"db"},"annotations":{}}
+			]
+		}`
+		assert.Equal(t, http.StatusOK, post(mux, "/alerts/alertmanager", "secret", body))
+		require.Len(t, ah.alerts, 2)
+		assert.Equal(t, "abc123", ah.alerts[0].Fingerprint)
+		assert.Equal(t, handler.AlertStatusFiring, ah.alerts[0].Status)
+		assert.Equal(t, "レイテンシが悪化", ah.alerts[0].Title())
+		assert.Equal(t, "http://prom/graph", ah.alerts[0].GeneratorURL)
+		assert.Equal(t, handler.AlertStatusResolved, ah.alerts[1].Status)
+		assert.NotEmpty(t, ah.alerts[1].Fingerprint, "フィンガープリントはラベルから補完される")
+		assert.Equal(t, "DiskFull", ah.alerts[1].Title())
+	})
+
+	t.Run("汎用ペイロードは同じラベルなら同じフィンガープリントになる", func(t *testing.T) {
+		mux, ah := newServer()
+		body := `{"title":"APIが500を返している","labels":{"service":"api","severity":"critical"}}`
+		assert.Equal(t, http.StatusOK, post(mux, "/alerts/generic", "secret", body))
+		assert.Equal(t, http.StatusOK, post(mux, "/alerts/generic", "secret", body))
+		require.Len(t, ah.alerts, 2)
+		assert.Equal(t, handler.AlertStatusFiring, ah.alerts[0].Status)
+		assert.Equal(t, ah.alerts[0].Fingerprint, ah.alerts[1].Fingerprint)
+	})
+
+	t.Run("タイトルもラベルも無い場合は400", func(t *testing.T) {
+		mux, ah := newServer()
+		assert.Equal(t, http.StatusBadRequest, post(mux, "/alerts/generic", "secret", `{"status":"firing"}`))
+		assert.Empty(t, ah.alerts)
+	})
+}
+
+func TestCallbackHandler_HandleAlert(t *testing.T) {
+	cfgRepo := &mockConfigRepo{
+		services: []entity.Service{
+			{ID: 1, Name: "api"},
+			{ID: 2, Name: "db", AlertMatchers: map[string]string{"team": "storage"}},
+			{ID: 3, Name: "other"},
+			{ID: 4, Name: "pager", OnCallSchedule: "sre"},
+		},
+	}
+	config := &repository.Config{
+		OnCallScheduleList: []entity.OnCallSchedule{
+			{ID: "sre", Rotation: "daily", Start: "2000-01-01T00:00", Members: []string{"UONCALL"}},
+		},
+		Alert: entity.AlertConfig{
+			Urgencies:        map[string]string{"page": "critical"},
+			DefaultServiceID: 3,
+		},
+	}
+	newHandler := func() (*handler.CallbackHandler, *mockIncidentRepo, *mockSlackRepo) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{}}
+		slackRepo := &mockSlackRepo{}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+		return handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, config), incRepo, slackRepo
+	}
+	firing := func(fingerprint string, labels map[string]string) *handler.Alert {
+		return &handler.Alert{
+			Fingerprint: fingerprint,
+			Status:      handler.AlertStatusFiring,
+			Labels:      labels,
+			Annotations: map[string]string{"summary": "APIが500を返している"},
+		}
+	}
+	onlyIncident := func(t *testing.T, incRepo *mockIncidentRepo) *entity.Incident {
+		t.Helper()
+		require.Len(t, incRepo.data, 1)
+		for _, inc := range incRepo.data {
+			return inc
+		}
+		return nil
+	}
+
+	t.Run("ラベルからサービスと緊急度を決めてインシデントを作成する", func(t *testing.T) {
+		h, incRepo, _ := newHandler()
+		require.NoError(t, h.HandleAlert(firing("fp1", map[string]string{"service": "api", "severity": "page"})))
+
+		inc := onlyIncident(t, incRepo)
+		assert.Equal(t, 1, inc.ServiceID)
+		assert.Equal(t, "critical", inc.Urgency)
+		assert.Equal(t, "fp1", inc.AlertFingerprint)
+		assert.Equal(t, "APIが500を返している", inc.Description)
+		assert.Empty(t, inc.CreatedUserID)
+		require.Len(t, incRepo.events, 1)
+		assert.Equal(t, "fp1", incRepo.events[0].Metadata["alert_fingerprint"])
+	})
+
+	t.Run("alert_matchers と default_service_id でサービスを決める", func(t *testing.T) {
+		h, incRepo, _ := newHandler()
+		require.NoError(t, h.HandleAlert(firing("fp2", map[string]string{"team": "storage", "severity": "warning"})))
+		inc := onlyIncident(t, incRepo)
+		assert.Equal(t, 2, inc.ServiceID)
+		assert.Equal(t, "warning", inc.Urgency)
+
+		h, incRepo, _ = newHandler()
+		require.NoError(t, h.HandleAlert(firing("fp3", map[string]string{"severity": "unknown"})))
+		inc = onlyIncident(t, incRepo)
+		assert.Equal(t, 3, inc.ServiceID)
+		assert.Equal(t, "warning", inc.Urgency)
+	})
+
+	t.Run("オンコールの担当者を招待してハンドラーにする", func(t *testing.T) {
+		h, incRepo, slackRepo := newHandler()
+		require.NoError(t, h.HandleAlert(firing("fp4", map[string]string{"service": "pager"})))
+
+		inc := onlyIncident(t, incRepo)
+		assert.Equal(t, "UONCALL", inc.HandlerUserID)
+		assert.Contains(t, slackRepo.invited[inc.ChannelID], "UONCALL")
+		require.Len(t, incRepo.events, 2)
+		assert.Equal(t, entity.IncidentEventHandlerAssigned, incRepo.events[1].Type)
+		assert.Equal(t, "sre", incRepo.events[1].Metadata["oncall_schedule"])
+	})
+
+	t.Run("同じフィンガープリントのアラートは重複して作成しない", func(t *testing.T) {
+		h, incRepo, slackRepo := newHandler()
+		alert := firing("fp1", map[string]string{"service": "api"})
+		require.NoError(t, h.HandleAlert(alert))
+		inc := onlyIncident(t, incRepo)
+		incRepo.active = []entity.Incident{*inc}
+		posted := len(slackRepo.messages[inc.ChannelID])
+
+		for i := 0; i < 5; i++ {
+			require.NoError(t, h.HandleAlert(alert))
+		}
+		assert.Len(t, incRepo.data, 1)
+		assert.Len(t, slackRepo.messages[inc.ChannelID], posted, "対応中の再通知は投稿しない")
+
+		// 復旧宣言後の再発火は知らせる
+		incRepo.active[0].RecoveredAt = inc.StartedAt
+		require.NoError(t, h.HandleAlert(alert))
+		assert.Len(t, incRepo.data, 1)
+		require.Len(t, slackRepo.messages[inc.ChannelID], posted+1)
+		assert.Contains(t, slackRepo.messages[inc.ChannelID][posted], "再度発火")
+	})
+
+	t.Run("一覧に反映される前の再発火や他のレプリカに届いたアラートでも重複して作成しない", func(t *testing.T) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{}}
+		leases := &mockLeaseRepo{now: time.Now()}
+		var replicas []*handler.CallbackHandler
+		for i := 0; i < 2; i++ {
+			repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, &mockSlackRepo{})
+			h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, config)
+			h.SetAlertLeases(leases)
+			replicas = append(replicas, h)
+		}
+
+		// incRepo.active を更新しないことで、未クローズの一覧への反映が遅れている状態にする
+		alert := firing("fp1", map[string]string{"service": "api"})
+		require.NoError(t, replicas[0].HandleAlert(alert))
+		require.NoError(t, replicas[0].HandleAlert(alert))
+		require.NoError(t, replicas[1].HandleAlert(alert))
+		// チャンネル名が同じになると data では区別できないため、作成の記録で数える
+		created := func() int {
+			n := 0
+			for _, ev := range incRepo.events {
+				if ev.Type == entity.IncidentEventCreated {
+					n++
+				}
+			}
+			return n
+		}
+		assert.Equal(t, 1, created())
+
+		// 期限が切れた後は一覧で重複を判定する
+		leases.now = leases.now.Add(time.Hour)
+		incRepo.active = []entity.Incident{*onlyIncident(t, incRepo)}
+		require.NoError(t, replicas[1].HandleAlert(alert))
+		assert.Equal(t, 1, created())
+	})
+
+	t.Run("解消したアラートは既存のインシデントチャンネルに投稿する", func(t *testing.T) {
+		h, incRepo, slackRepo := newHandler()
+		alert := firing("fp1", map[string]string{"service": "api"})
+		require.NoError(t, h.HandleAlert(alert))
+		inc := onlyIncident(t, incRepo)
+		incRepo.active = []entity.Incident{*inc}
+		posted := len(slackRepo.messages[inc.ChannelID])
+
+		alert.Status = handler.AlertStatusResolved
+		require.NoError(t, h.HandleAlert(alert))
+		require.Len(t, slackRepo.messages[inc.ChannelID], posted+1)
+		assert.Contains(t, slackRepo.messages[inc.ChannelID][posted], "アラートが解消しました")
+		assert.True(t, incRepo.data[inc.ChannelID].RecoveredAt.IsZero(), "復旧宣言は人が行う")
+	})
+
+	t.Run("インシデントが無い解消通知は無視する", func(t *testing.T) {
+		h, incRepo, slackRepo := newHandler()
+		alert := firing("fp9", map[string]string{"service": "api"})
+		alert.Status = handler.AlertStatusResolved
+		require.NoError(t, h.HandleAlert(alert))
+		assert.Empty(t, incRepo.data)
+		assert.Empty(t, slackRepo.messages)
+	})
+
+	t.Run("サービスを決められない場合はエラー", func(t *testing.T) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{}}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, &mockSlackRepo{})
+		h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, &repository.Config{})
+		assert.Error(t, h.HandleAlert(firing("fp1", map[string]string{"service": "unknown"})))
+		assert.Empty(t, incRepo.data)
+	})
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strconv"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+)
+
+const (
+	defaultAPIPageSize = 50
+	maxAPIPageSize     = 200
+
+	apiStatusAll    = "all"
+	apiStatusActive = "active"
+)
+
+// RegisterAPIRoutes はインシデントを参照する読み取り専用の API を mux に登録する
+// リクエストは Authorization: Bearer <token> で認証する
+func RegisterAPIRoutes(mux *http.ServeMux, token string, repo repository.Repository) {
+	a := &apiHandler{token: token, repository: repo}
+	mux.HandleFunc("GET /api/v1/incidents", a.authorized(a.listIncidents))
+	mux.HandleFunc("GET /api/v1/incidents/{channelID}", a.authorized(a.getIncident))
+	mux.HandleFunc("GET /api/v1/incidents/{channelID}/events", a.authorized(a.listIncidentEvents))
+	mux.HandleFunc("GET /api/v1/incidents/{channelID}/postmortem", a.authorized(a.getPostMortem))
+}
+
+type apiHandler struct {
+	token      string
+	repository repository.Repository
+}
+
+// APIIncident は API で返すインシデント
+type APIIncident struct {
+	entity.Incident
+	ServiceName string `json:"service_name,omitempty"`
+	// active / recovered / closed
+	Status string `json:"status"`
+}
+
+// APIIncidentList はインシデント一覧のレスポンス
+type APIIncidentList struct {
+	Incidents []APIIncident `json:"incidents"`
+	// 続きを取得する場合に cursor に指定する。続きが無い場合は空
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// APIIncidentEventList はインシデントの変更履歴のレスポンス
+type APIIncidentEventList struct {
+	Events []entity.IncidentEvent `json:"events"`
+}
+
+// APIPostMortem はポストモーテムのレスポンス
+type APIPostMortem struct {
+	URL string `json:"url"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+func (a *apiHandler) authorized(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !bearerAuthorized(r, a.token) {
+			writeJSON(w, http.StatusUnauthorized, apiError{Error: "unauthorized"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (a *apiHandler) listIncidents(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	limit := defaultAPIPageSize
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAPIPageSize {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: "limit must be between 1 and " + strconv.Itoa(maxAPIPageSize)})
+			return
+		}
+		limit = n
+	}
+
+	var (
+		incidents []entity.Incident
+		next      string
+		err       error
+	)
+	switch status := q.Get("status"); status {
+	case "", apiStatusAll:
+		incidents, next, err = a.repository.Incidents(r.Context(), q.Get("cursor"), limit)
+	case apiStatusActive:
+		incidents, next, err = a.activeIncidents(r, q.Get("cursor"), limit)
+	default:
+		writeJSON(w, http.StatusBadRequest, apiError{Error: "status must be all or active"})
+		return
+	}
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			writeJSON(w, http.StatusBadRequest, apiError{Error: err.Error()})
+			return
+		}
+		a.internalError(w, "failed to list incidents", err)
+		return
+	}
+
+	res := APIIncidentList{Incidents: make([]APIIncident, 0, len(incidents)), NextCursor: next}
+	for i := range incidents {
+		res.Incidents = append(res.Incidents, a.toAPIIncident(r, &incidents[i]))
+	}
+	writeJSON(w, http.StatusOK, res)
+}
+
+// 未クローズのインシデントは件数が少ないため、まとめて取得して Incidents と同じ順序とカーソルで切り出す
+func (a *apiHandler) activeIncidents(r *http.Request, cursor string, limit int) ([]entity.Incident, string, error) {
+	incidents, err := a.repository.ActiveIncidents(r.Context())
+	if err != nil {
+		return nil, "", err
+	}
+	return repository.PageIncidents(incidents, cursor, limit)
+}
+
+func (a *apiHandler) getIncident(w http.ResponseWriter, r *http.Request) {
+	incident, ok := a.findIncident(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, a.toAPIIncident(r, incident))
+}
+
+func (a *apiHandler) listIncidentEvents(w http.ResponseWriter, r *http.Request) {
+	incident, ok := a.findIncident(w, r)
+	if !ok {
+		return
+	}
+	events, err := a.repository.IncidentEvents(r.Context(), incident.ChannelID)
+	if err != nil {
+		a.internalError(w, "failed to get incident events", err)
+		return
+	}
+	if events == nil {
+		events = []entity.IncidentEvent{}
+	}
+	writeJSON(w, http.StatusOK, APIIncidentEventList{Events: events})
+}
+
+func (a *apiHandler) getPostMortem(w http.ResponseWriter, r *http.Request) {
+	incident, ok := a.findIncident(w, r)
+	if !ok {
+		return
+	}
+	if incident.PostMortemURL == "" {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "postmortem not found"})
+		return
+	}
+	writeJSON(w, http.StatusOK, APIPostMortem{URL: incident.PostMortemURL})
+}
+
+func (a *apiHandler) findIncident(w http.ResponseWriter, r *http.Request) (*entity.Incident, bool) {
+	incident, err := a.repository.FindIncidentByChannel(r.Context(), r.PathValue("channelID"))
+	if err != nil {
+		a.internalError(w, "failed to find incident", err)
+		return nil, false
+	}
+	if incident == nil {
+		writeJSON(w, http.StatusNotFound, apiError{Error: "incident not found"})
+		return nil, false
+	}
+	return incident, true
+}
+
+func (a *apiHandler) toAPIIncident(r *http.Request, incident *entity.Incident) APIIncident {
+	res := APIIncident{Incident: *incident, Status: apiStatusActive}
+	switch {
+	case !incident.ClosedAt.IsZero():
+		res.Status = "closed"
+	case !incident.RecoveredAt.IsZero():
+		res.Status = "recovered"
+	}
+	if service, err := a.repository.ServiceByID(r.Context(), incident.ServiceID); err == nil && service != nil {
+		res.ServiceName = service.Name
+	}
+	return res
+}
+
+func (a *apiHandler) internalError(w http.ResponseWriter, msg string, err error) {
+	slog.Error(msg, slog.Any("err", err))
+	writeJSON(w, http.StatusInternalServerError, apiError{Error: "internal server error"})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write response", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+func TestAPIRoutes(t *testing.T) {
+	base := time.Date(2025, 3, 1, 10, 0, 0, 0, time.UTC)
+	incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{
+		"C1": {ChannelID: "C1", ServiceID: 1, StartedAt: base, ClosedAt: base.Add(2 * time.Hour), PostMortemURL: "https://example.com/pm"},
+		"C2": {ChannelID: "C2", ServiceID: 1, StartedAt: base.Add(time.Hour), RecoveredAt: base.Add(90 * time.Minute)},
+		"C3": {ChannelID: "C3", ServiceID: 2, StartedAt: base.Add(2 * time.Hour)},
+	}}
+	incRepo.active = []entity.Incident{*incRepo.data["C2"], *incRepo.data["C3"]}
+	incRepo.events = []entity.IncidentEvent{
+		{ChannelID: "C1", EventID: "1", Type: entity.IncidentEventCreated},
+		{ChannelID: "C1", EventID: "2", Type: entity.IncidentEventClosed},
+	}
+	cfgRepo := &mockConfigRepo{services: []entity.Service{{ID: 1, Name: "api"}, {ID: 2, Name: "db"}}}
+	mux := http.NewServeMux()
+	handler.RegisterAPIRoutes(mux, "secret", repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, &mockSlackRepo{}))
+
+	get := func(t *testing.T, path, token string, out any) int {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		if out != nil && rec.Code == http.StatusOK {
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), out))
+		}
+		return rec.Code
+	}
+
+	t.Run("トークンが不正な場合は401", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, get(t, "/api/v1/incidents", "", nil))
+		assert.Equal(t, http.StatusUnauthorized, get(t, "/api/v1/incidents/C1", "wrong", nil))
+	})
+
+	t.Run("クローズ済みを含めて新しい順にページングして取得する", func(t *testing.T) {
+		var ids []string
+		var statuses []string
+		cursor := ""
+		for pages := 0; pages < 10; pages++ {
+			var res handler.APIIncidentList
+			require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?limit=2&cursor="+url.QueryEscape(cursor), "secret", &res))
+			assert.LessOrEqual(t, len(res.Incidents), 2)
+			for _, inc := range res.Incidents {
+				ids = append(ids, inc.ChannelID)
+				statuses = append(statuses, inc.Status)
+			}
+			if res.NextCursor == "" {
+				break
+			}
+			cursor = res.NextCursor
+		}
+		assert.Equal(t, []string{"C3", "C2", "C1"}, ids)
+		assert.Equal(t, []string{"active", "recovered", "closed"}, statuses)
+	})
+
+	t.Run("未クローズのインシデントを新しい順に取得する", func(t *testing.T) {
+		var res handler.APIIncidentList
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?status=active&limit=1", "secret", &res))
+		require.Len(t, res.Incidents, 1)
+		assert.Equal(t, "C3", res.Incidents[0].ChannelID)
+		assert.Equal(t, "db", res.Incidents[0].ServiceName)
+		require.NotEmpty(t, res.NextCursor)
+
+		cursor := res.NextCursor
+		res = handler.APIIncidentList{}
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?status=active&limit=1&cursor="+cursor, "secret", &res))
+		require.Len(t, res.Incidents, 1)
+		assert.Equal(t, "C2", res.Incidents[0].ChannelID)
+		assert.Empty(t, res.NextCursor)
+	})
+
+	t.Run("ページの間にクローズされても続きを取得できる", func(t *testing.T) {
+		var res handler.APIIncidentList
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?status=active&limit=1", "secret", &res))
+		require.Len(t, res.Incidents, 1)
+		assert.Equal(t, "C3", res.Incidents[0].ChannelID)
+
+		active := incRepo.active
+		incRepo.active = []entity.Incident{*incRepo.data["C2"]}
+		defer func() { incRepo.active = active }()
+
+		cursor := res.NextCursor
+		res = handler.APIIncidentList{}
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?status=active&limit=1&cursor="+cursor, "secret", &res))
+		require.Len(t, res.Incidents, 1)
+		assert.Equal(t, "C2", res.Incidents[0].ChannelID)
+	})
+
+	t.Run("同じカーソルを status=all でも使える", func(t *testing.T) {
+		var res handler.APIIncidentList
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?status=active&limit=1", "secret", &res))
+		cursor := res.NextCursor
+		res = handler.APIIncidentList{}
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents?limit=5&cursor="+cursor, "secret", &res))
+		ids := make([]string, 0, len(res.Incidents))
+		for _, inc := range res.Incidents {
+			ids = append(ids, inc.ChannelID)
+		}
+		assert.Equal(t, []string{"C2", "C1"}, ids)
+	})
+
+	t.Run("不正なパラメータは400", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get(t, "/api/v1/incidents?limit=0", "secret", nil))
+		assert.Equal(t, http.StatusBadRequest, get(t, "/api/v1/incidents?limit=1000", "secret", nil))
+		assert.Equal(t, http.StatusBadRequest, get(t, "/api/v1/incidents?status=unknown", "secret", nil))
+		assert.Equal(t, http.StatusBadRequest, get(t, "/api/v1/incidents?status=active&cursor=x", "secret", nil))
+		assert.Equal(t, http.StatusBadRequest, get(t, "/api/v1/incidents?cursor=x", "secret", nil))
+	})
+
+	t.Run("チャンネルIDでインシデントを取得する", func(t *testing.T) {
+		var res handler.APIIncident
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents/C1", "secret", &res))
+		assert.Equal(t, "C1", res.ChannelID)
+		assert.Equal(t, "api", res.ServiceName)
+		assert.Equal(t, "https://example.com/pm", res.PostMortemURL)
+		assert.True(t, res.StartedAt.Equal(base))
+
+		assert.Equal(t, http.StatusNotFound, get(t, "/api/v1/incidents/CNONE", "secret", nil))
+	})
+
+	t.Run("変更履歴を取得する", func(t *testing.T) {
+		var res handler.APIIncidentEventList
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents/C1/events", "secret", &res))
+		require.Len(t, res.Events, 2)
+		assert.Equal(t, entity.IncidentEventCreated, res.Events[0].Type)
+		assert.Equal(t, entity.IncidentEventClosed, res.Events[1].Type)
+	})
+
+	t.Run("ポストモーテムのURLを取得する", func(t *testing.T) {
+		var res handler.APIPostMortem
+		require.Equal(t, http.StatusOK, get(t, "/api/v1/incidents/C1/postmortem", "secret", &res))
+		assert.Equal(t, "https://example.com/pm", res.URL)
+
+		assert.Equal(t, http.StatusNotFound, get(t, "/api/v1/incidents/C2/postmortem", "secret", nil))
+	})
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+var (
+	// 回答に含まれる発言の引用 [3]
+	citationPattern = regexp.MustCompile(`\[(\d+)\]`)
+	// メンションの本文の先頭にあるボットへのメンション
+	leadingMentionPattern = regexp.MustCompile(`^(?:\s*<@[A-Z0-9]+(?:\|[^>]*)?>)+\s*`)
+)
+
+// mentionQuestion はメンションの本文からボットへのメンションを除いた質問を返す
+func mentionQuestion(text string) string {
+	return strings.TrimSpace(leadingMentionPattern.ReplaceAllString(text, ""))
+}
+
+// answerQuestion はインシデントチャンネルの発言をもとに質問に答え、threadTS のスレッドに返信する
+// questionTS は質問の発言で、回答の根拠からは除く
+func (h *CallbackHandler) answerQuestion(channelID, userID, question, questionTS, threadTS string) error {
+	slog.Info("answerQuestion", slog.String("channelID", channelID), slog.String("userID", userID))
+
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		h.postEphemeral(channelID, userID, slack.MsgOptionText("⛔️ このチャンネルはインシデントチャンネルではありません", false))
+		return nil
+	}
+
+	reply := func(opts ...slack.MsgOption) error {
+		_, _, err := h.repository.PostMessage(channelID, append(opts, slack.MsgOptionTS(threadTS))...)
+		return err
+	}
+	if h.aiRepository == nil {
+		return reply(slack.MsgOptionText("❌ AI が設定されていないため質問に答えられません", false))
+	}
+
+	messages, err := h.repository.GetAllChannelMessages(channelID)
+	if err != nil {
+		return fmt.Errorf("failed to GetAllChannelMessages: %w", err)
+	}
+	messages = slices.DeleteFunc(messages, func(m slack.Message) bool {
+		return m.Timestamp == questionTS
+	})
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Timestamp < messages[j].Timestamp
+	})
+	if len(messages) == 0 {
+		return reply(slack.MsgOptionText("❌ 回答の根拠にできるメッセージがありません", false))
+	}
+
+	_, loadingTS, err := h.repository.PostMessage(channelID,
+		slack.MsgOptionText("🔍 チャンネルの発言から回答を作成中です...", false),
+		slack.MsgOptionTS(threadTS),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post loading message: %w", err)
+	}
+
+	answer, err := h.aiRepository.AnswerQuestion(incident.Description, question, messages)
+	if err != nil {
+		h.repository.UpdateMessage(channelID, loadingTS, slack.MsgOptionText("❌ 回答の作成に失敗しました", false))
+		return fmt.Errorf("failed to AnswerQuestion: %w", err)
+	}
+
+	answer, references := h.linkCitations(channelID, answer, messages)
+	h.repository.UpdateMessage(channelID, loadingTS,
+		slack.MsgOptionText(answer, false),
+		slack.MsgOptionBlocks(blocks.QuestionAnswer(userID, question, answer, references)...),
+	)
+	return nil
+}
+
+// linkCitations は回答の [3] のような引用を発言へのリンクにし、引用された発言の一覧を返す
+// 存在しない番号の引用はそのまま残す
+func (h *CallbackHandler) linkCitations(channelID, answer string, messages []slack.Message) (string, []string) {
+	var cited []int
+	linked := citationPattern.ReplaceAllStringFunc(answer, func(s string) string {
+		n, err := strconv.Atoi(citationPattern.FindStringSubmatch(s)[1])
+		if err != nil || n < 1 || n > len(messages) {
+			return s
+		}
+		if !slices.Contains(cited, n) {
+			cited = append(cited, n)
+		}
+		return fmt.Sprintf("<%s|[%d]>", h.messagePermalink(channelID, messages[n-1]), n)
+	})
+
+	slices.Sort(cited)
+	references := make([]string, 0, len(cited))
+	for _, n := range cited {
+		m := messages[n-1]
+		text := []rune(strings.ReplaceAll(m.Text, "\n", " "))
+		if len(text) > 40 {
+			text = append(text[:40], '…')
+		}
+		references = append(references, fmt.Sprintf("<%s|[%d]> %s", h.messagePermalink(channelID, m), n, string(text)))
+	}
+	return linked, references
+}
+
+// messagePermalink は発言へのリンクを返す。スレッドの返信はスレッドを開いた状態のリンクにする
+func (h *CallbackHandler) messagePermalink(channelID string, m slack.Message) string {
+	url := fmt.Sprintf("%sarchives/%s/p%s", h.workSpaceURL, channelID, strings.ReplaceAll(m.Timestamp, ".", ""))
+	if m.ThreadTimestamp != "" && m.ThreadTimestamp != m.Timestamp {
+		url += fmt.Sprintf("?thread_ts=%s&cid=%s", m.ThreadTimestamp, channelID)
+	}
+	return url
+}
@@ -0,0 +1,84 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+// askAI は受け取った質問と発言を記録し、決まった回答を返す
+type askAI struct {
+	mockAIRepository
+	question string
+	messages []slack.Message
+	answer   string
+}
+
+func (m *askAI) AnswerQuestion(description, question string, messages []slack.Message) (string, error) {
+	m.question, m.messages = question, messages
+	return m.answer, nil
+}
+
+func TestCallbackHandler_Ask(t *testing.T) {
+	newHandler := func(ai repository.AIRepositorier) (*handler.CallbackHandler, *mockSlackRepo) {
+		incRepo := &mockIncidentRepo{
+			data: map[string]*entity.Incident{
+				"CINC": {ChannelID: "CINC", ServiceID: 1, Description: "APIが落ちた", StartedAt: time.Now()},
+			},
+		}
+		cfgRepo := &mockConfigRepo{services: []entity.Service{{ID: 1, Name: "svc"}}}
+		slackRepo := &mockSlackRepo{history: []slack.Message{
+			{Msg: slack.Msg{User: "U2", Text: "DBを再起動しました", Timestamp: "200.000002", ThreadTimestamp: "100.000001"}},
+			{Msg: slack.Msg{User: "U1", Text: "5xxが増えています", Timestamp: "100.000001", ThreadTimestamp: "100.000001"}},
+			{Msg: slack.Msg{User: "U3", Text: "ロールバックを検討中", Timestamp: "300.000003"}},
+		}}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+		return handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", ai, nil, nil), slackRepo
+	}
+	command := func(text string) *slack.SlashCommand {
+		return &slack.SlashCommand{Command: handler.IncidentCommand, Text: text, ChannelID: "CINC", UserID: "UASK"}
+	}
+
+	t.Run("発言を引用して回答する", func(t *testing.T) {
+		ai := &askAI{answer: "DBを再起動済みです [2]。存在しない発言 [9]"}
+		h, slackRepo := newHandler(ai)
+		require.NoError(t, h.HandleCommand(command("ask 何を試しましたか？")))
+
+		assert.Equal(t, "何を試しましたか？", ai.question)
+		// 発言は時系列に並べて渡す
+		require.Len(t, ai.messages, 3)
+		assert.Equal(t, "5xxが増えています", ai.messages[0].Text)
+
+		assert.Contains(t, slackRepo.messages["CINC"][0], "何を試しましたか？")
+		require.Len(t, slackRepo.updated, 1)
+		answer := slackRepo.updated[0]
+		assert.Contains(t, answer, "<https://example.com/archives/CINC/p200000002?thread_ts=100.000001&cid=CINC|[2]>")
+		assert.Contains(t, answer, "[9]")
+		assert.NotContains(t, answer, "|[9]>")
+		assert.Contains(t, answer, "参照した発言")
+	})
+
+	t.Run("質問が無い場合は使い方を表示する", func(t *testing.T) {
+		ai := &askAI{}
+		h, slackRepo := newHandler(ai)
+		require.NoError(t, h.HandleCommand(command("ask")))
+		require.Len(t, slackRepo.ephemerals, 1)
+		assert.Contains(t, slackRepo.ephemerals[0], "ask <質問>")
+		assert.Empty(t, ai.question)
+	})
+
+	t.Run("AI が無い場合は回答できないことを返信する", func(t *testing.T) {
+		h, slackRepo := newHandler(nil)
+		require.NoError(t, h.HandleCommand(command("ask 状況は？")))
+		require.Len(t, slackRepo.messages["CINC"], 2)
+		assert.Contains(t, slackRepo.messages["CINC"][1], "AI が設定されていない")
+	})
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+// AutoSummaryScheduler はインシデントのサービスとレベルに応じた進捗サマリの自動作成の間隔を返す
+type AutoSummaryScheduler interface {
+	AutoSummarySchedule(ctx context.Context, serviceID, level int) entity.AutoSummarySchedule
+}
+
+var errAutoSummaryAlreadyCreated = errors.New("progress summary is already created")
+
+// AutoSummarizer は設定された間隔で未復旧のインシデントの進捗サマリを作成し、チャンネルに投稿する
+// 前回のサマリ以降の発言だけを要約し、発言が無い場合は作成しない
+type AutoSummarizer struct {
+	ctx       context.Context
+	handler   *CallbackHandler
+	scheduler AutoSummaryScheduler
+	now       func() time.Time
+	// 発言が無く作成を見送った時刻。見送った後は間隔が経過するまで発言を確認しない
+	skippedAt map[string]time.Time
+}
+
+func NewAutoSummarizer(ctx context.Context, handler *CallbackHandler, scheduler AutoSummaryScheduler, now func() time.Time) *AutoSummarizer {
+	return &AutoSummarizer{
+		ctx:       ctx,
+		handler:   handler,
+		scheduler: scheduler,
+		now:       now,
+		skippedAt: map[string]time.Time{},
+	}
+}
+
+// Tick は前回のサマリから間隔が経過したインシデントの進捗サマリを作成する
+func (s *AutoSummarizer) Tick() error {
+	if s.handler.aiRepository == nil {
+		return nil
+	}
+	incidents, err := s.handler.repository.ActiveIncidents(s.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ActiveIncidents: %w", err)
+	}
+	now := s.now()
+	active := map[string]bool{}
+	for _, incident := range incidents {
+		active[incident.ChannelID] = true
+		if err := s.summarize(&incident, now); err != nil {
+			slog.Error("Failed to create scheduled progress summary", slog.Any("err", err), slog.String("channelID", incident.ChannelID))
+		}
+	}
+	// クローズしたインシデントの見送った記録は使わないため消す
+	for channelID := range s.skippedAt {
+		if !active[channelID] {
+			delete(s.skippedAt, channelID)
+		}
+	}
+	return nil
+}
+
+// autoSummaryDue は前回のサマリ(無い場合は検知)から間隔が経過している場合に true を返す
+func autoSummaryDue(incident *entity.Incident, schedule entity.AutoSummarySchedule, now time.Time) bool {
+	if schedule.IntervalMinutes <= 0 || !incident.RecoveredAt.IsZero() || incident.StartedAt.IsZero() {
+		return false
+	}
+	last := incident.LastSummaryAt
+	if last.IsZero() {
+		last = incident.StartedAt
+	}
+	return now.Sub(last) >= schedule.Interval()
+}
+
+func (s *AutoSummarizer) summarize(incident *entity.Incident, now time.Time) error {
+	h := s.handler
+	if !incident.RecoveredAt.IsZero() {
+		// 復旧後は作成しないため、見送った記録も消す
+		delete(s.skippedAt, incident.ChannelID)
+		return nil
+	}
+	schedule := s.scheduler.AutoSummarySchedule(s.ctx, incident.ServiceID, incident.Level)
+	if !autoSummaryDue(incident, schedule, now) {
+		return nil
+	}
+	if skipped, ok := s.skippedAt[incident.ChannelID]; ok && now.Sub(skipped) < schedule.Interval() {
+		return nil
+	}
+
+	messages, err := h.collectChannelMessages(incident.ChannelID, incident)
+	if err != nil {
+		return fmt.Errorf("failed to collect channel messages: %w", err)
+	}
+	// チェックポイントや前回のサマリなど、ボットの投稿だけの場合は進展が無いとみなす
+	if !slices.ContainsFunc(messages, func(m slack.Message) bool { return m.BotID == "" }) {
+		s.skippedAt[incident.ChannelID] = now
+		return nil
+	}
+	delete(s.skippedAt, incident.ChannelID)
+
+	// 作成より先に記録して、次の実行や他のプロセスと重複して作成しないようにする
+	lastSummaryAt := incident.LastSummaryAt
+	err = updateIncident(s.ctx, h.repository, incident, func(i *entity.Incident) error {
+		if !i.LastSummaryAt.Equal(lastSummaryAt) {
+			return errAutoSummaryAlreadyCreated
+		}
+		i.LastSummaryAt = now
+		return nil
+	})
+	if errors.Is(err, errAutoSummaryAlreadyCreated) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save incident: %w", err)
+	}
+
+	summary, err := h.aiRepository.SummarizeProgressAdvanced(incident.Description, messages, incident.LastSummary)
+	if err != nil {
+		return fmt.Errorf("failed to SummarizeProgressAdvanced: %w", err)
+	}
+
+	announced := false
+	if schedule.Announce {
+		service, err := h.repository.ServiceByID(s.ctx, incident.ServiceID)
+		if err != nil {
+			return fmt.Errorf("failed to ServiceByID: %w", err)
+		}
+		if err := h.announceProgressSummary(incident.ChannelID, summary, service); err != nil {
+			slog.Error("Failed to announce scheduled progress summary", slog.Any("err", err), slog.String("channelID", incident.ChannelID))
+		} else {
+			announced = true
+		}
+	}
+
+	_, _, err = h.repository.PostMessage(
+		incident.ChannelID,
+		slack.MsgOptionBlocks(blocks.ScheduledProgressSummary(summary, schedule.IntervalMinutes, announced)...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post progress summary: %w", err)
+	}
+
+	if err := h.updateIncidentSummary(incident, "", summary, messages); err != nil {
+		return fmt.Errorf("failed to update incident summary: %w", err)
+	}
+	// 進捗に合わせてインシデントレベルと緊急度を見直す
+	if err := h.suggestSeverity(incident.ChannelID); err != nil {
+		slog.Warn("failed to suggest severity", slog.String("channelID", incident.ChannelID), slog.Any("err", err))
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package handler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+// autoSummarySchedules はサービスごとの進捗サマリの自動作成の間隔
+type autoSummarySchedules map[int]entity.AutoSummarySchedule
+
+func (s autoSummarySchedules) AutoSummarySchedule(_ context.Context, serviceID, _ int) entity.AutoSummarySchedule {
+	return s[serviceID]
+}
+
+func TestAutoSummarizer_Tick(t *testing.T) {
+	// サマリの作成時刻は実際の時刻で記録されるため、現在時刻を基準にする
+	base := time.Now()
+	incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{
+		"C1": {ChannelID: "C1", ServiceID: 1, Description: "APIが遅い", StartedAt: base.Add(-31 * time.Minute)},
+		"C2": {ChannelID: "C2", ServiceID: 2, Description: "バッチが遅い", StartedAt: base.Add(-31 * time.Minute)},
+	}}
+	slackRepo := &mockSlackRepo{history: []slack.Message{
+		{Msg: slack.Msg{User: "U1", Text: "レイテンシが悪化しています", Timestamp: "100.000001"}},
+	}}
+	cfgRepo := &mockConfigRepo{services: []entity.Service{
+		{ID: 1, Name: "api", AnnouncementChannels: []string{"api-alerts"}},
+		{ID: 2, Name: "batch"},
+	}}
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+	ai := &mockAIRepository{summarizeProgressResult: "### 🔄 現在の状況\n- 調査中"}
+	h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", ai, nil, nil)
+
+	now := base
+	s := handler.NewAutoSummarizer(context.Background(), h, autoSummarySchedules{
+		1: {IntervalMinutes: 30, Announce: true},
+	}, func() time.Time { return now })
+	tick := func(t *testing.T) {
+		t.Helper()
+		incRepo.active = nil
+		for _, inc := range incRepo.data {
+			incRepo.active = append(incRepo.active, *inc)
+		}
+		require.NoError(t, s.Tick())
+	}
+	summaries := func(channelID string) []string {
+		var posted []string
+		for _, m := range slackRepo.messages[channelID] {
+			if strings.Contains(m, "自動で作成した進捗サマリです") {
+				posted = append(posted, m)
+			}
+		}
+		return posted
+	}
+
+	tick(t)
+	require.Len(t, summaries("C1"), 1)
+	assert.Contains(t, summaries("C1")[0], "30分ごとに自動で作成した進捗サマリです")
+	assert.Contains(t, summaries("C1")[0], "アナウンスチャンネルにも投稿しました")
+	assert.NotContains(t, summaries("C1")[0], "report_post_action", "投稿済みの場合は報告ボタンを表示しない")
+	// モックはどのチャンネル名も同じIDを返す
+	require.Len(t, slackRepo.messages["C123456"], 1)
+	assert.Contains(t, slackRepo.messages["C123456"][0], "インシデント進捗サマリ")
+	assert.Empty(t, slackRepo.messages["C2"], "設定されていないサービスは作成しない")
+
+	inc := incRepo.data["C1"]
+	assert.Equal(t, ai.summarizeProgressResult, inc.LastSummary)
+	assert.Equal(t, "100.000001", inc.LastProcessedMessageTS)
+
+	// 間隔が経過するまでは作成しない
+	now = base.Add(10 * time.Minute)
+	tick(t)
+	assert.Len(t, summaries("C1"), 1)
+
+	// 前回以降に発言が無い場合は作成しない
+	now = base.Add(31 * time.Minute)
+	tick(t)
+	assert.Len(t, summaries("C1"), 1)
+
+	// ボットの投稿は進展として扱わない
+	slackRepo.history = append(slackRepo.history, slack.Message{Msg: slack.Msg{BotID: "B1", Text: "チェックポイント", Timestamp: "200.000002"}})
+	now = base.Add(62 * time.Minute)
+	tick(t)
+	assert.Len(t, summaries("C1"), 1)
+
+	slackRepo.history = append(slackRepo.history, slack.Message{Msg: slack.Msg{User: "U1", Text: "ロールバックしました", Timestamp: "300.000003"}})
+	now = base.Add(93 * time.Minute)
+	tick(t)
+	require.Len(t, summaries("C1"), 2)
+	assert.Equal(t, "300.000003", incRepo.data["C1"].LastProcessedMessageTS)
+}
@@ -2,11 +2,13 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/pyama86/YAS3/domain/entity"
@@ -24,13 +26,68 @@ func timeNow() time.Time {
 	return time.Now().In(loc)
 }
 
+// インシデントの変更履歴を記録する。記録に失敗しても操作自体は失敗させない
+func recordIncidentEvent(ctx context.Context, repo repository.IncidentEventRepositoryer, channelID string, eventType entity.IncidentEventType, actorUserID string, metadata map[string]string) {
+	event := &entity.IncidentEvent{
+		ChannelID:   channelID,
+		Type:        eventType,
+		ActorUserID: actorUserID,
+		OccurredAt:  timeNow(),
+		Metadata:    metadata,
+	}
+	if err := repo.SaveIncidentEvent(ctx, event); err != nil {
+		slog.Error("failed to SaveIncidentEvent", slog.Any("err", err), slog.String("channelID", channelID), slog.String("type", string(eventType)))
+	}
+}
+
+// 競合時に読み直して再適用する回数
+const maxIncidentUpdateAttempts = 5
+
+var (
+	errAlreadyRecovered = errors.New("incident is already recovered")
+	errNotRecovered     = errors.New("incident is not recovered")
+	errAlreadyLinked    = errors.New("already linked")
+	errNotLinked        = errors.New("not linked")
+)
+
+// インシデントに変更を適用して保存する
+// 他の更新と競合した場合は最新のインシデントを読み直してmutateを再適用する
+// mutateがエラーを返した場合は保存せずにそのエラーを返す
+func updateIncident(ctx context.Context, repo repository.IncidentRepositoryer, incident *entity.Incident, mutate func(*entity.Incident) error) error {
+	for attempt := 1; ; attempt++ {
+		if err := mutate(incident); err != nil {
+			return err
+		}
+		err := repo.SaveIncident(ctx, incident)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, repository.ErrIncidentConflict) || attempt >= maxIncidentUpdateAttempts {
+			return err
+		}
+
+		slog.Info("incident update conflicted, retrying", slog.String("channelID", incident.ChannelID), slog.Int("attempt", attempt))
+		latest, err := repo.FindIncidentByChannel(ctx, incident.ChannelID)
+		if err != nil {
+			return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+		}
+		if latest == nil {
+			return fmt.Errorf("incident is nil")
+		}
+		*incident = *latest
+	}
+}
+
 type CallbackHandler struct {
 	ctx                context.Context
 	repository         repository.Repository
 	workSpaceURL       string
-	aiRepository       *repository.AIRepository
+	aiRepository       repository.AIRepositorier
 	postmortemExporter repository.PostMortemRepositoryer
 	config             *repository.Config
+	alertMu            sync.Mutex
+	alertLeases        repository.LeaseRepositoryer
+	incidentIndex      *repository.IncidentIndex
 }
 
 var urgencyColorMap = map[string]string{
@@ -44,7 +101,7 @@ func NewCallbackHandler(
 	ctx context.Context,
 	repository repository.Repository,
 	workSpaceURL string,
-	aiRepository *repository.AIRepository,
+	aiRepository repository.AIRepositorier,
 	postmortemExporter repository.PostMortemRepositoryer,
 	config *repository.Config,
 ) *CallbackHandler {
@@ -85,6 +142,23 @@ func (h *CallbackHandler) Handle(callback *slack.InteractionCallback) error {
 			if err := h.submitHandler(callback.User.ID, callback.Channel.ID); err != nil {
 				return fmt.Errorf("submitHandler failed: %w", err)
 			}
+		case "role_assign", "role_unassign":
+			var incident *entity.Incident
+			var err error
+			if action.ActionID == "role_assign" {
+				incident, err = h.assignRole(callback.User.ID, callback.Channel.ID, action.Value)
+			} else {
+				incident, err = h.unassignRole(callback.User.ID, callback.Channel.ID, action.Value)
+			}
+			if err != nil {
+				return fmt.Errorf("%s failed: %w", action.ActionID, err)
+			}
+			// 役割の一覧を最新の担当者で更新する
+			h.repository.UpdateMessage(
+				callback.Channel.ID,
+				callback.Message.Timestamp,
+				slack.MsgOptionBlocks(blocks.IncidentRoles(h.incidentRoles(), incident.Roles)...),
+			)
 		case "incident_level_button":
 			h.repository.DeleteMessage(
 				callback.Channel.ID,
@@ -96,13 +170,26 @@ func (h *CallbackHandler) Handle(callback *slack.InteractionCallback) error {
 			if err := h.setIncidentLevel(callback.Channel.ID, callback.User.ID, callback.ActionCallback.BlockActions[0].Value); err != nil {
 				return fmt.Errorf("setIncidentLevel failed: %w", err)
 			}
+		case "severity_suggestion_accept", "severity_suggestion_reject":
+			accepted := action.ActionID == "severity_suggestion_accept"
+			if err := h.resolveSeveritySuggestion(callback.Channel.ID, callback.User.ID, action.Value, callback.Message, accepted); err != nil {
+				return fmt.Errorf("resolveSeveritySuggestion failed: %w", err)
+			}
+		case "communication_drafts_action":
+			if err := h.draftCommunications(callback.Channel.ID); err != nil {
+				return fmt.Errorf("draftCommunications failed: %w", err)
+			}
+		case "communication_edit":
+			if err := h.openCommunicationModal(callback.TriggerID, callback.Channel.ID, action.Value, callback.Message); err != nil {
+				return fmt.Errorf("openCommunicationModal failed: %w", err)
+			}
 		case "postmortem_action":
 			h.repository.UpdateMessage(
 				callback.Channel.ID,
 				callback.Message.Timestamp,
 				slack.MsgOptionText("📝 ポストモーテムを作成中...", false),
 			)
-			if err := h.createPostMortem(callback.Channel, callback.User); err != nil {
+			if err := h.createPostMortem(callback.Channel, callback.User, callback.Message.Timestamp); err != nil {
 				return fmt.Errorf("createPostMortem failed: %w", err)
 			}
 		case "progress_summary_action":
@@ -211,6 +298,11 @@ func (h *CallbackHandler) Handle(callback *slack.InteractionCallback) error {
 			case "set_incident_level":
 				slog.Info("set_incident_level", slog.Any("channelID", callback.Channel.ID))
 				h.showIncidentLevelButtons(callback.Channel.ID)
+			case "show_incident_roles":
+				slog.Info("show_incident_roles", slog.Any("channelID", callback.Channel.ID))
+				if err := h.showIncidentRoles(callback.Channel.ID); err != nil {
+					return fmt.Errorf("showIncidentRoles failed: %w", err)
+				}
 			case "edit_incident_summary":
 				slog.Info("edit_incident_summary", slog.Any("channelID", callback.Channel.ID))
 				if err := h.openEditSummaryModal(callback.TriggerID, callback.Channel.ID); err != nil {
@@ -221,6 +313,23 @@ func (h *CallbackHandler) Handle(callback *slack.InteractionCallback) error {
 				if err := h.showPostMortemButton(callback.Channel.ID); err != nil {
 					return fmt.Errorf("showPostMortemButton failed: %w", err)
 				}
+			case "draft_communications":
+				slog.Info("draft_communications", slog.Any("channelID", callback.Channel.ID))
+				if err := h.draftCommunications(callback.Channel.ID); err != nil {
+					return fmt.Errorf("draftCommunications failed: %w", err)
+				}
+			case "find_similar_incidents":
+				slog.Info("find_similar_incidents", slog.Any("channelID", callback.Channel.ID))
+				incident, err := h.repository.FindIncidentByChannel(h.ctx, callback.Channel.ID)
+				if err != nil {
+					return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+				}
+				if incident == nil {
+					return fmt.Errorf("incident is nil")
+				}
+				if err := h.postSimilarIncidents(incident, true); err != nil {
+					return fmt.Errorf("postSimilarIncidents failed: %w", err)
+				}
 			case "create_progress_summary":
 				slog.Info("create_progress_summary", slog.Any("channelID", callback.Channel.ID))
 				// 確認フォームを表示
@@ -295,6 +404,10 @@ func (h *CallbackHandler) Handle(callback *slack.InteractionCallback) error {
 			if err := h.submitLinkIncidentModal(callback); err != nil {
 				return fmt.Errorf("submitLinkIncidentModal failed: %w", err)
 			}
+		case "communication_modal":
+			if err := h.submitCommunicationModal(callback); err != nil {
+				return fmt.Errorf("submitCommunicationModal failed: %w", err)
+			}
 		}
 	}
 	return nil
@@ -312,10 +425,20 @@ func (h *CallbackHandler) submitHandler(userID, channelID string) error {
 	}
 
 	// インシデントにハンドラを保存する
-	incident.HandlerUserID = userID
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	var previousHandler string
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		previousHandler = i.HandlerUserID
+		i.HandlerUserID = userID
+		i.HandlerAssignedAt = timeNow()
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventHandlerAssigned, userID, map[string]string{
+		"from": previousHandler,
+		"to":   userID,
+	})
 
 	_, _, err = h.repository.PostMessage(
 		channelID,
@@ -361,11 +484,9 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 	summaryText := callback.View.State.Values["incident_summary_block"]["summary_text"].Value
 	urgency := callback.View.State.Values["urgency_block"]["urgency_select"].SelectedOption.Value
 	userID := callback.User.ID
-	originalChannelID := callback.View.PrivateMetadata
 
 	slog.Info("submitIncidentModal", slog.Any("serviceID", serviceID), slog.Any("summary_text", summaryText), slog.Any("urgency", urgency))
 
-	// チャンネル作成
 	num, err := strconv.Atoi(serviceID)
 	if err != nil {
 		return fmt.Errorf("failed to strconv.Atoi: %w", err)
@@ -376,6 +497,49 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 		return fmt.Errorf("failed to ServiceByID: %w", err)
 	}
 
+	incident, err := h.createIncident(&incidentRequest{
+		service:           service,
+		description:       summaryText,
+		urgency:           urgency,
+		userID:            userID,
+		originalChannelID: callback.View.PrivateMetadata,
+		errorChannelID:    callback.Channel.ID,
+	})
+	if err != nil {
+		return err
+	}
+	// 検索や提案に失敗してもインシデントの作成は成功しているため、エラーは返さない
+	if err := h.postSimilarIncidents(incident, false); err != nil {
+		slog.Warn("failed to post similar incidents", slog.String("channelID", incident.ChannelID), slog.Any("err", err))
+	}
+	if err := h.suggestSeverity(incident.ChannelID); err != nil {
+		slog.Warn("failed to suggest severity", slog.String("channelID", incident.ChannelID), slog.Any("err", err))
+	}
+	return nil
+}
+
+// incidentRequest はインシデントチャンネルを作成するための情報
+type incidentRequest struct {
+	service     *entity.Service
+	description string
+	urgency     string
+	// 作成したユーザー。オンコールの担当者がいなければハンドラの初期値にもなる。アラートから作成した場合は空
+	userID string
+	// 作成を依頼されたチャンネル。インシデントチャンネルへの移動案内を投稿する
+	originalChannelID string
+	// チャンネルの作成に失敗した場合に通知するチャンネル
+	errorChannelID   string
+	alertFingerprint string
+}
+
+// createIncident はインシデントチャンネルを作成し、メンバーの招待や各所への通知を行う
+func (h *CallbackHandler) createIncident(req *incidentRequest) (*entity.Incident, error) {
+	service := req.service
+	urgencyText, ok := blocks.UrgencyMap[req.urgency]
+	if !ok {
+		return nil, fmt.Errorf("invalid urgency: %s", req.urgency)
+	}
+
 	prefix := ""
 	if h.config != nil && h.config.ChannelPrefix != "" {
 		prefix = h.config.ChannelPrefix
@@ -386,7 +550,7 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 	// すでに存在する場合はユニークな名前にする
 	c, err := h.repository.GetChannelByName(channelName)
 	if err != nil && err != repository.ErrSlackNotFound {
-		return fmt.Errorf("failed to GetChannelByID: %w", err)
+		return nil, fmt.Errorf("failed to GetChannelByID: %w", err)
 	}
 	if c != nil {
 		channelName = fmt.Sprintf("%s-%02d", channelName, timeNow().Unix()%100)
@@ -397,44 +561,75 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 	})
 
 	if err != nil {
-		_, _, postErr := h.repository.PostMessage(
-			callback.Channel.ID,
-			slack.MsgOptionText(fmt.Sprintf("❌ チャンネルの作成に失敗しました:%s", err), false),
-		)
-		if postErr != nil {
-			slog.Error("Failed to post channel creation error message", slog.Any("err", postErr))
+		if req.errorChannelID != "" {
+			_, _, postErr := h.repository.PostMessage(
+				req.errorChannelID,
+				slack.MsgOptionText(fmt.Sprintf("❌ チャンネルの作成に失敗しました:%s", err), false),
+			)
+			if postErr != nil {
+				slog.Error("Failed to post channel creation error message", slog.Any("err", postErr))
+			}
 		}
 
-		return fmt.Errorf("failed to CreateConversation: %w", err)
+		return nil, fmt.Errorf("failed to CreateConversation: %w", err)
 	}
 	h.repository.FlushChannelCache()
+	// 作成者を仮のハンドラーにし、オンコールの担当者がいれば優先してハンドラーに決める
+	startedAt := timeNow()
+	handlerUserID := req.userID
+	var handlerAssignedAt time.Time
+	onCallUserID := h.onCallUserID(service)
+	if onCallUserID != "" {
+		handlerUserID = onCallUserID
+		handlerAssignedAt = startedAt
+	}
 	// インシデントを保存する
 	incident := &entity.Incident{
-		ChannelID:     channel.ID,
-		ServiceID:     num,
-		Description:   summaryText,
-		HandlerUserID: userID,
-		Urgency:       urgency,
-		Level:         0,
-		CreatedUserID: userID,
-		StartedAt:     timeNow(),
+		ChannelID:         channel.ID,
+		ServiceID:         service.ID,
+		Description:       req.description,
+		HandlerUserID:     handlerUserID,
+		HandlerAssignedAt: handlerAssignedAt,
+		Urgency:           req.urgency,
+		Level:             0,
+		CreatedUserID:     req.userID,
+		StartedAt:         startedAt,
+		AlertFingerprint:  req.alertFingerprint,
 	}
 	slog.Info("save_incident", slog.Any("incident", incident))
 	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
-		return fmt.Errorf("failed to SaveIncident: %w", err)
+		return nil, fmt.Errorf("failed to SaveIncident: %w", err)
 	}
-
-	urgencyText, ok := blocks.UrgencyMap[urgency]
-	if !ok {
-		return fmt.Errorf("invalid urgency: %s", urgency)
+	metadata := map[string]string{
+		"service_id":  strconv.Itoa(service.ID),
+		"urgency":     req.urgency,
+		"description": req.description,
+	}
+	if req.alertFingerprint != "" {
+		metadata["alert_fingerprint"] = req.alertFingerprint
+	}
+	recordIncidentEvent(h.ctx, h.repository, channel.ID, entity.IncidentEventCreated, req.userID, metadata)
+	if onCallUserID != "" {
+		recordIncidentEvent(h.ctx, h.repository, channel.ID, entity.IncidentEventHandlerAssigned, "", map[string]string{
+			"from":            req.userID,
+			"to":              onCallUserID,
+			"oncall_schedule": service.OnCallSchedule,
+		})
 	}
 
-	topic := fmt.Sprintf("サービス名:%s 緊急度:%s 事象内容:%s", service.Name, urgencyText, summaryText)
+	topic := incidentTopic(service, urgencyText, req.description, "")
 	slog.Info("set_topic_of_conversation", slog.Any("topic", topic))
 	err = h.repository.SetTopicOfConversation(channel.ID, topic)
 	if err != nil {
-		return fmt.Errorf("failed to SetPurposeOfConversation: %w", err)
+		return nil, fmt.Errorf("failed to SetPurposeOfConversation: %w", err)
+	}
+	if onCallUserID != "" {
+		slog.Info("invite_oncall_to_conversation", slog.String("user", onCallUserID))
+		if err := h.repository.InviteUsersToConversation(channel.ID, onCallUserID); err != nil {
+			slog.Error("Failed to invite oncall", slog.Any("err", err), slog.String("user", onCallUserID))
+		}
 	}
+
 	var members []string
 	errMembers := []string{}
 	slog.Info("get incident_team_members", slog.Int("count", len(service.IncidentTeamMembers)))
@@ -454,7 +649,7 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 		slog.Info("invite_users_to_conversation", slog.Any("members", members))
 		err = h.repository.InviteUsersToConversation(channel.ID, members...)
 		if err != nil {
-			return fmt.Errorf("failed to InviteUsersToConversation: %w", err)
+			return nil, fmt.Errorf("failed to InviteUsersToConversation: %w", err)
 		}
 
 		_, _, err = h.repository.PostMessage(
@@ -477,8 +672,8 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 	}
 
 	attachment := slack.Attachment{
-		Color:  urgencyColorMap[urgency],
-		Blocks: slack.Blocks{BlockSet: blocks.IncidentCreated(summaryText, urgencyText, channel.ID, service)},
+		Color:  urgencyColorMap[req.urgency],
+		Blocks: slack.Blocks{BlockSet: blocks.IncidentCreated(req.description, urgencyText, channel.ID, service)},
 	}
 	_, _, err = h.repository.PostMessage(
 		channel.ID,
@@ -490,30 +685,37 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 
 	// 共有チャンネルにお知らせを投稿
 	if err := h.broadCastAnnouncement(channel.ID, attachment, service, true); err != nil {
-		return fmt.Errorf("failed to broadCastAnnouncement: %w", err)
+		return nil, fmt.Errorf("failed to broadCastAnnouncement: %w", err)
 	}
 
-	_, _, err = h.repository.PostMessage(
-		channel.ID,
-		slack.MsgOptionBlocks(blocks.IncidentReportRequest(userID)...),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to PostMessage: %w", err)
+	// アラートから作成した場合は依頼者がいないため、報告のお願いは投稿しない
+	if req.userID != "" {
+		_, _, err = h.repository.PostMessage(
+			channel.ID,
+			slack.MsgOptionBlocks(blocks.IncidentReportRequest(req.userID)...),
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to PostMessage: %w", err)
+		}
 	}
 
+	recruitment := blocks.HandlerRecruitmentMessage()
+	if onCallUserID != "" {
+		recruitment = blocks.OnCallHandler(onCallUserID)
+	}
 	_, _, err = h.repository.PostMessage(
 		channel.ID,
-		slack.MsgOptionBlocks(blocks.HandlerRecruitmentMessage()...),
+		slack.MsgOptionBlocks(recruitment...),
 	)
 	if err != nil {
 		slog.Error("Failed to post handler recruitment message", slog.Any("err", err))
 	}
 
 	// 元のチャンネルにインシデントチャンネルへの移動案内を送信
-	if originalChannelID != "" && originalChannelID != channel.ID {
+	if req.originalChannelID != "" && req.originalChannelID != channel.ID {
 		moveMessage := fmt.Sprintf("🚨 インシデント対応は <#%s> で行います。関係者の方はそちらのチャンネルにご参加ください。", channel.ID)
 		_, _, err := h.repository.PostMessage(
-			originalChannelID,
+			req.originalChannelID,
 			slack.MsgOptionText(moveMessage, false),
 		)
 		if err != nil {
@@ -521,7 +723,7 @@ func (h *CallbackHandler) submitIncidentModal(callback *slack.InteractionCallbac
 		}
 	}
 
-	return nil
+	return incident, nil
 }
 
 // 障害が復旧したらトピックを変更して、各所に通知する
@@ -534,7 +736,18 @@ func (h *CallbackHandler) recoveryIncident(userID, channelID string) error {
 	if incident == nil {
 		return fmt.Errorf("incident is nil")
 	}
-	if !incident.RecoveredAt.IsZero() {
+
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		// 既に復旧している場合は何もしない
+		if !i.RecoveredAt.IsZero() {
+			return errAlreadyRecovered
+		}
+		i.RecoveredAt = timeNow()
+		i.RecoveredUserID = userID
+		i.DisableTimer = true
+		return nil
+	})
+	if errors.Is(err, errAlreadyRecovered) {
 		_, _, err := h.repository.PostMessage(
 			channelID,
 			slack.MsgOptionBlocks(blocks.AlreadyRecovered()...),
@@ -544,13 +757,10 @@ func (h *CallbackHandler) recoveryIncident(userID, channelID string) error {
 		}
 		return nil
 	}
-
-	incident.RecoveredAt = timeNow()
-	incident.RecoveredUserID = userID
-	incident.DisableTimer = true
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventRecovered, userID, nil)
 
 	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
 	if err != nil {
@@ -620,10 +830,14 @@ func (h *CallbackHandler) stopTimeKeeper(channelID, userID string) error {
 		return fmt.Errorf("incident is nil")
 	}
 
-	incident.DisableTimer = true
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		i.DisableTimer = true
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventTimerStopped, userID, nil)
 
 	_, _, err = h.repository.PostMessage(
 		channelID,
@@ -650,10 +864,19 @@ func (h *CallbackHandler) setIncidentLevel(channelID, userID, level string) erro
 	if err != nil {
 		return fmt.Errorf("failed to strconv.Atoi: %w", err)
 	}
-	incident.Level = levelInt
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	var previousLevel int
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		previousLevel = i.Level
+		i.Level = levelInt
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventLevelChanged, userID, map[string]string{
+		"from": strconv.Itoa(previousLevel),
+		"to":   strconv.Itoa(levelInt),
+	})
 
 	description := "サービスに影響なし"
 	levels := h.repository.IncidentLevels(h.ctx)
@@ -748,7 +971,8 @@ func (h *CallbackHandler) showPostMortemButton(channelID string) error {
 }
 
 // AIを活用して、ポストモーテムを作成する
-func (h *CallbackHandler) createPostMortem(channel slack.Channel, user slack.User) error {
+// progressTS のメッセージを生成の進捗で更新する
+func (h *CallbackHandler) createPostMortem(channel slack.Channel, user slack.User, progressTS string) error {
 	incident, err := h.repository.FindIncidentByChannel(h.ctx, channel.ID)
 	if err != nil {
 		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
@@ -808,7 +1032,12 @@ func (h *CallbackHandler) createPostMortem(channel slack.Channel, user slack.Use
 				userCache[m.User] = h.repository.GetUserPreferredName(user)
 			}
 		}
-		formattedMessages += fmt.Sprintf("- %s %s:%s\n", ts.Format("2006-01-02 15:04:05"), userCache[m.User], m.Text)
+		text := m.Text
+		if h.aiRepository != nil {
+			// タイムラインは AI に送るため、秘密情報や個人情報を伏せる
+			text = h.aiRepository.Redact(text)
+		}
+		formattedMessages += fmt.Sprintf("- %s %s:%s\n", ts.Format("2006-01-02 15:04:05"), userCache[m.User], text)
 	}
 	formattedMessages += fmt.Sprintf("- %s %sさんがインシデントを復旧を宣言\n", recoveredAt.Format("2006-01-02 15:04:05"), h.repository.GetUserPreferredName(recoveredUser))
 
@@ -825,106 +1054,91 @@ func (h *CallbackHandler) createPostMortem(channel slack.Channel, user slack.Use
 		}
 	}
 
-	channelURL := fmt.Sprintf("%sarchives/%s", h.workSpaceURL, channel.ID)
+	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
+	if err != nil {
+		slog.Error("failed to ServiceByID", slog.Any("err", err), slog.Any("serviceID", incident.ServiceID))
+	}
+
+	// テンプレートの誤りで AI の生成が無駄にならないよう、先に読み込む
+	var templatePath string
+	if h.config != nil {
+		templatePath = h.config.PostmortemTemplatePath(service)
+	}
+	tmpl, err := postmortem.Load(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to load postmortem template: %w", err)
+	}
 
-	// デフォルト値の設定
-	title := "例: サービスAPIが応答停止"
-	summary := "例: サービスAPIが応答しない"
-	status := "解決済み"
-	impact := "例: サービスが断続的にダウンし、最大で１割のユーザーが影響を受けました。"
-	rootCause := "例: ExampleAPIのバグ、設定ミス"
-	trigger := "例: 監視アラート、ユーザーからの報告"
-	solution := "例: 切り戻し、データベースの再起動"
-	actionItems := "例:\n- 【根本対応】原因となったエンドポイントの修正\n- 【緩和策】エラーハンドリングの追加"
-	lessonsGood := "例: 迅速な対応により影響時間を最小限に抑えることができた"
-	lessonsBad := "例: 初期対応時の情報共有が不十分だった"
-	lessensLucky := "例: 障害発生がピーク時間外だったため影響が限定的だった"
+	data := postmortem.Data{
+		Author: author,
+		Incident: postmortem.Incident{
+			Description: incident.Description,
+			Urgency:     incident.Urgency,
+			Level:       incident.Level,
+			Handler:     h.userName(incident.HandlerUserID),
+			CreatedBy:   h.repository.GetUserPreferredName(createdUser),
+			RecoveredBy: h.repository.GetUserPreferredName(recoveredUser),
+			StartedAt:   createdAt,
+			RecoveredAt: recoveredAt,
+		},
+		Roles: h.rolesForPostMortem(incident),
+		Links: postmortem.Links{
+			Channel: fmt.Sprintf("%sarchives/%s", h.workSpaceURL, channel.ID),
+		},
+	}
+	if service != nil {
+		data.Incident.Service = service.Name
+	}
 
 	postmortemFileTitle := fmt.Sprintf("%s postmortem-%s", createdAt.Format("2006/01/02"), channel.Name)
 
+	draft := newPostMortemDraft(formattedMessages)
 	if h.aiRepository != nil {
-		// タイトル生成
-		t, err := h.aiRepository.GenerateTitle(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateTitle: %w", err)
-		}
-		title = t
-		postmortemFileTitle = fmt.Sprintf("%s %s", createdAt.Format("2006/01/02"), t)
-
-		// サマリー生成
-		s, err := h.aiRepository.Summarize(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to Summarize: %w", err)
-		}
-		summary = s
-		incident.Description = s
-
-		// ステータス生成
-		st, err := h.aiRepository.GenerateStatus(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateStatus: %w", err)
-		}
-		status = st
-
-		// 影響分析生成
-		i, err := h.aiRepository.GenerateImpact(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateImpact: %w", err)
-		}
-		impact = i
-
-		// 根本原因生成
-		rc, err := h.aiRepository.GenerateRootCause(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateRootCause: %w", err)
-		}
-		rootCause = rc
-
-		// トリガー生成
-		tr, err := h.aiRepository.GenerateTrigger(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateTrigger: %w", err)
-		}
-		trigger = tr
-
-		// 解決策生成
-		so, err := h.aiRepository.GenerateSolution(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateSolution: %w", err)
+		failed := h.generatePostMortem(draft, h.aiRepository.Redact(incident.Description), data.Roster(), formattedMessages, func(done, total int) {
+			if progressTS == "" {
+				return
+			}
+			h.repository.UpdateMessage(
+				channel.ID,
+				progressTS,
+				slack.MsgOptionText(fmt.Sprintf("📝 ポストモーテムを作成中... (%d/%d)", done, total), false),
+			)
+		})
+		if len(failed) > 0 {
+			_, _, err := h.repository.PostMessage(
+				channel.ID,
+				slack.MsgOptionText(fmt.Sprintf("⚠️ 次のセクションは生成に失敗したため記入例のままです: %s", strings.Join(failed, "、")), false),
+			)
+			if err != nil {
+				slog.Error("Failed to post postmortem failed sections message", slog.Any("err", err))
+			}
 		}
-		solution = so
-
-		// アクションアイテム生成
-		ai, err := h.aiRepository.GenerateActionItems(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateActionItems: %w", err)
+		if draft.titleGenerated {
+			postmortemFileTitle = fmt.Sprintf("%s %s", createdAt.Format("2006/01/02"), draft.title)
 		}
-		actionItems = ai
-
-		// 学んだ教訓生成
-		lg, lb, ll, err := h.aiRepository.GenerateLessonsLearned(incident.Description, formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to GenerateLessonsLearned: %w", err)
+		// 伏せた値のうち戻してよいものは、元の発言から戻す
+		sources := []string{incident.Description}
+		for _, m := range slackMessages {
+			sources = append(sources, m.Text)
 		}
-		lessonsGood = lg
-		lessonsBad = lb
-		lessensLucky = ll
-
-		// タイムライン整形
-		formattedMessages, err = h.aiRepository.FormatTimeline(formattedMessages)
-		if err != nil {
-			return fmt.Errorf("failed to FormatTimeline: %w", err)
+		draft.restore(func(text string) string {
+			return h.aiRepository.Restore(text, sources...)
+		})
+		// AIで要約できた場合のみ事象内容を置き換える
+		if draft.summarized {
+			incident.Description = draft.sections.Summary
 		}
 	}
 
-	rendered := postmortem.Render(title, createdAt.Format("2006-01-02 15:04:05"), author, status, summary, impact, rootCause, trigger, solution, actionItems, lessonsGood, lessonsBad, lessensLucky, formattedMessages, channelURL)
+	data.Title = draft.title
+	data.Sections = draft.sections
+	data.Timeline = draft.timeline
+	rendered, err := tmpl.Render(data)
+	if err != nil {
+		return fmt.Errorf("failed to render postmortem: %w", err)
+	}
 
 	if h.postmortemExporter != nil {
-		service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
-		if err != nil {
-			slog.Error("failed to ServiceByID", slog.Any("err", err), slog.Any("serviceID", incident.ServiceID))
-		}
-
 		url, err := h.postmortemExporter.ExportPostMortem(h.ctx, postmortemFileTitle, rendered, service)
 		if err != nil {
 			return fmt.Errorf("failed to ExportPostMortem: %w", err)
@@ -932,7 +1146,7 @@ func (h *CallbackHandler) createPostMortem(channel slack.Channel, user slack.Use
 		incident.PostMortemURL = url
 	} else {
 		// アップロードする
-		url, err := h.repository.UploadFile(h.workSpaceURL, user.ID, channel.ID, postmortemFileTitle, title, rendered)
+		url, err := h.repository.UploadFile(h.workSpaceURL, user.ID, channel.ID, postmortemFileTitle, data.Title, rendered)
 		if err != nil {
 			return fmt.Errorf("failed to UploadFile: %w", err)
 		}
@@ -947,9 +1161,24 @@ func (h *CallbackHandler) createPostMortem(channel slack.Channel, user slack.Use
 		slog.Error("Failed to post postmortem created message", slog.Any("err", err))
 	}
 
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	postMortemURL, description := incident.PostMortemURL, incident.Description
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		i.PostMortemURL = postMortemURL
+		if draft.summarized {
+			i.Description = description
+		}
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channel.ID, entity.IncidentEventPostMortemCreated, user.ID, map[string]string{
+		"url": incident.PostMortemURL,
+	})
+	// 類似インシデントの検索には、ポストモーテムのうち原因と対応がわかる部分を使う
+	h.indexIncident(channel.ID, entity.EmbeddingSourcePostMortem, strings.Join([]string{
+		data.Title, data.Sections.Summary, data.Sections.RootCause, data.Sections.Solution,
+	}, "\n"))
 	return nil
 }
 
@@ -985,6 +1214,11 @@ func (h *CallbackHandler) broadCastAnnouncement(channelID string, attachment sla
 		slog.Error("failed to FindIncidentByChannel for linked channels", slog.Any("err", err))
 	}
 
+	// 担当者がいる場合は対応体制を添える
+	if incident != nil && blocks.RosterText(h.incidentRoles(), incident.Roles) != "" {
+		attachment.Blocks.BlockSet = append(attachment.Blocks.BlockSet, blocks.RosterContext(h.incidentRoles(), incident.Roles))
+	}
+
 	// 投稿済みチャンネルを追跡して重複を防止
 	postedChannels := make(map[string]bool)
 
@@ -1167,19 +1401,25 @@ func (h *CallbackHandler) submitEditSummaryModal(callback *slack.InteractionCall
 		return fmt.Errorf("incident is nil")
 	}
 
-	// 古い事象内容を保存
-	oldSummary := incident.Description
-
-	// 新しい事象内容を設定
-	incident.Description = summaryText
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	// 古い事象内容を保存し、新しい事象内容を設定
+	var oldSummary string
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		oldSummary = i.Description
+		i.Description = summaryText
+		return nil
+	})
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventSummaryEdited, userID, map[string]string{
+		"from": oldSummary,
+		"to":   summaryText,
+	})
+	h.indexIncident(channelID, entity.EmbeddingSourceDescription, summaryText)
 
 	// チャンネルのトピックも更新
-	channel, err := h.repository.GetChannelByID(channelID)
-	if err != nil {
-		return fmt.Errorf("failed to GetChannelByID: %w", err)
+	if err := h.updateIncidentTopic(incident); err != nil {
+		return err
 	}
 
 	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
@@ -1187,22 +1427,6 @@ func (h *CallbackHandler) submitEditSummaryModal(callback *slack.InteractionCall
 		return fmt.Errorf("failed to ServiceByID: %w", err)
 	}
 
-	urgencyText, ok := blocks.UrgencyMap[incident.Urgency]
-	if !ok {
-		return fmt.Errorf("invalid urgency: %s", incident.Urgency)
-	}
-
-	// トピックを更新（復旧済みの場合は【復旧】のプレフィックスを維持）
-	topic := fmt.Sprintf("サービス名:%s 緊急度:%s 事象内容:%s", service.Name, urgencyText, summaryText)
-	if strings.HasPrefix(channel.Topic.Value, "【復旧】") {
-		topic = fmt.Sprintf("【復旧】%s", topic)
-	}
-
-	err = h.repository.SetTopicOfConversation(channelID, topic)
-	if err != nil {
-		return fmt.Errorf("failed to SetTopicOfConversation: %w", err)
-	}
-
 	// 変更を通知
 	_, _, err = h.repository.PostMessage(
 		channelID,
@@ -1240,8 +1464,20 @@ func (h *CallbackHandler) reopenIncident(userID, channelID string) error {
 		return fmt.Errorf("incident is nil")
 	}
 
-	// 既に復旧していない場合はエラー
-	if incident.RecoveredAt.IsZero() {
+	// インシデントを再開状態に更新
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		// 復旧していない場合は再開できない
+		if i.RecoveredAt.IsZero() {
+			return errNotRecovered
+		}
+		i.ReopenedAt = timeNow()
+		i.ReopenedUserID = userID
+		i.RecoveredAt = time.Time{} // 復旧時刻をリセット
+		i.RecoveredUserID = ""      // 復旧者をリセット
+		i.DisableTimer = false      // タイマーを再開
+		return nil
+	})
+	if errors.Is(err, errNotRecovered) {
 		_, _, err := h.repository.PostMessage(
 			channelID,
 			slack.MsgOptionText("⚠️ インシデントはまだ復旧していません。復旧していないインシデントは再開できません。", false),
@@ -1251,17 +1487,10 @@ func (h *CallbackHandler) reopenIncident(userID, channelID string) error {
 		}
 		return nil
 	}
-
-	// インシデントを再開状態に更新
-	incident.ReopenedAt = timeNow()
-	incident.ReopenedUserID = userID
-	incident.RecoveredAt = time.Time{} // 復旧時刻をリセット
-	incident.RecoveredUserID = ""      // 復旧者をリセット
-	incident.DisableTimer = false      // タイマーを再開
-
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventReopened, userID, nil)
 
 	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
 	if err != nil {
@@ -1379,11 +1608,15 @@ func (h *CallbackHandler) createProgressSummary(channel slack.Channel, user slac
 	}
 
 	// インシデントにサマリ情報を保存
-	err = h.updateIncidentSummary(incident, summary, messages)
+	err = h.updateIncidentSummary(incident, user.ID, summary, messages)
 	if err != nil {
 		slog.Error("Failed to update incident summary", slog.Any("err", err))
 		// エラーでも続行（サマリ表示は成功したため）
 	}
+	// 進捗に合わせてインシデントレベルと緊急度を見直す
+	if err := h.suggestSeverity(channel.ID); err != nil {
+		slog.Warn("failed to suggest severity", slog.String("channelID", channel.ID), slog.Any("err", err))
+	}
 
 	return nil
 }
@@ -1431,17 +1664,24 @@ func (h *CallbackHandler) collectChannelMessages(channelID string, incident *ent
 }
 
 // インシデントのサマリ情報を更新
-func (h *CallbackHandler) updateIncidentSummary(incident *entity.Incident, summary string, messages []slack.Message) error {
+func (h *CallbackHandler) updateIncidentSummary(incident *entity.Incident, userID, summary string, messages []slack.Message) error {
 	now := time.Now()
-	incident.LastSummary = summary
-	incident.LastSummaryAt = now
+	err := updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		i.LastSummary = summary
+		i.LastSummaryAt = now
 
-	// 最後に処理したメッセージのタイムスタンプを更新
-	if len(messages) > 0 {
-		incident.LastProcessedMessageTS = messages[len(messages)-1].Timestamp
+		// 最後に処理したメッセージのタイムスタンプを更新
+		if len(messages) > 0 {
+			i.LastProcessedMessageTS = messages[len(messages)-1].Timestamp
+		}
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-
-	return h.repository.SaveIncident(h.ctx, incident)
+	recordIncidentEvent(h.ctx, h.repository, incident.ChannelID, entity.IncidentEventProgressSummaryUpdated, userID, nil)
+	h.indexIncident(incident.ChannelID, entity.EmbeddingSourceSummary, summary)
+	return nil
 }
 
 // フォールバック用のサマリ作成（従来の方式）
@@ -1557,13 +1797,7 @@ func (h *CallbackHandler) postToReportChannel(channel slack.Channel, user slack.
 		return nil
 	}
 
-	// 既存のbroadCastAnnouncement機能を使用してアナウンスチャンネルに投稿
-	attachment := slack.Attachment{
-		Color:  "#36a64f",
-		Blocks: slack.Blocks{BlockSet: blocks.ProgressSummaryAnnouncement(summaryText, channel.ID, service)},
-	}
-
-	if err := h.broadCastAnnouncement(channel.ID, attachment, service, false); err != nil {
+	if err := h.announceProgressSummary(channel.ID, summaryText, service); err != nil {
 		_, _, postErr := h.repository.PostMessage(
 			channel.ID,
 			slack.MsgOptionText("❌ アナウンスチャンネルへの投稿に失敗しました", false),
@@ -1586,6 +1820,15 @@ func (h *CallbackHandler) postToReportChannel(channel slack.Channel, user slack.
 	return nil
 }
 
+// announceProgressSummary は進捗サマリをアナウンスチャンネルに投稿する
+func (h *CallbackHandler) announceProgressSummary(channelID, summary string, service *entity.Service) error {
+	attachment := slack.Attachment{
+		Color:  "#36a64f",
+		Blocks: slack.Blocks{BlockSet: blocks.ProgressSummaryAnnouncement(summary, channelID, service)},
+	}
+	return h.broadCastAnnouncement(channelID, attachment, service, false)
+}
+
 // 進捗サマリを作成し、指定されたメッセージを更新する
 func (h *CallbackHandler) createProgressSummaryWithUpdate(channel slack.Channel, user slack.User, updateMsgTS string) error {
 	incident, err := h.repository.FindIncidentByChannel(h.ctx, channel.ID)
@@ -1647,11 +1890,15 @@ func (h *CallbackHandler) createProgressSummaryWithUpdate(channel slack.Channel,
 	)
 
 	// インシデントにサマリ情報を保存
-	err = h.updateIncidentSummary(incident, summary, messages)
+	err = h.updateIncidentSummary(incident, user.ID, summary, messages)
 	if err != nil {
 		slog.Error("Failed to update incident summary", slog.Any("err", err))
 		// エラーでも続行（サマリ表示は成功したため）
 	}
+	// 進捗に合わせてインシデントレベルと緊急度を見直す
+	if err := h.suggestSeverity(channel.ID); err != nil {
+		slog.Warn("failed to suggest severity", slog.String("channelID", channel.ID), slog.Any("err", err))
+	}
 
 	return nil
 }
@@ -1847,45 +2094,50 @@ func (h *CallbackHandler) submitLinkIncidentModal(callback *slack.InteractionCal
 		return fmt.Errorf("incident not found")
 	}
 
-	// 既に紐づけられていないかチェック
-	for _, linked := range incident.LinkedChannels {
-		if linked.ChannelID == linkChannelID && linked.ThreadTS == actualThreadTS {
-			var msgText string
-			if actualThreadTS != "" {
-				msgText = "このスレッドは既にインシデントに紐づけられています"
-			} else {
-				msgText = "このチャンネルは既にインシデントに紐づけられています"
-			}
-
-			msgOptions := []slack.MsgOption{
-				slack.MsgOptionText(msgText, false),
-			}
-			if actualThreadTS != "" {
-				msgOptions = append(msgOptions, slack.MsgOptionTS(actualThreadTS))
+	// 既に紐づけられていなければ新しい紐づけを追加して保存
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		for _, linked := range i.LinkedChannels {
+			if linked.ChannelID == linkChannelID && linked.ThreadTS == actualThreadTS {
+				return errAlreadyLinked
 			}
+		}
+		if i.LinkedChannels == nil {
+			i.LinkedChannels = []entity.LinkedChannel{}
+		}
+		i.LinkedChannels = append(i.LinkedChannels, entity.LinkedChannel{
+			ChannelID: linkChannelID,
+			ThreadTS:  actualThreadTS,
+		})
+		return nil
+	})
+	if errors.Is(err, errAlreadyLinked) {
+		var msgText string
+		if actualThreadTS != "" {
+			msgText = "このスレッドは既にインシデントに紐づけられています"
+		} else {
+			msgText = "このチャンネルは既にインシデントに紐づけられています"
+		}
 
-			_, _, err := h.repository.PostMessage(linkChannelID, msgOptions...)
-			if err != nil {
-				return fmt.Errorf("failed to post already linked message: %w", err)
-			}
-			return nil
+		msgOptions := []slack.MsgOption{
+			slack.MsgOptionText(msgText, false),
+		}
+		if actualThreadTS != "" {
+			msgOptions = append(msgOptions, slack.MsgOptionTS(actualThreadTS))
 		}
-	}
 
-	// 新しい紐づけを追加
-	if incident.LinkedChannels == nil {
-		incident.LinkedChannels = []entity.LinkedChannel{}
+		_, _, err := h.repository.PostMessage(linkChannelID, msgOptions...)
+		if err != nil {
+			return fmt.Errorf("failed to post already linked message: %w", err)
+		}
+		return nil
 	}
-
-	incident.LinkedChannels = append(incident.LinkedChannels, entity.LinkedChannel{
-		ChannelID: linkChannelID,
-		ThreadTS:  actualThreadTS,
-	})
-
-	// インシデントを保存
-	if err := h.repository.SaveIncident(h.ctx, incident); err != nil {
+	if err != nil {
 		return fmt.Errorf("failed to SaveIncident: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, incidentChannelID, entity.IncidentEventLinked, callback.User.ID, map[string]string{
+		"channel_id": linkChannelID,
+		"thread_ts":  actualThreadTS,
+	})
 
 	// 紐づけ成功メッセージを投稿
 	incidentChannel, err := h.repository.GetChannelByID(incidentChannelID)
@@ -1958,13 +2210,11 @@ func (h *CallbackHandler) unlinkFromIncident(callback *slack.InteractionCallback
 	}
 
 	var foundIncident *entity.Incident
-	var linkIndex = -1
 
 	for i, incident := range incidents {
-		for j, linked := range incident.LinkedChannels {
+		for _, linked := range incident.LinkedChannels {
 			if linked.ChannelID == channelID && linked.ThreadTS == threadTS {
 				foundIncident = &incidents[i]
-				linkIndex = j
 				break
 			}
 		}
@@ -1973,7 +2223,24 @@ func (h *CallbackHandler) unlinkFromIncident(callback *slack.InteractionCallback
 		}
 	}
 
-	if foundIncident == nil {
+	// 紐づけを削除して保存
+	if foundIncident != nil {
+		err = updateIncident(h.ctx, h.repository, foundIncident, func(i *entity.Incident) error {
+			for j, linked := range i.LinkedChannels {
+				if linked.ChannelID == channelID && linked.ThreadTS == threadTS {
+					i.LinkedChannels = append(i.LinkedChannels[:j], i.LinkedChannels[j+1:]...)
+					return nil
+				}
+			}
+			// 他の操作で先に解除されていた
+			return errNotLinked
+		})
+		if err != nil && !errors.Is(err, errNotLinked) {
+			return fmt.Errorf("failed to SaveIncident: %w", err)
+		}
+	}
+
+	if foundIncident == nil || errors.Is(err, errNotLinked) {
 		var msgText string
 		if threadTS != "" {
 			msgText = "このスレッドはインシデントに紐づけられていません"
@@ -1995,16 +2262,10 @@ func (h *CallbackHandler) unlinkFromIncident(callback *slack.InteractionCallback
 		return nil
 	}
 
-	// 紐づけを削除
-	foundIncident.LinkedChannels = append(
-		foundIncident.LinkedChannels[:linkIndex],
-		foundIncident.LinkedChannels[linkIndex+1:]...,
-	)
-
-	// インシデントを保存
-	if err := h.repository.SaveIncident(h.ctx, foundIncident); err != nil {
-		return fmt.Errorf("failed to SaveIncident: %w", err)
-	}
+	recordIncidentEvent(h.ctx, h.repository, foundIncident.ChannelID, entity.IncidentEventUnlinked, callback.User.ID, map[string]string{
+		"channel_id": channelID,
+		"thread_ts":  threadTS,
+	})
 
 	// 解除成功メッセージを送信
 	incidentChannel, err := h.repository.GetChannelByID(foundIncident.ChannelID)
@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"errors"
 	"strings"
 	"testing"
 
@@ -78,6 +79,30 @@ func (m *mockAIRepository) PrepareMessagesForPostMortem(messages []slack.Message
 	return "", nil
 }
 
+func (m *mockAIRepository) AnswerQuestion(description, question string, messages []slack.Message) (string, error) {
+	return "", nil
+}
+
+func (m *mockAIRepository) SuggestSeverity(description, slackMessages string, levels []entity.IncidentLevel, urgencies map[string]string) (*repository.SeveritySuggestion, error) {
+	return nil, errors.New("not supported")
+}
+
+func (m *mockAIRepository) DraftCommunication(audience, description, summary, slackMessages string) (string, error) {
+	return "", errors.New("not supported")
+}
+
+func (m *mockAIRepository) Embed(texts ...string) ([][]float32, string, error) {
+	return nil, "", errors.New("not supported")
+}
+
+func (m *mockAIRepository) Redact(text string) string {
+	return text
+}
+
+func (m *mockAIRepository) Restore(text string, sources ...string) string {
+	return text
+}
+
 func TestSummarizeProgress(t *testing.T) {
 	t.Setenv("TEST_MODE", "true")
 
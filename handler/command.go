@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+const IncidentCommand = "/incident"
+
+// エスケープされたユーザーメンション <@U123|name> または <@U123>
+var userMentionPattern = regexp.MustCompile(`^<@([A-Z0-9]+)(?:\|[^>]*)?>$`)
+
+// HandleCommand は /incident スラッシュコマンドをサブコマンドに応じた操作に振り分ける
+func (h *CallbackHandler) HandleCommand(cmd *slack.SlashCommand) error {
+	if cmd.Command != IncidentCommand {
+		return fmt.Errorf("unsupported command: %s", cmd.Command)
+	}
+
+	args := strings.Fields(cmd.Text)
+	subcommand := ""
+	if len(args) > 0 {
+		subcommand = strings.ToLower(args[0])
+		args = args[1:]
+	}
+	slog.Info("HandleCommand", slog.String("subcommand", subcommand), slog.String("channelID", cmd.ChannelID), slog.String("userID", cmd.UserID))
+
+	var err error
+	switch subcommand {
+	case "new":
+		err = h.openIncidentModal(cmd.TriggerID, cmd.ChannelID)
+	case "list":
+		err = h.commandList(cmd)
+	case "level", "recover", "reopen", "handler", "summary", "status", "ask":
+		err = h.handleIncidentCommand(cmd, subcommand, args)
+	default:
+		h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionBlocks(blocks.IncidentCommandHelp()...))
+	}
+
+	if err != nil {
+		h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(fmt.Sprintf("❌ `%s %s` の実行に失敗しました: %s", IncidentCommand, subcommand, err), false))
+		return fmt.Errorf("%s %s failed: %w", IncidentCommand, subcommand, err)
+	}
+	return nil
+}
+
+// インシデントチャンネル内でのみ実行できるサブコマンド
+func (h *CallbackHandler) handleIncidentCommand(cmd *slack.SlashCommand, subcommand string, args []string) error {
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, cmd.ChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText("⛔️ このチャンネルはインシデントチャンネルではありません", false))
+		return nil
+	}
+
+	switch subcommand {
+	case "level":
+		return h.commandLevel(cmd, args)
+	case "recover":
+		return h.recoveryIncident(cmd.UserID, cmd.ChannelID)
+	case "reopen":
+		return h.reopenIncident(cmd.UserID, cmd.ChannelID)
+	case "handler":
+		userID := cmd.UserID
+		if len(args) > 0 {
+			m := userMentionPattern.FindStringSubmatch(args[0])
+			if m == nil {
+				h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText("⛔️ ハンドラは `@ユーザー` の形式で指定してください", false))
+				return nil
+			}
+			userID = m[1]
+		}
+		return h.submitHandler(userID, cmd.ChannelID)
+	case "summary":
+		return h.openEditSummaryModal(cmd.TriggerID, cmd.ChannelID)
+	case "status":
+		return h.commandStatus(cmd, incident)
+	case "ask":
+		return h.commandAsk(cmd, args)
+	}
+	return nil
+}
+
+func (h *CallbackHandler) commandLevel(cmd *slack.SlashCommand, args []string) error {
+	levels := h.repository.IncidentLevels(h.ctx)
+	valid := make([]string, 0, len(levels))
+	for _, l := range levels {
+		valid = append(valid, fmt.Sprintf("`%d`: %s", l.Level, l.Description))
+	}
+	usage := fmt.Sprintf("⛔️ `%s level <n>` の形式でレベルを指定してください\n%s", IncidentCommand, strings.Join(valid, "\n"))
+
+	if len(args) == 0 {
+		h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(usage, false))
+		return nil
+	}
+	level, err := strconv.Atoi(args[0])
+	if err != nil {
+		h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(usage, false))
+		return nil
+	}
+	if level != 0 {
+		if _, err := h.repository.IncidentLevelByLevel(h.ctx, level); err != nil {
+			h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(usage, false))
+			return nil
+		}
+	}
+	return h.setIncidentLevel(cmd.ChannelID, cmd.UserID, strconv.Itoa(level))
+}
+
+func (h *CallbackHandler) commandStatus(cmd *slack.SlashCommand, incident *entity.Incident) error {
+	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to ServiceByID: %w", err)
+	}
+
+	levelText := "サービスに影響なし"
+	if incident.Level > 0 {
+		if l, err := h.repository.IncidentLevelByLevel(h.ctx, incident.Level); err == nil && l != nil {
+			levelText = l.Description
+		}
+	}
+	urgencyText, ok := blocks.UrgencyMap[incident.Urgency]
+	if !ok {
+		urgencyText = incident.Urgency
+	}
+
+	h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionBlocks(blocks.IncidentStatus(incident, service, levelText, urgencyText, timeNow())...))
+	return nil
+}
+
+// commandAsk は質問をチャンネルに投稿し、そのスレッドで回答する
+func (h *CallbackHandler) commandAsk(cmd *slack.SlashCommand, args []string) error {
+	question := strings.Join(args, " ")
+	if question == "" {
+		h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionText(fmt.Sprintf("⛔️ `%s ask <質問>` の形式で質問を指定してください", IncidentCommand), false))
+		return nil
+	}
+	_, ts, err := h.repository.PostMessage(cmd.ChannelID, slack.MsgOptionText(fmt.Sprintf("❓ <@%s> の質問: %s", cmd.UserID, question), false))
+	if err != nil {
+		return fmt.Errorf("failed to post question: %w", err)
+	}
+	return h.answerQuestion(cmd.ChannelID, cmd.UserID, question, ts, ts)
+}
+
+func (h *CallbackHandler) commandList(cmd *slack.SlashCommand) error {
+	incidents, err := h.repository.ActiveIncidents(h.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ActiveIncidents: %w", err)
+	}
+
+	sort.Slice(incidents, func(i, j int) bool {
+		return incidents[i].StartedAt.After(incidents[j].StartedAt)
+	})
+
+	lines := make([]string, 0, len(incidents))
+	for _, incident := range incidents {
+		serviceName := "不明なサービス"
+		if service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID); err == nil && service != nil {
+			serviceName = service.Name
+		}
+		status := "🚨"
+		if !incident.RecoveredAt.IsZero() {
+			status = "✅"
+		}
+		lines = append(lines, fmt.Sprintf("%s <#%s> %s - %s", status, incident.ChannelID, serviceName, incident.Description))
+	}
+
+	h.postEphemeral(cmd.ChannelID, cmd.UserID, slack.MsgOptionBlocks(blocks.OpenIncidentSummaries(lines)...))
+	return nil
+}
+
+func (h *CallbackHandler) postEphemeral(channelID, userID string, opts ...slack.MsgOption) {
+	if _, err := h.repository.PostEphemeral(channelID, userID, opts...); err != nil {
+		slog.Error("Failed to post ephemeral message", slog.Any("err", err))
+	}
+}
@@ -0,0 +1,186 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+	"golang.org/x/sync/errgroup"
+)
+
+// communicationChannels は audience 向けの連絡の投稿先。サービスと全体の設定を合わせる
+func (h *CallbackHandler) communicationChannels(service *entity.Service, audience string) []string {
+	channels := slices.Clone(service.Communication.Channels(audience))
+	if h.config != nil {
+		channels = append(channels, h.config.GetCommunicationChannels(h.ctx).Channels(audience)...)
+	}
+	slices.Sort(channels)
+	return slices.Compact(channels)
+}
+
+// draftCommunications は事象内容、最新の進捗サマリ、チャンネルの発言から読み手ごとの連絡の下書きを作成して投稿する
+// 下書きは編集してから投稿するため、この時点では読み手のチャンネルには投稿しない
+func (h *CallbackHandler) draftCommunications(channelID string) error {
+	if h.aiRepository == nil {
+		_, _, err := h.repository.PostMessage(channelID, slack.MsgOptionText("❌ AI が設定されていないため連絡の下書きを作成できません", false))
+		return err
+	}
+
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to ServiceByID: %w", err)
+	}
+
+	_, loadingTS, err := h.repository.PostMessage(channelID, slack.MsgOptionBlocks(blocks.CommunicationDraftsLoading()...))
+	if err != nil {
+		return fmt.Errorf("failed to post loading message: %w", err)
+	}
+
+	messages, err := h.repository.GetAllChannelMessages(channelID)
+	if err != nil {
+		h.repository.UpdateMessage(channelID, loadingTS, slack.MsgOptionText("❌ メッセージの収集に失敗しました", false))
+		return fmt.Errorf("failed to GetAllChannelMessages: %w", err)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Timestamp < messages[j].Timestamp
+	})
+	slackMessages, err := h.aiRepository.PrepareMessagesForPostMortem(messages, incident.Description)
+	if err != nil {
+		h.repository.UpdateMessage(channelID, loadingTS, slack.MsgOptionText("❌ メッセージの準備に失敗しました", false))
+		return fmt.Errorf("failed to PrepareMessagesForPostMortem: %w", err)
+	}
+
+	items := make([]blocks.CommunicationDraftItem, len(entity.Audiences))
+	var g errgroup.Group
+	for i, audience := range entity.Audiences {
+		items[i] = blocks.CommunicationDraftItem{
+			Audience: audience,
+			Channels: h.communicationChannels(service, audience),
+		}
+		g.Go(func() error {
+			text, err := h.aiRepository.DraftCommunication(audience, incident.Description, incident.LastSummary, slackMessages)
+			if err != nil {
+				slog.Error("Failed to draft communication", slog.String("channelID", channelID), slog.String("audience", audience), slog.Any("err", err))
+				return nil
+			}
+			items[i].Text = strings.TrimSpace(text)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	h.repository.UpdateMessage(channelID, loadingTS, slack.MsgOptionBlocks(blocks.CommunicationDrafts(items)...))
+	return nil
+}
+
+// communicationMetadata はモーダルの PrivateMetadata。チャンネル、下書きのメッセージ、読み手を | でつなぐ
+func communicationMetadata(channelID, messageTS, audience string) string {
+	return strings.Join([]string{channelID, messageTS, audience}, "|")
+}
+
+func parseCommunicationMetadata(metadata string) (string, string, string, error) {
+	parts := strings.Split(metadata, "|")
+	if len(parts) != 3 {
+		return "", "", "", fmt.Errorf("invalid communication metadata: %s", metadata)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+// openCommunicationModal は下書きのメッセージから audience 向けの本文を読み出し、編集するモーダルを開く
+func (h *CallbackHandler) openCommunicationModal(triggerID, channelID, audience string, message slack.Message) error {
+	var text string
+	for _, b := range message.Blocks.BlockSet {
+		if section, ok := b.(*slack.SectionBlock); ok && section.BlockID == blocks.CommunicationDraftBlockID(audience) && section.Text != nil {
+			text = section.Text.Text
+		}
+	}
+
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to ServiceByID: %w", err)
+	}
+
+	view := slack.ModalViewRequest{
+		Type:            slack.ViewType("modal"),
+		Title:           slack.NewTextBlockObject("plain_text", "✍️ 連絡の編集", false, false),
+		CallbackID:      "communication_modal",
+		Submit:          slack.NewTextBlockObject("plain_text", "📣 投稿", false, false),
+		Close:           slack.NewTextBlockObject("plain_text", "❌ キャンセル", false, false),
+		Blocks:          blocks.EditCommunication(audience, text, h.communicationChannels(service, audience)),
+		PrivateMetadata: communicationMetadata(channelID, message.Timestamp, audience),
+	}
+	return h.repository.OpenView(triggerID, view)
+}
+
+// submitCommunicationModal は編集した連絡を読み手のチャンネルに投稿し、下書きのスレッドに投稿したことを残す
+func (h *CallbackHandler) submitCommunicationModal(callback *slack.InteractionCallback) error {
+	channelID, messageTS, audience, err := parseCommunicationMetadata(callback.View.PrivateMetadata)
+	if err != nil {
+		return err
+	}
+	text := callback.View.State.Values["communication_block"]["communication_text"].Value
+	userID := callback.User.ID
+
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to ServiceByID: %w", err)
+	}
+
+	var posted []string
+	for _, name := range h.communicationChannels(service, audience) {
+		ch, err := h.repository.GetChannelByName(name)
+		if err != nil || ch == nil {
+			slog.Error("Failed to find communication channel", slog.String("channel", name), slog.Any("err", err))
+			continue
+		}
+		_, _, err = h.repository.PostMessage(ch.ID, slack.MsgOptionBlocks(blocks.Communication(audience, text, channelID, userID)...))
+		if err != nil {
+			slog.Error("Failed to post communication", slog.String("channel", name), slog.Any("err", err))
+			continue
+		}
+		posted = append(posted, name)
+	}
+	if len(posted) == 0 {
+		_, _, err := h.repository.PostMessage(channelID,
+			slack.MsgOptionText(fmt.Sprintf("❌ %sの連絡を投稿できませんでした", blocks.AudienceNames[audience]), false),
+			slack.MsgOptionTS(messageTS),
+		)
+		return err
+	}
+
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventCommunicationPosted, userID, map[string]string{
+		"audience": audience,
+		"channels": strings.Join(posted, ","),
+	})
+	_, _, err = h.repository.PostMessage(channelID,
+		slack.MsgOptionText(blocks.CommunicationPosted(audience, userID, posted), false),
+		slack.MsgOptionTS(messageTS),
+	)
+	return err
+}
@@ -0,0 +1,133 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+	"github.com/pyama86/YAS3/presentation/blocks"
+)
+
+// communicationAI は読み手ごとに決まった下書きを返す。drafts に無い読み手は失敗する
+type communicationAI struct {
+	mockAIRepository
+	drafts map[string]string
+	mu     sync.Mutex
+	called []string
+}
+
+func (m *communicationAI) DraftCommunication(audience, description, summary, slackMessages string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.called = append(m.called, audience)
+	draft, ok := m.drafts[audience]
+	if !ok {
+		return "", errors.New("failed")
+	}
+	return draft, nil
+}
+
+func TestCallbackHandler_CommunicationDrafts(t *testing.T) {
+	incRepo := &mockIncidentRepo{
+		data: map[string]*entity.Incident{
+			"CINC": {ChannelID: "CINC", ServiceID: 1, Description: "APIが遅い", StartedAt: time.Now()},
+		},
+	}
+	cfgRepo := &mockConfigRepo{
+		services: []entity.Service{{ID: 1, Name: "svc", Communication: entity.CommunicationChannels{
+			Executive: []string{"exec"},
+			Customer:  []string{"cs"},
+		}}},
+	}
+	slackRepo := &mockSlackRepo{history: []slack.Message{
+		{Msg: slack.Msg{User: "U1", Text: "レイテンシが悪化しています", Timestamp: "100.000001"}},
+	}}
+	ai := &communicationAI{drafts: map[string]string{
+		entity.AudienceExecutive: "APIの応答が遅延しています",
+		entity.AudienceCustomer:  "ご不便をおかけしております",
+	}}
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+	h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", ai, nil, nil)
+
+	require.NoError(t, h.Handle(&slack.InteractionCallback{
+		Type: slack.InteractionTypeBlockActions,
+		Channel: slack.Channel{
+			GroupConversation: slack.GroupConversation{
+				Conversation: slack.Conversation{ID: "CINC"},
+			},
+		},
+		User: slack.User{ID: "UA"},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: "communication_drafts_action"}},
+		},
+	}))
+	assert.ElementsMatch(t, entity.Audiences, ai.called)
+	drafts := slackRepo.updated[len(slackRepo.updated)-1]
+	assert.Contains(t, drafts, "ご不便をおかけしております")
+	assert.Contains(t, drafts, `"block_id":"communication_edit_customer"`)
+	assert.Contains(t, drafts, "下書きの作成に失敗しました", "失敗した読み手も表示する")
+	assert.NotContains(t, drafts, `"block_id":"communication_edit_engineering"`, "下書きが無い読み手は投稿できない")
+	assert.Empty(t, slackRepo.messages["C123456"], "編集するまで投稿しない")
+
+	// 下書きのメッセージから本文を読み出してモーダルを開く
+	message := slack.Message{Msg: slack.Msg{
+		Timestamp: "111.222",
+		Blocks: slack.Blocks{BlockSet: blocks.CommunicationDrafts([]blocks.CommunicationDraftItem{
+			{Audience: entity.AudienceCustomer, Text: "ご不便をおかけしております", Channels: []string{"cs"}},
+		})},
+	}}
+	require.NoError(t, h.Handle(&slack.InteractionCallback{
+		Type:      slack.InteractionTypeBlockActions,
+		TriggerID: "trigger",
+		Channel: slack.Channel{
+			GroupConversation: slack.GroupConversation{
+				Conversation: slack.Conversation{ID: "CINC"},
+			},
+		},
+		Message: message,
+		User:    slack.User{ID: "UA"},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: "communication_edit", Value: entity.AudienceCustomer}},
+		},
+	}))
+	require.Len(t, slackRepo.views, 1)
+	view := slackRepo.views[0]
+	assert.Equal(t, "communication_modal", view.CallbackID)
+	input := view.Blocks.BlockSet[1].(*slack.InputBlock).Element.(*slack.PlainTextInputBlockElement)
+	assert.Equal(t, "ご不便をおかけしております", input.InitialValue)
+
+	require.NoError(t, h.Handle(&slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		User: slack.User{ID: "UB"},
+		View: slack.View{
+			CallbackID:      view.CallbackID,
+			PrivateMetadata: view.PrivateMetadata,
+			State: &slack.ViewState{Values: map[string]map[string]slack.BlockAction{
+				"communication_block": {"communication_text": {Value: "ご不便をおかけしております。現在復旧作業中です"}},
+			}},
+		},
+	}))
+	// モックはどのチャンネル名も同じIDを返す
+	require.Len(t, slackRepo.messages["C123456"], 1)
+	assert.Contains(t, slackRepo.messages["C123456"][0], "現在復旧作業中です", "編集した内容を投稿する")
+	assert.Contains(t, slackRepo.messages["CINC"][len(slackRepo.messages["CINC"])-1], "顧客向けの連絡を #cs に投稿しました")
+
+	var posted []entity.IncidentEvent
+	for _, ev := range incRepo.events {
+		if ev.Type == entity.IncidentEventCommunicationPosted {
+			posted = append(posted, ev)
+		}
+	}
+	require.Len(t, posted, 1)
+	assert.Equal(t, "UB", posted[0].ActorUserID)
+	assert.Equal(t, map[string]string{"audience": entity.AudienceCustomer, "channels": "cs"}, posted[0].Metadata)
+}
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+// AnnouncementConfig は全体のアナウンスチャンネルを返す
+type AnnouncementConfig interface {
+	GetGlobalAnnouncementChannels(ctx context.Context) []string
+}
+
+var errAlreadyEscalated = errors.New("incident is already escalated")
+
+// Escalator はサービスごとのエスカレーションポリシーに従い、対応が進んでいないインシデントを
+// エスカレーショングループにメンションし、アナウンスチャンネルにも投稿する
+type Escalator struct {
+	ctx        context.Context
+	repository repository.Repository
+	config     AnnouncementConfig
+	now        func() time.Time
+}
+
+func NewEscalator(ctx context.Context, repository repository.Repository, config AnnouncementConfig, now func() time.Time) *Escalator {
+	return &Escalator{
+		ctx:        ctx,
+		repository: repository,
+		config:     config,
+		now:        now,
+	}
+}
+
+// Tick はエスカレーションの条件を満たした未復旧のインシデントをエスカレーションする
+func (e *Escalator) Tick() error {
+	incidents, err := e.repository.ActiveIncidents(e.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ActiveIncidents: %w", err)
+	}
+	now := e.now()
+	for _, incident := range incidents {
+		if err := e.escalate(&incident, now); err != nil {
+			slog.Error("Failed to escalate incident", slog.Any("err", err), slog.String("channelID", incident.ChannelID))
+		}
+	}
+	return nil
+}
+
+// noHandlerDue はハンドラーが決まらないまま規定の時間が経過し、まだエスカレーションしていない場合に true を返す
+// 作成者は仮のハンドラーになるため、HandlerUserID ではなくハンドラーが決まった時刻で判定する
+func noHandlerDue(incident *entity.Incident, policy entity.EscalationPolicy, now time.Time) bool {
+	if policy.NoHandlerMinutes <= 0 || !incident.HandlerAssignedAt.IsZero() {
+		return false
+	}
+	if _, ok := incident.LastEscalation(entity.EscalationNoHandler); ok {
+		return false
+	}
+	return now.Sub(incident.StartedAt) >= time.Duration(policy.NoHandlerMinutes)*time.Minute
+}
+
+// silentSince は発言が無いとみなす期間の始まりを返す
+// 沈黙が続く間は SilentMinutes ごとに繰り返しエスカレーションする
+func silentSince(incident *entity.Incident, policy entity.EscalationPolicy, now time.Time) (time.Time, bool) {
+	if policy.SilentMinutes <= 0 || incident.Level < policy.SilentMinLevel {
+		return time.Time{}, false
+	}
+	window := time.Duration(policy.SilentMinutes) * time.Minute
+	since := now.Add(-window)
+	if incident.StartedAt.After(since) {
+		return time.Time{}, false
+	}
+	if last, ok := incident.LastEscalation(entity.EscalationSilent); ok && last.EscalatedAt.After(since) {
+		return time.Time{}, false
+	}
+	return since, true
+}
+
+func (e *Escalator) escalate(incident *entity.Incident, now time.Time) error {
+	if !incident.RecoveredAt.IsZero() {
+		return nil
+	}
+	service, err := e.repository.ServiceByID(e.ctx, incident.ServiceID)
+	if err != nil {
+		return nil
+	}
+	policy := service.Escalation
+	if len(policy.UserGroups) == 0 {
+		return nil
+	}
+
+	var reason string
+	var minutes int
+	if noHandlerDue(incident, policy, now) {
+		reason, minutes = entity.EscalationNoHandler, policy.NoHandlerMinutes
+	} else if since, ok := silentSince(incident, policy, now); ok {
+		// スレッドでのやり取りも発言として数える
+		active, err := e.repository.HasMessagesSince(incident.ChannelID, fmt.Sprintf("%d.000000", since.Unix()))
+		if err != nil {
+			return fmt.Errorf("failed to HasMessagesSince: %w", err)
+		}
+		if active {
+			return nil
+		}
+		reason, minutes = entity.EscalationSilent, policy.SilentMinutes
+	} else {
+		return nil
+	}
+
+	// 投稿より先に記録して、次の実行や他のプロセスと重複して投稿しないようにする
+	err = updateIncident(e.ctx, e.repository, incident, func(i *entity.Incident) error {
+		switch reason {
+		case entity.EscalationNoHandler:
+			if !noHandlerDue(i, policy, now) {
+				return errAlreadyEscalated
+			}
+		case entity.EscalationSilent:
+			if _, ok := silentSince(i, policy, now); !ok {
+				return errAlreadyEscalated
+			}
+		}
+		i.Escalations = append(i.Escalations, entity.Escalation{Reason: reason, EscalatedAt: now})
+		return nil
+	})
+	if errors.Is(err, errAlreadyEscalated) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save escalation: %w", err)
+	}
+	recordIncidentEvent(e.ctx, e.repository, incident.ChannelID, entity.IncidentEventEscalated, "", map[string]string{
+		"reason":      reason,
+		"user_groups": strings.Join(policy.UserGroups, ","),
+	})
+
+	_, _, err = e.repository.PostMessage(
+		incident.ChannelID,
+		slack.MsgOptionBlocks(blocks.Escalation(e.mentions(policy.UserGroups), reason, minutes)...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post escalation: %w", err)
+	}
+
+	announceChannels := map[string]bool{}
+	for _, c := range service.AnnouncementChannels {
+		announceChannels[c] = true
+	}
+	for _, c := range e.config.GetGlobalAnnouncementChannels(e.ctx) {
+		announceChannels[c] = true
+	}
+	for c := range announceChannels {
+		cinfo, err := e.repository.GetChannelByName(c)
+		if err != nil {
+			slog.Error("failed to GetChannelByName", slog.Any("err", err), slog.Any("channel", c))
+			continue
+		}
+		if cinfo == nil {
+			continue
+		}
+		_, _, err = e.repository.PostMessage(
+			cinfo.ID,
+			slack.MsgOptionBlocks(blocks.EscalationAnnouncement(incident.ChannelID, reason, minutes)...),
+		)
+		if err != nil {
+			slog.Error("Failed to post escalation announcement", slog.Any("err", err), slog.String("channel", c))
+		}
+	}
+	return nil
+}
+
+// mentions はユーザーグループとユーザーのメンションを返す。見つからない場合は名前のまま表示する
+func (e *Escalator) mentions(names []string) string {
+	mentions := make([]string, 0, len(names))
+	for _, name := range names {
+		id, err := e.repository.GetSlackID(name)
+		switch {
+		case err != nil:
+			slog.Warn("failed to GetSlackID", slog.Any("err", err), slog.String("name", name))
+			mentions = append(mentions, "@"+name)
+		case strings.HasPrefix(id, "S"):
+			mentions = append(mentions, fmt.Sprintf("<!subteam^%s>", id))
+		default:
+			mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+		}
+	}
+	return strings.Join(mentions, " ")
+}
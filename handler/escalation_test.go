@@ -0,0 +1,187 @@
+package handler_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+func TestEscalator_Tick(t *testing.T) {
+	startedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	setup := func(incident entity.Incident, policy entity.EscalationPolicy) (*handler.Escalator, *mockIncidentRepo, *mockSlackRepo, *time.Time) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{incident.ChannelID: &incident}}
+		slackRepo := &mockSlackRepo{}
+		cfgRepo := &mockConfigRepo{
+			services: []entity.Service{{ID: 1, Name: "api", AnnouncementChannels: []string{"api-alerts"}, Escalation: policy}},
+			announce: []string{"all"},
+		}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+		now := startedAt
+		return handler.NewEscalator(context.Background(), repo, cfgRepo, func() time.Time { return now }), incRepo, slackRepo, &now
+	}
+	// 保存された最新のインシデントを ActiveIncidents から返す
+	tick := func(t *testing.T, e *handler.Escalator, incRepo *mockIncidentRepo) {
+		t.Helper()
+		incRepo.active = nil
+		for _, inc := range incRepo.data {
+			incRepo.active = append(incRepo.active, *inc)
+		}
+		require.NoError(t, e.Tick())
+	}
+
+	t.Run("no handler", func(t *testing.T) {
+		e, incRepo, slackRepo, now := setup(
+			entity.Incident{ChannelID: "C1", ServiceID: 1, Level: 1, StartedAt: startedAt},
+			entity.EscalationPolicy{NoHandlerMinutes: 10, UserGroups: []string{"sre"}},
+		)
+
+		*now = startedAt.Add(9 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Empty(t, slackRepo.messages)
+
+		*now = startedAt.Add(10 * time.Minute)
+		tick(t, e, incRepo)
+		tick(t, e, incRepo)
+		require.Len(t, slackRepo.messages["C1"], 1)
+		assert.Contains(t, slackRepo.messages["C1"][0], `\u003c@U123456\u003e`)
+		// モックはどのチャンネル名も同じIDを返すため、サービスと全体の両方がC123456に投稿される
+		assert.Len(t, slackRepo.messages["C123456"], 2)
+
+		inc := incRepo.data["C1"]
+		require.Len(t, inc.Escalations, 1)
+		assert.Equal(t, entity.EscalationNoHandler, inc.Escalations[0].Reason)
+		require.Len(t, incRepo.events, 1)
+		assert.Equal(t, entity.IncidentEventEscalated, incRepo.events[0].Type)
+	})
+
+	t.Run("handler assigned", func(t *testing.T) {
+		e, incRepo, slackRepo, now := setup(
+			entity.Incident{ChannelID: "C1", ServiceID: 1, Level: 1, StartedAt: startedAt, HandlerUserID: "UHANDLER", HandlerAssignedAt: startedAt},
+			entity.EscalationPolicy{NoHandlerMinutes: 10, UserGroups: []string{"sre"}},
+		)
+
+		*now = startedAt.Add(30 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Empty(t, slackRepo.messages)
+	})
+
+	t.Run("モーダルから作成したインシデントは作成者がハンドラーでも応募が無ければエスカレーションする", func(t *testing.T) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{}}
+		slackRepo := &mockSlackRepo{}
+		cfgRepo := &mockConfigRepo{services: []entity.Service{{ID: 1, Name: "api", Escalation: entity.EscalationPolicy{NoHandlerMinutes: 10, UserGroups: []string{"sre"}}}}}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+		h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
+		require.NoError(t, h.Handle(&slack.InteractionCallback{
+			Type: slack.InteractionTypeViewSubmission,
+			View: slack.View{
+				CallbackID: "incident_modal",
+				State: &slack.ViewState{
+					Values: map[string]map[string]slack.BlockAction{
+						"service_block":          {"service_select": {SelectedOption: slack.OptionBlockObject{Value: "1"}}},
+						"incident_summary_block": {"summary_text": {Value: "APIが応答しない"}},
+						"urgency_block":          {"urgency_select": {SelectedOption: slack.OptionBlockObject{Value: "none"}}},
+					},
+				},
+			},
+			User: slack.User{ID: "UMODAL"},
+		}))
+		require.Len(t, incRepo.data, 1)
+		var incident *entity.Incident
+		for _, inc := range incRepo.data {
+			incident = inc
+		}
+		assert.Equal(t, "UMODAL", incident.HandlerUserID)
+		assert.True(t, incident.HandlerAssignedAt.IsZero())
+
+		now := incident.StartedAt.Add(10 * time.Minute)
+		e := handler.NewEscalator(context.Background(), repo, cfgRepo, func() time.Time { return now })
+		tick(t, e, incRepo)
+		require.Len(t, incRepo.data[incident.ChannelID].Escalations, 1)
+		assert.Equal(t, entity.EscalationNoHandler, incRepo.data[incident.ChannelID].Escalations[0].Reason)
+
+		// ハンドラーに応募するとエスカレーションの対象から外れる
+		require.NoError(t, h.Handle(&slack.InteractionCallback{
+			Type: slack.InteractionTypeBlockActions,
+			Channel: slack.Channel{
+				GroupConversation: slack.GroupConversation{
+					Conversation: slack.Conversation{ID: incident.ChannelID},
+				},
+			},
+			User: slack.User{ID: "UHANDLER"},
+			ActionCallback: slack.ActionCallbacks{
+				BlockActions: []*slack.BlockAction{{ActionID: "handler_button"}},
+			},
+		}))
+		assert.Equal(t, "UHANDLER", incRepo.data[incident.ChannelID].HandlerUserID)
+		assert.False(t, incRepo.data[incident.ChannelID].HandlerAssignedAt.IsZero())
+	})
+
+	t.Run("silent", func(t *testing.T) {
+		policy := entity.EscalationPolicy{SilentMinutes: 30, SilentMinLevel: 2, UserGroups: []string{"sre"}}
+		e, incRepo, slackRepo, now := setup(
+			entity.Incident{ChannelID: "C1", ServiceID: 1, Level: 3, StartedAt: startedAt, HandlerUserID: "UHANDLER", HandlerAssignedAt: startedAt},
+			policy,
+		)
+
+		*now = startedAt.Add(31 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 1)
+
+		*now = startedAt.Add(50 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 1)
+
+		// 沈黙が続く場合は繰り返す
+		*now = startedAt.Add(62 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 2)
+
+		// 発言があればエスカレーションしない
+		*now = startedAt.Add(100 * time.Minute)
+		slackRepo.history = []slack.Message{{Msg: slack.Msg{User: "U1", Text: "調査中", Timestamp: slackTS(now.Add(-time.Minute))}}}
+		tick(t, e, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 2)
+		assert.Len(t, incRepo.data["C1"].Escalations, 2)
+
+		// 古い投稿へのスレッドの返信も発言として数える
+		*now = startedAt.Add(200 * time.Minute)
+		slackRepo.history = []slack.Message{{Msg: slack.Msg{
+			User:        "U1",
+			Text:        "調査中",
+			Timestamp:   slackTS(startedAt),
+			LatestReply: slackTS(now.Add(-time.Minute)),
+		}}}
+		tick(t, e, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 2)
+
+		// スレッドの返信も途絶えればエスカレーションする
+		*now = startedAt.Add(300 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 3)
+	})
+
+	t.Run("silent below level", func(t *testing.T) {
+		e, incRepo, slackRepo, now := setup(
+			entity.Incident{ChannelID: "C1", ServiceID: 1, Level: 1, StartedAt: startedAt, HandlerUserID: "UHANDLER", HandlerAssignedAt: startedAt},
+			entity.EscalationPolicy{SilentMinutes: 30, SilentMinLevel: 2, UserGroups: []string{"sre"}},
+		)
+
+		*now = startedAt.Add(60 * time.Minute)
+		tick(t, e, incRepo)
+		assert.Empty(t, slackRepo.messages)
+	})
+}
+
+func slackTS(t time.Time) string {
+	return fmt.Sprintf("%d.000000", t.Unix())
+}
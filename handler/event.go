@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log/slog"
 
+	"github.com/pyama86/YAS3/domain/entity"
 	"github.com/pyama86/YAS3/domain/repository"
 	"github.com/pyama86/YAS3/presentation/blocks"
 	"github.com/slack-go/slack"
@@ -54,11 +55,14 @@ func (h *EventHandler) saveClosedAt(event *slackevents.ChannelArchiveEvent) erro
 	if incident == nil {
 		return nil
 	}
-	incident.ClosedAt = timeNow()
-	err = h.repository.SaveIncident(h.ctx, incident)
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		i.ClosedAt = timeNow()
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to UpdateClosedAt: %w", err)
 	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventClosed, event.User, nil)
 	return nil
 }
 
@@ -101,6 +105,15 @@ func (h *EventHandler) handleMetionEvent(event *slackevents.AppMentionEvent) err
 			return fmt.Errorf("failed to PostMessage: %w", err)
 		}
 	} else {
+		// メンションに質問が書かれている場合はメニューの代わりに回答する
+		if question := mentionQuestion(event.Text); question != "" && h.callbackHandler != nil {
+			threadTS := event.ThreadTimeStamp
+			if threadTS == "" {
+				threadTS = event.TimeStamp
+			}
+			return h.callbackHandler.answerQuestion(channelID, event.User, question, event.TimeStamp, threadTS)
+		}
+
 		msgOptions := []slack.MsgOption{
 			slack.MsgOptionBlocks(blocks.IncidentMenu()...),
 		}
@@ -4,12 +4,11 @@ import (
 	"context"
 	"fmt"
 	"log/slog"
+	"net/http"
 	"os"
 	"time"
 
-	"github.com/pyama86/YAS3/domain/entity"
 	"github.com/pyama86/YAS3/domain/repository"
-	"github.com/pyama86/YAS3/presentation/blocks"
 	"github.com/slack-go/slack"
 	"github.com/slack-go/slack/slackevents"
 	"github.com/slack-go/slack/socketmode"
@@ -19,14 +18,34 @@ type Handler interface {
 	Handle(event slackevents.EventsAPIInnerEvent) error
 }
 
-func Handle(ctx context.Context, configPath string) error {
+// Options は起動方法を指定する
+type Options struct {
+	// ModeSocket(デフォルト) または ModeHTTP
+	Mode string
+	// HTTPモードで待ち受けるアドレス
+	Listen string
+	// 複数のレプリカで動かす場合に、定期処理を1台だけで実行する
+	LeaderElection bool
+}
+
+func Handle(ctx context.Context, configPath string, opts Options) error {
+	switch opts.Mode {
+	case "", ModeSocket:
+		if os.Getenv("SLACK_APP_TOKEN") == "" {
+			return fmt.Errorf("environment variable SLACK_APP_TOKEN is required for %s mode", ModeSocket)
+		}
+	case ModeHTTP:
+		if os.Getenv("SLACK_SIGNING_SECRET") == "" {
+			return fmt.Errorf("environment variable SLACK_SIGNING_SECRET is required for %s mode", ModeHTTP)
+		}
+	default:
+		return fmt.Errorf("unsupported mode: %s", opts.Mode)
+	}
+
 	webApi := slack.New(
 		os.Getenv("SLACK_BOT_TOKEN"),
 		slack.OptionAppLevelToken(os.Getenv("SLACK_APP_TOKEN")),
 	)
-	socketMode := socketmode.New(
-		webApi,
-	)
 	authTest, authTestErr := webApi.AuthTest()
 	if authTestErr != nil {
 		fmt.Fprintf(os.Stderr, "SLACK_BOT_TOKEN is invalid: %v\n", authTestErr)
@@ -36,7 +55,7 @@ func Handle(ctx context.Context, configPath string) error {
 	workSpaceURL := authTest.URL
 	slog.Info("Bot ID", slog.String("bot_id", botID))
 
-	dynamoRepository, err := repository.NewDynamoDBRepository()
+	dbRepository, err := repository.NewDBRepository()
 	if err != nil {
 		return err
 	}
@@ -45,15 +64,21 @@ func Handle(ctx context.Context, configPath string) error {
 	if err != nil {
 		return err
 	}
+	cfgRepository.Watch()
 
 	slackRepository := repository.NewSlackRepository(webApi)
 
-	aiRepository, err := repository.NewAIRepository()
+	// AI を使わない場合に nil のポインタを渡すと nil でないインターフェースになるため、設定されている場合のみ代入する
+	var aiRepository repository.AIRepositorier
+	r, err := repository.NewAIRepository(cfgRepository.AI)
 	if err != nil {
 		return err
 	}
+	if r != nil {
+		aiRepository = r
+	}
 
-	repo := repository.NewRepository(dynamoRepository, cfgRepository, cfgRepository, slackRepository)
+	repo := repository.NewRepository(dbRepository, dbRepository, cfgRepository, cfgRepository, slackRepository)
 
 	var postmortemExporter repository.PostMortemRepositoryer
 	if os.Getenv("CONFLUENCE_USERNAME") != "" && os.Getenv("CONFLUENCE_PASSWORD") != "" && cfgRepository.DefaultConfluence.Domain != "" {
@@ -88,31 +113,73 @@ func Handle(ctx context.Context, configPath string) error {
 
 	// EventHandlerにCallbackHandlerを設定
 	eventHandler.SetCallbackHandler(callbackHandler)
+	callbackHandler.SetAlertLeases(dbRepository)
 
-	// 1分ごとに各インシデントの15分間隔チェックを確認
-	ticker := time.NewTicker(1 * time.Minute)
-	defer ticker.Stop()
-	go func() {
-		for range ticker.C {
-			incidents, err := repo.ActiveIncidents(ctx)
-			if err != nil {
-				continue
-			}
-			now := time.Now()
-			for _, incident := range incidents {
-				if !incident.DisableTimer {
-					// StartedAtから15分間隔で判定
-					elapsed := now.Sub(incident.StartedAt)
-					if int(elapsed.Minutes())%15 == 0 && int(elapsed.Seconds())%60 < 60 {
-						if err := timeKeeperMessage(webApi, &incident, slackRepository); err != nil {
-							slog.Error("Failed to send time keeper message", slog.Any("err", err))
-						}
-					}
-				}
-			}
+	// 埋め込みに対応したプロバイダーがある場合のみ、類似インシデントを検索する
+	if r != nil && r.SupportsEmbedding() && !cfgRepository.AI.SimilarIncidents.Disabled {
+		// yas3 migrate の前でテーブルが無い場合などは、検索せずに起動する
+		index, err := repository.NewIncidentIndex(ctx, dbRepository)
+		if err != nil {
+			slog.Warn("failed to load incident embeddings, similar incident search is disabled. run yas3 migrate if the table does not exist", slog.Any("err", err))
+		} else {
+			callbackHandler.SetIncidentIndex(index)
 		}
-	}()
+	}
+
+	// 定期処理はリーダーのレプリカだけで実行する。Slack からのイベントはすべてのレプリカで処理する
+	var isLeader func() bool
+	if opts.LeaderElection {
+		holder, err := leaseHolderID()
+		if err != nil {
+			return err
+		}
+		elector := NewLeaderElector(dbRepository, periodicJobsLease, holder, 30*time.Second)
+		go elector.Run(ctx)
+		isLeader = elector.IsLeader
+	}
+
+	// 1分ごとに各インシデントのチェックポイントを確認
+	timekeeper := NewTimekeeper(ctx, repo, cfgRepository, time.Now)
+	go runPeriodically(ctx, 1*time.Minute, isLeader, timekeeper.Tick)
+
+	// 1分ごとに対応が進んでいないインシデントをエスカレーション
+	escalator := NewEscalator(ctx, repo, cfgRepository, time.Now)
+	go runPeriodically(ctx, 1*time.Minute, isLeader, escalator.Tick)
+
+	// 1分ごとに進捗サマリの自動作成が設定されたインシデントを確認
+	autoSummarizer := NewAutoSummarizer(ctx, callbackHandler, cfgRepository, time.Now)
+	go runPeriodically(ctx, 1*time.Minute, isLeader, autoSummarizer.Tick)
+
+	// アラートの受け付けと API はトークンが設定されている場合のみ有効にする
+	alertToken := os.Getenv("ALERT_WEBHOOK_TOKEN")
+	apiToken := os.Getenv("API_TOKEN")
+	mux := http.NewServeMux()
+	if opts.Mode == ModeHTTP {
+		mux = NewHTTPHandler(os.Getenv("SLACK_SIGNING_SECRET"), eventHandler, callbackHandler, callbackHandler)
+	}
+	if alertToken != "" {
+		RegisterAlertRoutes(mux, alertToken, callbackHandler)
+	}
+	if apiToken != "" {
+		RegisterAPIRoutes(mux, apiToken, repo)
+	}
+
+	if opts.Mode == ModeHTTP {
+		return listenAndServe(ctx, opts.Listen, mux)
+	}
+
+	// Socket Mode ではアラートや API を受け付けるためだけに HTTP サーバーを起動する
+	if alertToken != "" || apiToken != "" {
+		go func() {
+			if err := listenAndServe(ctx, opts.Listen, mux); err != nil {
+				slog.Error("Failed to serve http", slog.Any("err", err))
+			}
+		}()
+	}
 
+	socketMode := socketmode.New(
+		webApi,
+	)
 	go func() {
 		for envelope := range socketMode.Events {
 			switch envelope.Type {
@@ -141,38 +208,19 @@ func Handle(ctx context.Context, configPath string) error {
 				if err := callbackHandler.Handle(&callback); err != nil {
 					slog.Error("Failed to handle callback", slog.Any("err", err))
 				}
+			case socketmode.EventTypeSlashCommand:
+				socketMode.Ack(*envelope.Request)
+				cmd, ok := envelope.Data.(slack.SlashCommand)
+				if !ok {
+					slog.Error("Failed to cast to SlashCommand")
+					continue
+				}
+				if err := callbackHandler.HandleCommand(&cmd); err != nil {
+					slog.Error("Failed to handle command", slog.Any("err", err))
+				}
 			}
 		}
 	}()
 
 	return socketMode.Run()
 }
-
-func timeKeeperMessage(client *slack.Client, incident *entity.Incident, slackRepository *repository.SlackRepository) error {
-	channelID := incident.ChannelID
-	channel, err := slackRepository.GetChannelByID(channelID)
-	if err != nil {
-		if err == repository.ErrSlackNotFound {
-			return nil
-		}
-		return fmt.Errorf("failed to get channel %s: %w", channelID, err)
-	}
-	if channel.IsArchived {
-		return nil
-	}
-
-	elapsed := time.Since(incident.StartedAt)
-	hours := int(elapsed.Hours())
-	minutes := int(elapsed.Minutes()) % 60
-	elapsedStr := fmt.Sprintf("%d時間%d分", hours, minutes)
-
-	// 15分ごとのチェックポイントの案内
-	_, _, err = client.PostMessage(
-		channelID,
-		slack.MsgOptionBlocks(blocks.CheckPoint(elapsedStr)...),
-	)
-	if err != nil {
-		return fmt.Errorf("failed to post time keeper message %s: %w", channel.Name, err)
-	}
-	return nil
-}
@@ -25,8 +25,11 @@ import (
 type mockIncidentRepo struct {
 	data    map[string]*entity.Incident
 	active  []entity.Incident
+	events  []entity.IncidentEvent
 	findErr error
 	saveErr error
+	// 保存前に呼ばれ、trueを返すと他の更新と競合したものとして扱う
+	conflict func(m *mockIncidentRepo) bool
 }
 
 func (m *mockIncidentRepo) FindIncidentByChannel(_ context.Context, ch string) (*entity.Incident, error) {
@@ -34,7 +37,8 @@ func (m *mockIncidentRepo) FindIncidentByChannel(_ context.Context, ch string) (
 		return nil, m.findErr
 	}
 	if inc, ok := m.data[ch]; ok {
-		return inc, nil
+		c := *inc
+		return &c, nil
 	}
 	return nil, nil
 }
@@ -42,14 +46,47 @@ func (m *mockIncidentRepo) SaveIncident(_ context.Context, inc *entity.Incident)
 	if m.saveErr != nil {
 		return m.saveErr
 	}
-	m.data[inc.ChannelID] = inc
+	if m.conflict != nil && m.conflict(m) {
+		return repository.ErrIncidentConflict
+	}
+	inc.Version++
+	c := *inc
+	m.data[inc.ChannelID] = &c
 	return nil
 }
 func (m *mockIncidentRepo) ActiveIncidents(_ context.Context) ([]entity.Incident, error) {
 	return m.active, nil
 }
+func (m *mockIncidentRepo) Incidents(_ context.Context, cursor string, limit int) ([]entity.Incident, string, error) {
+	incidents := make([]entity.Incident, 0, len(m.data))
+	for _, incident := range m.data {
+		incidents = append(incidents, *incident)
+	}
+	return repository.PageIncidents(incidents, cursor, limit)
+}
+func (m *mockIncidentRepo) SaveIncidentEvent(_ context.Context, ev *entity.IncidentEvent) error {
+	m.events = append(m.events, *ev)
+	return nil
+}
+func (m *mockIncidentRepo) IncidentEvents(_ context.Context, ch string) ([]entity.IncidentEvent, error) {
+	var events []entity.IncidentEvent
+	for _, ev := range m.events {
+		if ev.ChannelID == ch {
+			events = append(events, ev)
+		}
+	}
+	return events, nil
+}
 
-type mockSlackRepo struct{}
+type mockSlackRepo struct {
+	messages   map[string][]string
+	ephemerals []string
+	history    []slack.Message
+	invited    map[string][]string
+	uploaded   []string
+	updated    []string
+	views      []slack.ModalViewRequest
+}
 
 func (m *mockSlackRepo) GetChannelByID(channelID string) (*slack.Channel, error) {
 	return &slack.Channel{
@@ -76,19 +113,48 @@ func (m *mockSlackRepo) GetChannelByName(name string) (*slack.Channel, error) {
 }
 
 func (m *mockSlackRepo) PostMessage(channelID string, options ...slack.MsgOption) (string, string, error) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", "", err
+	}
+	if m.messages == nil {
+		m.messages = map[string][]string{}
+	}
+	m.messages[channelID] = append(m.messages[channelID], values.Get("text")+values.Get("blocks")+values.Get("attachments"))
 	return channelID, "123456.789", nil
 }
 
-func (m *mockSlackRepo) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) {}
+func (m *mockSlackRepo) PostEphemeral(channelID, userID string, options ...slack.MsgOption) (string, error) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return "", err
+	}
+	m.ephemerals = append(m.ephemerals, values.Get("text")+values.Get("blocks"))
+	return "123456.789", nil
+}
+
+func (m *mockSlackRepo) UpdateMessage(channelID, timestamp string, options ...slack.MsgOption) {
+	_, values, err := slack.UnsafeApplyMsgOptions("", channelID, "", options...)
+	if err != nil {
+		return
+	}
+	m.updated = append(m.updated, values.Get("text")+values.Get("blocks"))
+}
 
 func (m *mockSlackRepo) DeleteMessage(channelID, timestamp string) {}
 
 func (m *mockSlackRepo) OpenView(triggerID string, view slack.ModalViewRequest) error {
+	m.views = append(m.views, view)
 	return nil
 }
 
 func (m *mockSlackRepo) CreateConversation(params slack.CreateConversationParams) (*slack.Channel, error) {
-	return &slack.Channel{}, nil
+	return &slack.Channel{
+		GroupConversation: slack.GroupConversation{
+			Conversation: slack.Conversation{ID: "C-" + params.ChannelName},
+			Name:         params.ChannelName,
+		},
+	}, nil
 }
 
 func (m *mockSlackRepo) SetTopicOfConversation(channelID, topic string) error {
@@ -96,6 +162,10 @@ func (m *mockSlackRepo) SetTopicOfConversation(channelID, topic string) error {
 }
 
 func (m *mockSlackRepo) InviteUsersToConversation(channelID string, users ...string) error {
+	if m.invited == nil {
+		m.invited = map[string][]string{}
+	}
+	m.invited[channelID] = append(m.invited[channelID], users...)
 	return nil
 }
 
@@ -118,19 +188,35 @@ func (m *mockSlackRepo) GetUserPreferredName(user *slack.User) string {
 }
 
 func (m *mockSlackRepo) UploadFile(workspaceURL, userID, channelID, filename, title, content string) (string, error) {
+	m.uploaded = append(m.uploaded, content)
 	return "http://example.com/file", nil
 }
 
 func (m *mockSlackRepo) GetChannelHistory(channelID, oldest, latest string, limit int) ([]slack.Message, error) {
-	return []slack.Message{}, nil
+	return append([]slack.Message{}, m.history...), nil
 }
 
 func (m *mockSlackRepo) GetChannelMessagesAfter(channelID, after string) ([]slack.Message, error) {
-	return []slack.Message{}, nil
+	messages := []slack.Message{}
+	for _, msg := range m.history {
+		if msg.Timestamp > after {
+			messages = append(messages, msg)
+		}
+	}
+	return messages, nil
+}
+
+func (m *mockSlackRepo) HasMessagesSince(channelID, oldest string) (bool, error) {
+	for _, msg := range m.history {
+		if msg.Timestamp >= oldest || (msg.LatestReply != "" && msg.LatestReply >= oldest) {
+			return true, nil
+		}
+	}
+	return false, nil
 }
 
 func (m *mockSlackRepo) GetAllChannelMessages(channelID string) ([]slack.Message, error) {
-	return []slack.Message{}, nil
+	return append([]slack.Message{}, m.history...), nil
 }
 
 func (m *mockSlackRepo) GetThreadReplies(channelID, threadTS string) ([]slack.Message, error) {
@@ -302,7 +388,7 @@ func TestEventHandler_Handle(t *testing.T) {
 	}}
 	cfgRepo := &mockConfigRepo{}
 	slackRepo := &mockSlackRepo{}
-	repo := repository.NewRepository(incRepo, cfgRepo, cfgRepo, slackRepo)
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
 	config := &repository.Config{} // 空のConfig構造体
 	evHandler := handler.NewEventHandler(context.Background(), api, repo, config)
 
@@ -445,7 +531,7 @@ func TestEditIncidentSummaryNotification(t *testing.T) {
 		levels:   []entity.IncidentLevel{{Level: 0, Description: "サービス影響なし"}},
 		announce: []string{"announcement"},
 	}
-	repo := repository.NewRepository(incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
 	cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
 
 	// 障害概要編集のテスト
@@ -621,7 +707,7 @@ func TestCallbackHandler_Handle(t *testing.T) {
 		levels:   []entity.IncidentLevel{{Level: 0, Description: "none"}, {Level: 1, Description: "critical"}},
 		announce: []string{"ANN"},
 	}
-	repo := repository.NewRepository(incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
 	cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
 
 	tcs := []struct {
@@ -1051,7 +1137,7 @@ func TestIncidentReopen(t *testing.T) {
 			announce: []string{"CANN"},
 		}
 
-		repo := repository.NewRepository(incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
 		cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
 
 		// 初期状態をリセット
@@ -1096,6 +1182,13 @@ func TestIncidentReopen(t *testing.T) {
 		assert.Empty(t, reopenedIncident.RecoveredUserID, "復旧者がリセットされていません")
 		assert.False(t, reopenedIncident.DisableTimer, "タイマーが有効になっていません")
 
+		// 再開イベントが記録されたことを確認
+		events, err := incRepo.IncidentEvents(context.Background(), "CREOPEN")
+		require.NoError(t, err)
+		require.Len(t, events, 1)
+		assert.Equal(t, entity.IncidentEventReopened, events[0].Type)
+		assert.Equal(t, "UREOPEN", events[0].ActorUserID)
+
 		// チャンネルトピックが更新されたことを確認
 		assert.Len(t, setTopicCalls, 1, "トピック更新が呼ばれていません")
 		if len(setTopicCalls) > 0 {
@@ -1144,7 +1237,7 @@ func TestIncidentReopen(t *testing.T) {
 			},
 		}
 
-		repo := repository.NewRepository(incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
 		cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
 
 		// 初期状態をリセット
@@ -1196,8 +1289,184 @@ func TestIncidentReopen(t *testing.T) {
 		incident := incRepo.data["CNOTREOPEN"]
 		assert.True(t, incident.ReopenedAt.IsZero(), "インシデントが誤って再開されています")
 		assert.Empty(t, incident.ReopenedUserID, "再開者が誤って設定されています")
+		assert.Empty(t, incRepo.events, "イベントが誤って記録されています")
 
 		// トピックが変更されていないことを確認
 		assert.Empty(t, setTopicCalls, "トピックが誤って変更されています")
 	})
 }
+
+func TestCallbackHandler_ConcurrentUpdate(t *testing.T) {
+	srv := slacktest.NewTestServer(func(c slacktest.Customize) {
+		c.Handle("/conversations.list", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = w.Write([]byte(`{"ok":true,"channels":[{"id":"CRACE","name":"race","topic":{"value":"topic"}}]}`))
+		}))
+	})
+	go srv.Start()
+	defer srv.Stop()
+	api := slack.New("dummy", slack.OptionAPIURL(srv.GetAPIURL()))
+
+	cfgRepo := &mockConfigRepo{
+		services: []entity.Service{{ID: 1, Name: "svc"}},
+		levels:   []entity.IncidentLevel{{Level: 1, Description: "レベル1"}, {Level: 3, Description: "レベル3"}},
+	}
+	recoveryCallback := &slack.InteractionCallback{
+		Type: slack.InteractionTypeBlockActions,
+		User: slack.User{ID: "URECOVER"},
+		Channel: slack.Channel{
+			GroupConversation: slack.GroupConversation{
+				Conversation: slack.Conversation{ID: "CRACE"},
+			},
+		},
+		Message: slack.Message{Msg: slack.Msg{Timestamp: "123.456"}},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: "recovery_execute"}},
+		},
+	}
+
+	t.Run("競合した他の更新を失わずに再適用する", func(t *testing.T) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{
+			"CRACE": {ChannelID: "CRACE", ServiceID: 1, Level: 1, Urgency: "none", Version: 1},
+		}}
+		conflicted := false
+		incRepo.conflict = func(m *mockIncidentRepo) bool {
+			if conflicted {
+				return false
+			}
+			conflicted = true
+			// 読み込み後に別の操作でレベルが変更された
+			m.data["CRACE"].Level = 3
+			m.data["CRACE"].Version++
+			return true
+		}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+		cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
+
+		require.NoError(t, cbHandler.Handle(recoveryCallback))
+
+		saved := incRepo.data["CRACE"]
+		assert.True(t, conflicted)
+		assert.Equal(t, 3, saved.Level, "競合した更新が失われています")
+		assert.Equal(t, "URECOVER", saved.RecoveredUserID)
+		assert.False(t, saved.RecoveredAt.IsZero())
+		assert.Equal(t, 3, saved.Version)
+	})
+
+	t.Run("競合相手が先に復旧していた場合は上書きしない", func(t *testing.T) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{
+			"CRACE": {ChannelID: "CRACE", ServiceID: 1, Level: 1, Urgency: "none", Version: 1},
+		}}
+		incRepo.conflict = func(m *mockIncidentRepo) bool {
+			m.data["CRACE"].RecoveredAt = time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+			m.data["CRACE"].RecoveredUserID = "UOTHER"
+			m.data["CRACE"].Version++
+			incRepo.conflict = nil
+			return true
+		}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+		cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
+
+		require.NoError(t, cbHandler.Handle(recoveryCallback))
+
+		saved := incRepo.data["CRACE"]
+		assert.Equal(t, "UOTHER", saved.RecoveredUserID)
+		assert.Equal(t, 2, saved.Version)
+		assert.Empty(t, incRepo.events)
+	})
+
+	t.Run("競合が解消しない場合はエラー", func(t *testing.T) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{
+			"CRACE": {ChannelID: "CRACE", ServiceID: 1, Level: 1, Urgency: "none"},
+		}}
+		incRepo.conflict = func(m *mockIncidentRepo) bool { return true }
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, repository.NewSlackRepository(api))
+		cbHandler := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
+
+		err := cbHandler.Handle(recoveryCallback)
+		assert.ErrorIs(t, err, repository.ErrIncidentConflict)
+		assert.True(t, incRepo.data["CRACE"].RecoveredAt.IsZero())
+	})
+}
+
+func TestCallbackHandler_HandleCommand(t *testing.T) {
+	cfgRepo := &mockConfigRepo{
+		services: []entity.Service{{ID: 1, Name: "svc"}},
+		levels:   []entity.IncidentLevel{{Level: 1, Description: "レベル1"}, {Level: 2, Description: "レベル2"}},
+	}
+	newHandler := func() (*handler.CallbackHandler, *mockIncidentRepo, *mockSlackRepo) {
+		incRepo := &mockIncidentRepo{
+			data: map[string]*entity.Incident{
+				"CINC": {ChannelID: "CINC", ServiceID: 1, Level: 1, Urgency: "none", Description: "APIが落ちた", StartedAt: time.Now()},
+			},
+			active: []entity.Incident{
+				{ChannelID: "CINC", ServiceID: 1, Description: "APIが落ちた", StartedAt: time.Now()},
+			},
+		}
+		slackRepo := &mockSlackRepo{}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+		return handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil), incRepo, slackRepo
+	}
+	command := func(channelID, text string) *slack.SlashCommand {
+		return &slack.SlashCommand{Command: handler.IncidentCommand, Text: text, ChannelID: channelID, UserID: "UCMD", TriggerID: "T1"}
+	}
+
+	t.Run("level でレベルを変更する", func(t *testing.T) {
+		h, incRepo, _ := newHandler()
+		require.NoError(t, h.HandleCommand(command("CINC", "level 2")))
+		assert.Equal(t, 2, incRepo.data["CINC"].Level)
+		require.Len(t, incRepo.events, 1)
+		assert.Equal(t, entity.IncidentEventLevelChanged, incRepo.events[0].Type)
+		assert.Equal(t, "UCMD", incRepo.events[0].ActorUserID)
+	})
+
+	t.Run("存在しないレベルは変更しない", func(t *testing.T) {
+		h, incRepo, slackRepo := newHandler()
+		require.NoError(t, h.HandleCommand(command("CINC", "level 9")))
+		assert.Equal(t, 1, incRepo.data["CINC"].Level)
+		require.Len(t, slackRepo.ephemerals, 1)
+		assert.Contains(t, slackRepo.ephemerals[0], "level <n>")
+	})
+
+	t.Run("handler で指定したユーザーをハンドラにする", func(t *testing.T) {
+		h, incRepo, _ := newHandler()
+		require.NoError(t, h.HandleCommand(command("CINC", "handler <@UHANDLER|someone>")))
+		assert.Equal(t, "UHANDLER", incRepo.data["CINC"].HandlerUserID)
+	})
+
+	t.Run("recover で復旧する", func(t *testing.T) {
+		h, incRepo, _ := newHandler()
+		require.NoError(t, h.HandleCommand(command("CINC", "recover")))
+		assert.Equal(t, "UCMD", incRepo.data["CINC"].RecoveredUserID)
+		assert.False(t, incRepo.data["CINC"].RecoveredAt.IsZero())
+	})
+
+	t.Run("status は本人にだけ状況を表示する", func(t *testing.T) {
+		h, _, slackRepo := newHandler()
+		require.NoError(t, h.HandleCommand(command("CINC", "status")))
+		require.Len(t, slackRepo.ephemerals, 1)
+		assert.Contains(t, slackRepo.ephemerals[0], "APIが落ちた")
+	})
+
+	t.Run("list は未クローズのインシデントを表示する", func(t *testing.T) {
+		h, _, slackRepo := newHandler()
+		require.NoError(t, h.HandleCommand(command("COTHER", "list")))
+		require.Len(t, slackRepo.ephemerals, 1)
+		assert.Contains(t, slackRepo.ephemerals[0], "APIが落ちた")
+	})
+
+	t.Run("インシデントチャンネル以外では操作しない", func(t *testing.T) {
+		h, incRepo, slackRepo := newHandler()
+		require.NoError(t, h.HandleCommand(command("COTHER", "recover")))
+		assert.Empty(t, incRepo.events)
+		require.Len(t, slackRepo.ephemerals, 1)
+		assert.Contains(t, slackRepo.ephemerals[0], "インシデントチャンネルではありません")
+	})
+
+	t.Run("不明なサブコマンドはヘルプを表示する", func(t *testing.T) {
+		h, _, slackRepo := newHandler()
+		require.NoError(t, h.HandleCommand(command("CINC", "")))
+		require.Len(t, slackRepo.ephemerals, 1)
+		assert.Contains(t, slackRepo.ephemerals[0], "/incident new")
+	})
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+)
+
+const (
+	ModeSocket = "socket"
+	ModeHTTP   = "http"
+
+	// Slackのリクエストボディの上限
+	maxSlackRequestBody = 1 << 20
+)
+
+// EventHandlerer は Events API のイベントを処理する
+type EventHandlerer interface {
+	Handle(event *slackevents.EventsAPIInnerEvent) error
+}
+
+// CallbackHandlerer は Interactivity のコールバックを処理する
+type CallbackHandlerer interface {
+	Handle(callback *slack.InteractionCallback) error
+}
+
+// CommandHandlerer はスラッシュコマンドを処理する
+type CommandHandlerer interface {
+	HandleCommand(cmd *slack.SlashCommand) error
+}
+
+// NewHTTPHandler は署名を検証した上で Events API、Interactivity、スラッシュコマンドのリクエストを受け付ける http.Handler を返す
+// Slackの3秒制限に間に合うよう、処理は非同期で行い即座に応答する
+func NewHTTPHandler(signingSecret string, eventHandler EventHandlerer, callbackHandler CallbackHandlerer, commandHandler CommandHandlerer) *http.ServeMux {
+	s := &slackHTTPHandler{
+		signingSecret:   signingSecret,
+		eventHandler:    eventHandler,
+		callbackHandler: callbackHandler,
+		commandHandler:  commandHandler,
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /slack/events", s.handleEvents)
+	mux.HandleFunc("POST /slack/interactivity", s.handleInteractivity)
+	mux.HandleFunc("POST /slack/commands", s.handleCommands)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+type slackHTTPHandler struct {
+	signingSecret   string
+	eventHandler    EventHandlerer
+	callbackHandler CallbackHandlerer
+	commandHandler  CommandHandlerer
+}
+
+// 署名を検証してリクエストボディを返す
+func (s *slackHTTPHandler) verify(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	verifier, err := slack.NewSecretsVerifier(r.Header, s.signingSecret)
+	if err != nil {
+		slog.Warn("invalid slack request headers", slog.Any("err", err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.TeeReader(http.MaxBytesReader(w, r.Body, maxSlackRequestBody), &verifier))
+	if err != nil {
+		slog.Warn("failed to read slack request body", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return nil, false
+	}
+
+	if err := verifier.Ensure(); err != nil {
+		slog.Warn("failed to verify slack request signature", slog.Any("err", err))
+		w.WriteHeader(http.StatusUnauthorized)
+		return nil, false
+	}
+	return body, true
+}
+
+func (s *slackHTTPHandler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.verify(w, r)
+	if !ok {
+		return
+	}
+
+	eventPayload, err := slackevents.ParseEvent(json.RawMessage(body), slackevents.OptionNoVerifyToken())
+	if err != nil {
+		slog.Error("Failed to parse event", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	switch eventPayload.Type {
+	case slackevents.URLVerification:
+		var challenge slackevents.ChallengeResponse
+		if err := json.Unmarshal(body, &challenge); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Header().Set("Content-Type", "text/plain")
+		_, _ = w.Write([]byte(challenge.Challenge))
+	case slackevents.CallbackEvent:
+		w.WriteHeader(http.StatusOK)
+		// 応答が遅れた場合などのSlackによる再送は、既に受け付け済みなので処理しない
+		if r.Header.Get("X-Slack-Retry-Num") != "" {
+			slog.Info("skip retried event", slog.String("retry_num", r.Header.Get("X-Slack-Retry-Num")), slog.String("reason", r.Header.Get("X-Slack-Retry-Reason")))
+			return
+		}
+		innerEvent := eventPayload.InnerEvent
+		go func() {
+			if err := s.eventHandler.Handle(&innerEvent); err != nil {
+				slog.Error("Failed to handle event", slog.Any("err", err))
+			}
+		}()
+	default:
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+func (s *slackHTTPHandler) handleInteractivity(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.verify(w, r)
+	if !ok {
+		return
+	}
+
+	// 検証のために読み込んだボディをフォームとして解析する
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	var callback slack.InteractionCallback
+	if err := json.Unmarshal([]byte(form.Get("payload")), &callback); err != nil {
+		slog.Error("Failed to parse interaction payload", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	go func() {
+		if err := s.callbackHandler.Handle(&callback); err != nil {
+			slog.Error("Failed to handle callback", slog.Any("err", err))
+		}
+	}()
+}
+
+func (s *slackHTTPHandler) handleCommands(w http.ResponseWriter, r *http.Request) {
+	body, ok := s.verify(w, r)
+	if !ok {
+		return
+	}
+
+	// 検証のために読み込んだボディを戻して解析する
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	cmd, err := slack.SlashCommandParse(r)
+	if err != nil {
+		slog.Error("Failed to parse slash command", slog.Any("err", err))
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	go func() {
+		if err := s.commandHandler.HandleCommand(&cmd); err != nil {
+			slog.Error("Failed to handle command", slog.Any("err", err))
+		}
+	}()
+}
+
+// Authorization ヘッダの Bearer トークンが token と一致するか
+func bearerAuthorized(r *http.Request, token string) bool {
+	return token != "" && subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), []byte("Bearer "+token)) == 1
+}
+
+// addr で HTTP サーバーを起動し、ctx がキャンセルされたら停止する
+func listenAndServe(ctx context.Context, addr string, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	errCh := make(chan error, 1)
+	go func() {
+		slog.Info("HTTP server started", slog.String("addr", addr))
+		errCh <- server.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errCh:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			return err
+		}
+		if err := <-errCh; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	}
+}
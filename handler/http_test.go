@@ -0,0 +1,199 @@
+package handler_test
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/slack-go/slack/slackevents"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/handler"
+)
+
+const testSigningSecret = "test-signing-secret"
+
+type mockEventHandler struct {
+	events chan *slackevents.EventsAPIInnerEvent
+}
+
+func (m *mockEventHandler) Handle(event *slackevents.EventsAPIInnerEvent) error {
+	m.events <- event
+	return nil
+}
+
+type mockCallbackHandler struct {
+	callbacks chan *slack.InteractionCallback
+}
+
+func (m *mockCallbackHandler) Handle(callback *slack.InteractionCallback) error {
+	m.callbacks <- callback
+	return nil
+}
+
+type mockCommandHandler struct {
+	commands chan *slack.SlashCommand
+}
+
+func (m *mockCommandHandler) HandleCommand(cmd *slack.SlashCommand) error {
+	m.commands <- cmd
+	return nil
+}
+
+func signedRequest(t *testing.T, path, contentType, body, secret string) *http.Request {
+	t.Helper()
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	_, _ = mac.Write([]byte(fmt.Sprintf("v0:%s:%s", ts, body)))
+
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("X-Slack-Request-Timestamp", ts)
+	req.Header.Set("X-Slack-Signature", "v0="+hex.EncodeToString(mac.Sum(nil)))
+	return req
+}
+
+func TestHTTPHandler_Events(t *testing.T) {
+	ev := &mockEventHandler{events: make(chan *slackevents.EventsAPIInnerEvent, 1)}
+	cb := &mockCallbackHandler{callbacks: make(chan *slack.InteractionCallback, 1)}
+	cmd := &mockCommandHandler{commands: make(chan *slack.SlashCommand, 1)}
+	h := handler.NewHTTPHandler(testSigningSecret, ev, cb, cmd)
+
+	t.Run("url_verification", func(t *testing.T) {
+		body := `{"type":"url_verification","token":"x","challenge":"challenge-value"}`
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, signedRequest(t, "/slack/events", "application/json", body, testSigningSecret))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "challenge-value", rec.Body.String())
+	})
+
+	t.Run("署名が不正な場合は401", func(t *testing.T) {
+		body := `{"type":"url_verification","token":"x","challenge":"challenge-value"}`
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, signedRequest(t, "/slack/events", "application/json", body, "wrong-secret"))
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("署名ヘッダが無い場合は401", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/slack/events", strings.NewReader(`{}`))
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, http.StatusUnauthorized, rec.Code)
+	})
+
+	t.Run("event_callback は EventHandler に渡す", func(t *testing.T) {
+		body := `{"type":"event_callback","token":"x","team_id":"T1","event":{"type":"app_mention","user":"U1","channel":"C1","text":"hi","ts":"1.1"}}`
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, signedRequest(t, "/slack/events", "application/json", body, testSigningSecret))
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		select {
+		case got := <-ev.events:
+			mention, ok := got.Data.(*slackevents.AppMentionEvent)
+			require.True(t, ok)
+			assert.Equal(t, "C1", mention.Channel)
+		case <-time.After(time.Second):
+			t.Fatal("event was not dispatched")
+		}
+	})
+
+	t.Run("再送されたイベントは処理しない", func(t *testing.T) {
+		body := `{"type":"event_callback","token":"x","team_id":"T1","event":{"type":"app_mention","user":"U1","channel":"C1","text":"hi","ts":"1.1"}}`
+		req := signedRequest(t, "/slack/events", "application/json", body, testSigningSecret)
+		req.Header.Set("X-Slack-Retry-Num", "1")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		assert.Equal(t, http.StatusOK, rec.Code)
+
+		select {
+		case <-ev.events:
+			t.Fatal("retried event was dispatched")
+		case <-time.After(100 * time.Millisecond):
+		}
+	})
+}
+
+func TestHTTPHandler_Interactivity(t *testing.T) {
+	ev := &mockEventHandler{events: make(chan *slackevents.EventsAPIInnerEvent, 1)}
+	cb := &mockCallbackHandler{callbacks: make(chan *slack.InteractionCallback, 1)}
+	cmd := &mockCommandHandler{commands: make(chan *slack.SlashCommand, 1)}
+	h := handler.NewHTTPHandler(testSigningSecret, ev, cb, cmd)
+
+	payload, err := json.Marshal(slack.InteractionCallback{
+		Type: slack.InteractionTypeBlockActions,
+		User: slack.User{ID: "U1"},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: "handler_button"}},
+		},
+	})
+	require.NoError(t, err)
+	body := url.Values{"payload": {string(payload)}}.Encode()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signedRequest(t, "/slack/interactivity", "application/x-www-form-urlencoded", body, testSigningSecret))
+	assert.Equal(t, http.StatusOK, rec.Code)
+	b, _ := io.ReadAll(rec.Body)
+	assert.Empty(t, b)
+
+	select {
+	case got := <-cb.callbacks:
+		assert.Equal(t, slack.InteractionTypeBlockActions, got.Type)
+		assert.Equal(t, "U1", got.User.ID)
+		require.Len(t, got.ActionCallback.BlockActions, 1)
+		assert.Equal(t, "handler_button", got.ActionCallback.BlockActions[0].ActionID)
+	case <-time.After(time.Second):
+		t.Fatal("callback was not dispatched")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, signedRequest(t, "/slack/interactivity", "application/x-www-form-urlencoded", body, "wrong-secret"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
+
+func TestHTTPHandler_Commands(t *testing.T) {
+	ev := &mockEventHandler{events: make(chan *slackevents.EventsAPIInnerEvent, 1)}
+	cb := &mockCallbackHandler{callbacks: make(chan *slack.InteractionCallback, 1)}
+	cmd := &mockCommandHandler{commands: make(chan *slack.SlashCommand, 1)}
+	h := handler.NewHTTPHandler(testSigningSecret, ev, cb, cmd)
+
+	body := url.Values{
+		"command":    {"/incident"},
+		"text":       {"level 2"},
+		"channel_id": {"C1"},
+		"user_id":    {"U1"},
+		"trigger_id": {"T1"},
+	}.Encode()
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, signedRequest(t, "/slack/commands", "application/x-www-form-urlencoded", body, testSigningSecret))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	select {
+	case got := <-cmd.commands:
+		assert.Equal(t, "/incident", got.Command)
+		assert.Equal(t, "level 2", got.Text)
+		assert.Equal(t, "C1", got.ChannelID)
+		assert.Equal(t, "U1", got.UserID)
+	case <-time.After(time.Second):
+		t.Fatal("command was not dispatched")
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, signedRequest(t, "/slack/commands", "application/x-www-form-urlencoded", body, "wrong-secret"))
+	assert.Equal(t, http.StatusUnauthorized, rec.Code)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/repository"
+)
+
+// 定期処理を実行するレプリカを選ぶためのリース名
+const periodicJobsLease = "periodic-jobs"
+
+// LeaderElector はリースを使って、複数のレプリカのうち1台だけを定期処理のリーダーにする
+// リースは ttl の1/3ごとに延長し、延長できなかった場合はリーダーをやめる
+type LeaderElector struct {
+	leases repository.LeaseRepositoryer
+	name   string
+	holder string
+	ttl    time.Duration
+	leader atomic.Bool
+}
+
+func NewLeaderElector(leases repository.LeaseRepositoryer, name, holder string, ttl time.Duration) *LeaderElector {
+	return &LeaderElector{
+		leases: leases,
+		name:   name,
+		holder: holder,
+		ttl:    ttl,
+	}
+}
+
+// leaseHolderID はホスト名にランダムな接尾辞を付けて、同じホストで動くプロセスも区別する
+func leaseHolderID() (string, error) {
+	host, err := os.Hostname()
+	if err != nil {
+		return "", fmt.Errorf("failed to get hostname: %w", err)
+	}
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate holder id: %w", err)
+	}
+	return fmt.Sprintf("%s-%s", host, hex.EncodeToString(b)), nil
+}
+
+func (e *LeaderElector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Elect はリースの取得または延長を1回試みる
+func (e *LeaderElector) Elect(ctx context.Context) {
+	ok, err := e.leases.AcquireLease(ctx, e.name, e.holder, e.ttl)
+	if err != nil {
+		slog.Error("Failed to acquire lease", slog.Any("err", err), slog.String("lease", e.name))
+		ok = false
+	}
+	if was := e.leader.Swap(ok); was != ok {
+		slog.Info("Leadership changed", slog.String("lease", e.name), slog.String("holder", e.holder), slog.Bool("leader", ok))
+	}
+}
+
+// Run は ctx が終了するまでリースを維持し、終了時に解放する
+func (e *LeaderElector) Run(ctx context.Context) {
+	e.Elect(ctx)
+	ticker := time.NewTicker(e.ttl / 3)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			e.leader.Store(false)
+			// ctx は終了しているため、解放には別の ctx を使う
+			releaseCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if err := e.leases.ReleaseLease(releaseCtx, e.name, e.holder); err != nil {
+				slog.Error("Failed to release lease", slog.Any("err", err), slog.String("lease", e.name))
+			}
+			return
+		case <-ticker.C:
+			e.Elect(ctx)
+		}
+	}
+}
+
+// runPeriodically は interval ごとに job を実行する
+// isLeader が指定されている場合はリーダーの間だけ実行する
+func runPeriodically(ctx context.Context, interval time.Duration, isLeader func() bool, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if isLeader != nil && !isLeader() {
+				continue
+			}
+			if err := job(); err != nil {
+				slog.Error("Failed to run periodic job", slog.Any("err", err))
+			}
+		}
+	}
+}
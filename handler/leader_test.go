@@ -0,0 +1,52 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/pyama86/YAS3/handler"
+)
+
+type mockLeaseRepo struct {
+	holder    string
+	expiresAt time.Time
+	now       time.Time
+}
+
+func (m *mockLeaseRepo) AcquireLease(_ context.Context, _, holder string, ttl time.Duration) (bool, error) {
+	if m.holder != "" && m.holder != holder && m.now.Before(m.expiresAt) {
+		return false, nil
+	}
+	m.holder = holder
+	m.expiresAt = m.now.Add(ttl)
+	return true, nil
+}
+
+func (m *mockLeaseRepo) ReleaseLease(_ context.Context, _, holder string) error {
+	if m.holder == holder {
+		m.holder = ""
+	}
+	return nil
+}
+
+func TestLeaderElector(t *testing.T) {
+	ctx := context.Background()
+	leases := &mockLeaseRepo{now: time.Now()}
+	a := handler.NewLeaderElector(leases, "jobs", "a", 30*time.Second)
+	b := handler.NewLeaderElector(leases, "jobs", "b", 30*time.Second)
+
+	a.Elect(ctx)
+	b.Elect(ctx)
+	assert.True(t, a.IsLeader())
+	assert.False(t, b.IsLeader())
+
+	// a が延長できないまま期限が切れると b がリーダーになる
+	leases.now = leases.now.Add(time.Minute)
+	b.Elect(ctx)
+	a.Elect(ctx)
+	assert.False(t, a.IsLeader())
+	assert.True(t, b.IsLeader())
+}
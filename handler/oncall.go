@@ -0,0 +1,30 @@
+package handler
+
+import (
+	"log/slog"
+
+	"github.com/pyama86/YAS3/domain/entity"
+)
+
+// onCallUserID はサービスのオンコール担当者の Slack ID を返す
+// 解決できない場合はログに残して空文字を返し、インシデントの作成は続ける
+func (h *CallbackHandler) onCallUserID(service *entity.Service) string {
+	if h.config == nil || service.OnCallSchedule == "" {
+		return ""
+	}
+	name, err := h.config.OnCall(h.ctx, service, timeNow())
+	if err != nil {
+		slog.Error("failed to resolve oncall", slog.Any("err", err), slog.String("schedule", service.OnCallSchedule))
+		return ""
+	}
+	if name == "" {
+		slog.Info("nobody is on call", slog.String("schedule", service.OnCallSchedule))
+		return ""
+	}
+	ids, err := h.repository.GetMemberIDs(name)
+	if err != nil || len(ids) == 0 {
+		slog.Error("failed to GetMemberIDs", slog.Any("err", err), slog.String("member", name))
+		return ""
+	}
+	return ids[0]
+}
@@ -0,0 +1,182 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"sync"
+
+	"github.com/pyama86/YAS3/presentation/postmortem"
+	"golang.org/x/sync/errgroup"
+)
+
+// postMortemConcurrency は AI にセクションを同時に生成させる数
+const postMortemConcurrency = 4
+
+// postMortemDraft は AI で生成するポストモーテムの内容
+// 生成に失敗したセクションは記入例のまま残る
+type postMortemDraft struct {
+	title          string
+	sections       postmortem.Sections
+	timeline       string
+	titleGenerated bool
+	summarized     bool
+}
+
+func newPostMortemDraft(timeline string) *postMortemDraft {
+	return &postMortemDraft{
+		title: "例: サービスAPIが応答停止",
+		sections: postmortem.Sections{
+			Summary:      "例: サービスAPIが応答しない",
+			Status:       "解決済み",
+			Impact:       "例: サービスが断続的にダウンし、最大で１割のユーザーが影響を受けました。",
+			RootCause:    "例: ExampleAPIのバグ、設定ミス",
+			Trigger:      "例: 監視アラート、ユーザーからの報告",
+			Solution:     "例: 切り戻し、データベースの再起動",
+			ActionItems:  "例:\n- 【根本対応】原因となったエンドポイントの修正\n- 【緩和策】エラーハンドリングの追加",
+			LessonsGood:  "例: 迅速な対応により影響時間を最小限に抑えることができた",
+			LessonsBad:   "例: 初期対応時の情報共有が不十分だった",
+			LessonsLucky: "例: 障害発生がピーク時間外だったため影響が限定的だった",
+		},
+		timeline: timeline,
+	}
+}
+
+// restore は生成した文章のすべてに restore を適用する
+func (d *postMortemDraft) restore(restore func(string) string) {
+	d.title = restore(d.title)
+	d.timeline = restore(d.timeline)
+	for _, s := range []*string{
+		&d.sections.Summary, &d.sections.Status, &d.sections.Impact, &d.sections.RootCause, &d.sections.Trigger,
+		&d.sections.Solution, &d.sections.ActionItems, &d.sections.LessonsGood, &d.sections.LessonsBad, &d.sections.LessonsLucky,
+	} {
+		*s = restore(*s)
+	}
+}
+
+// generatePostMortem は AI でポストモーテムの各セクションを生成し、失敗したセクションの名前を返す
+// 概要は他のセクションの入力にもなるため先に生成し、残りは並行して生成する
+func (h *CallbackHandler) generatePostMortem(draft *postMortemDraft, description, roster, messages string, progress func(done, total int)) []string {
+	// 対応体制は AI にも事象内容と合わせて渡す
+	withRoster := func(description string) string {
+		if roster == "" {
+			return description
+		}
+		return fmt.Sprintf("%s\n\n対応体制:\n%s", description, roster)
+	}
+
+	var (
+		mu     sync.Mutex
+		done   int
+		failed = map[string]bool{}
+	)
+	const total = 10
+	run := func(name string, generate func() error) {
+		err := generate()
+		mu.Lock()
+		defer mu.Unlock()
+		done++
+		if err != nil {
+			slog.Warn("failed to generate postmortem section", slog.String("section", name), slog.Any("err", err))
+			failed[name] = true
+		}
+		progress(done, total)
+	}
+
+	run("概要", func() error {
+		s, err := h.aiRepository.Summarize(withRoster(description), messages)
+		if err != nil {
+			return err
+		}
+		draft.sections.Summary = s
+		draft.summarized = true
+		description = s
+		return nil
+	})
+
+	// 各セクションは draft の別々のフィールドにだけ書き込む
+	sections := []struct {
+		name     string
+		generate func() error
+	}{
+		{"タイトル", func() error {
+			title, err := h.aiRepository.GenerateTitle(withRoster(description), messages)
+			if err == nil {
+				draft.title, draft.titleGenerated = title, true
+			}
+			return err
+		}},
+		{"ステータス", func() error {
+			return generateInto(&draft.sections.Status, func() (string, error) {
+				return h.aiRepository.GenerateStatus(withRoster(description), messages)
+			})
+		}},
+		{"影響", func() error {
+			return generateInto(&draft.sections.Impact, func() (string, error) {
+				return h.aiRepository.GenerateImpact(withRoster(description), messages)
+			})
+		}},
+		{"主な原因", func() error {
+			return generateInto(&draft.sections.RootCause, func() (string, error) {
+				return h.aiRepository.GenerateRootCause(withRoster(description), messages)
+			})
+		}},
+		{"障害発生のトリガー", func() error {
+			return generateInto(&draft.sections.Trigger, func() (string, error) {
+				return h.aiRepository.GenerateTrigger(withRoster(description), messages)
+			})
+		}},
+		{"解決策", func() error {
+			return generateInto(&draft.sections.Solution, func() (string, error) {
+				return h.aiRepository.GenerateSolution(withRoster(description), messages)
+			})
+		}},
+		{"アクションアイテム", func() error {
+			return generateInto(&draft.sections.ActionItems, func() (string, error) {
+				return h.aiRepository.GenerateActionItems(withRoster(description), messages)
+			})
+		}},
+		{"学んだ教訓", func() error {
+			good, bad, lucky, err := h.aiRepository.GenerateLessonsLearned(withRoster(description), messages)
+			if err == nil {
+				draft.sections.LessonsGood, draft.sections.LessonsBad, draft.sections.LessonsLucky = good, bad, lucky
+			}
+			return err
+		}},
+		{"タイムライン", func() error {
+			return generateInto(&draft.timeline, func() (string, error) {
+				return h.aiRepository.FormatTimeline(messages)
+			})
+		}},
+	}
+
+	var g errgroup.Group
+	g.SetLimit(postMortemConcurrency)
+	for _, s := range sections {
+		g.Go(func() error {
+			run(s.name, s.generate)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	var names []string
+	if failed["概要"] {
+		names = append(names, "概要")
+	}
+	for _, s := range sections {
+		if failed[s.name] {
+			names = append(names, s.name)
+		}
+	}
+	return names
+}
+
+// generateInto は生成に成功した場合のみ dst を置き換える
+func generateInto(dst *string, generate func() (string, error)) error {
+	s, err := generate()
+	if err != nil {
+		return err
+	}
+	*dst = s
+	return nil
+}
@@ -0,0 +1,124 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+// postMortemAI は同時に実行された生成の数を記録し、影響の生成だけ失敗する
+type postMortemAI struct {
+	mockAIRepository
+	mu          sync.Mutex
+	running     int
+	maxRunning  int
+	description string
+}
+
+func (m *postMortemAI) generate(text string) (string, error) {
+	m.mu.Lock()
+	m.running++
+	m.maxRunning = max(m.maxRunning, m.running)
+	m.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	m.mu.Lock()
+	m.running--
+	m.mu.Unlock()
+	return text, nil
+}
+
+func (m *postMortemAI) Summarize(description, slackMessages string) (string, error) {
+	return "AIの概要", nil
+}
+
+func (m *postMortemAI) GenerateTitle(description, slackMessages string) (string, error) {
+	return m.generate("AIのタイトル")
+}
+
+func (m *postMortemAI) GenerateStatus(description, slackMessages string) (string, error) {
+	m.mu.Lock()
+	m.description = description
+	m.mu.Unlock()
+	return m.generate("AIのステータス")
+}
+
+func (m *postMortemAI) GenerateImpact(description, slackMessages string) (string, error) {
+	_, _ = m.generate("")
+	return "", errors.New("rate limited")
+}
+
+func (m *postMortemAI) GenerateRootCause(description, slackMessages string) (string, error) {
+	return m.generate("AIの原因")
+}
+
+func (m *postMortemAI) GenerateTrigger(description, slackMessages string) (string, error) {
+	return m.generate("AIのトリガー")
+}
+
+func (m *postMortemAI) GenerateSolution(description, slackMessages string) (string, error) {
+	return m.generate("AIの解決策")
+}
+
+func (m *postMortemAI) GenerateActionItems(description, slackMessages string) (string, error) {
+	return m.generate("AIのアクションアイテム")
+}
+
+func (m *postMortemAI) FormatTimeline(rawTimeline string) (string, error) {
+	return m.generate("AIのタイムライン")
+}
+
+func TestCallbackHandler_CreatePostMortem(t *testing.T) {
+	startedAt := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	incRepo := &mockIncidentRepo{
+		data: map[string]*entity.Incident{
+			"CINC": {ChannelID: "CINC", ServiceID: 1, Description: "APIが落ちた", StartedAt: startedAt, RecoveredAt: startedAt.Add(time.Hour)},
+		},
+	}
+	cfgRepo := &mockConfigRepo{services: []entity.Service{{ID: 1, Name: "svc"}}}
+	slackRepo := &mockSlackRepo{}
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+	ai := &postMortemAI{}
+	h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", ai, nil, nil)
+
+	require.NoError(t, h.Handle(&slack.InteractionCallback{
+		Type: slack.InteractionTypeBlockActions,
+		Channel: slack.Channel{
+			GroupConversation: slack.GroupConversation{
+				Conversation: slack.Conversation{ID: "CINC"},
+			},
+		},
+		Message: slack.Message{Msg: slack.Msg{Timestamp: "111.222"}},
+		User:    slack.User{ID: "UA"},
+		ActionCallback: slack.ActionCallbacks{
+			BlockActions: []*slack.BlockAction{{ActionID: "postmortem_action"}},
+		},
+	}))
+
+	require.Len(t, slackRepo.uploaded, 1)
+	content := slackRepo.uploaded[0]
+	assert.Contains(t, content, "AIのタイトル")
+	assert.Contains(t, content, "## ステータス\n\nAIのステータス\n")
+	assert.Contains(t, content, "## 概要\n\nAIの概要\n")
+	assert.Contains(t, content, "AIのタイムライン")
+	// 失敗したセクションは記入例のまま残す
+	assert.Contains(t, content, "## 影響\n\n例: サービスが断続的にダウンし")
+	assert.Contains(t, slackRepo.messages["CINC"][0], "影響")
+
+	// 概要を生成してから、残りを上限付きで並行して生成する
+	assert.Equal(t, "AIの概要", ai.description)
+	assert.Greater(t, ai.maxRunning, 1)
+	assert.LessOrEqual(t, ai.maxRunning, 4)
+
+	assert.Equal(t, "AIの概要", incRepo.data["CINC"].Description)
+	assert.Equal(t, "http://example.com/file", incRepo.data["CINC"].PostMortemURL)
+}
@@ -0,0 +1,227 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/pyama86/YAS3/presentation/postmortem"
+	"github.com/slack-go/slack"
+)
+
+var (
+	errRoleAlreadyAssigned = errors.New("role is already assigned")
+	errRoleNotAssigned     = errors.New("role is not assigned")
+)
+
+// 設定が無い場合はデフォルトの役割を使う
+func (h *CallbackHandler) incidentRoles() []entity.IncidentRole {
+	if h.config == nil {
+		return entity.DefaultIncidentRoles
+	}
+	return h.config.IncidentRoles(h.ctx)
+}
+
+func (h *CallbackHandler) incidentRole(roleID string) (*entity.IncidentRole, error) {
+	for _, r := range h.incidentRoles() {
+		if r.ID == roleID {
+			return &r, nil
+		}
+	}
+	return nil, fmt.Errorf("role not found: %s", roleID)
+}
+
+// incidentTopic はチャンネルのトピックを返す。担当者がいる場合は対応体制も含める
+func incidentTopic(service *entity.Service, urgencyText, description, roster string) string {
+	topic := fmt.Sprintf("サービス名:%s 緊急度:%s 事象内容:%s", service.Name, urgencyText, description)
+	if roster != "" {
+		topic = fmt.Sprintf("%s 対応体制:%s", topic, roster)
+	}
+	return topic
+}
+
+// updateIncidentTopic はインシデントの最新の状態でチャンネルのトピックを更新する
+// 復旧済みの場合は【復旧】のプレフィックスを維持する
+func (h *CallbackHandler) updateIncidentTopic(incident *entity.Incident) error {
+	channel, err := h.repository.GetChannelByID(incident.ChannelID)
+	if err != nil {
+		return fmt.Errorf("failed to GetChannelByID: %w", err)
+	}
+	service, err := h.repository.ServiceByID(h.ctx, incident.ServiceID)
+	if err != nil {
+		return fmt.Errorf("failed to ServiceByID: %w", err)
+	}
+	urgencyText, ok := blocks.UrgencyMap[incident.Urgency]
+	if !ok {
+		return fmt.Errorf("invalid urgency: %s", incident.Urgency)
+	}
+
+	topic := incidentTopic(service, urgencyText, incident.Description, blocks.RosterText(h.incidentRoles(), incident.Roles))
+	if strings.HasPrefix(channel.Topic.Value, "【復旧】") {
+		topic = fmt.Sprintf("【復旧】%s", topic)
+	}
+	if err := h.repository.SetTopicOfConversation(incident.ChannelID, topic); err != nil {
+		return fmt.Errorf("failed to SetTopicOfConversation: %w", err)
+	}
+	return nil
+}
+
+// 役割の一覧と担当ボタンを表示する
+func (h *CallbackHandler) showIncidentRoles(channelID string) error {
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+
+	_, _, err = h.repository.PostMessage(
+		channelID,
+		slack.MsgOptionBlocks(blocks.IncidentRoles(h.incidentRoles(), incident.Roles)...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to PostMessage: %w", err)
+	}
+	return nil
+}
+
+// assignRole は userID を役割の担当にする
+// 1人だけが担当する役割の場合は前任者から引き継ぐ
+func (h *CallbackHandler) assignRole(userID, channelID, roleID string) (*entity.Incident, error) {
+	role, err := h.incidentRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return nil, fmt.Errorf("incident is nil")
+	}
+
+	var previous string
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		previous = ""
+		assigned := i.Roles[roleID]
+		if slices.Contains(assigned, userID) {
+			return errRoleAlreadyAssigned
+		}
+		if i.Roles == nil {
+			i.Roles = map[string][]string{}
+		}
+		if role.Multiple {
+			i.Roles[roleID] = append(assigned, userID)
+			return nil
+		}
+		if len(assigned) > 0 {
+			previous = assigned[0]
+		}
+		i.Roles[roleID] = []string{userID}
+		return nil
+	})
+	if errors.Is(err, errRoleAlreadyAssigned) {
+		return incident, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to SaveIncident: %w", err)
+	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventRoleAssigned, userID, map[string]string{
+		"role": roleID,
+		"from": previous,
+		"to":   userID,
+	})
+
+	if err := h.updateIncidentTopic(incident); err != nil {
+		slog.Error("Failed to update topic", slog.Any("err", err))
+	}
+	_, _, err = h.repository.PostMessage(
+		channelID,
+		slack.MsgOptionBlocks(blocks.RoleAssigned(userID, role.Name, previous)...),
+	)
+	if err != nil {
+		slog.Error("Failed to post role assigned message", slog.Any("err", err))
+	}
+	return incident, nil
+}
+
+// unassignRole は userID を役割の担当から外す
+func (h *CallbackHandler) unassignRole(userID, channelID, roleID string) (*entity.Incident, error) {
+	role, err := h.incidentRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return nil, fmt.Errorf("incident is nil")
+	}
+
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		assigned := i.Roles[roleID]
+		if !slices.Contains(assigned, userID) {
+			return errRoleNotAssigned
+		}
+		i.Roles[roleID] = slices.DeleteFunc(slices.Clone(assigned), func(id string) bool { return id == userID })
+		if len(i.Roles[roleID]) == 0 {
+			delete(i.Roles, roleID)
+		}
+		return nil
+	})
+	if errors.Is(err, errRoleNotAssigned) {
+		return incident, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to SaveIncident: %w", err)
+	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventRoleUnassigned, userID, map[string]string{
+		"role": roleID,
+	})
+
+	if err := h.updateIncidentTopic(incident); err != nil {
+		slog.Error("Failed to update topic", slog.Any("err", err))
+	}
+	_, _, err = h.repository.PostMessage(
+		channelID,
+		slack.MsgOptionBlocks(blocks.RoleUnassigned(userID, role.Name)...),
+	)
+	if err != nil {
+		slog.Error("Failed to post role unassigned message", slog.Any("err", err))
+	}
+	return incident, nil
+}
+
+// rolesForPostMortem はポストモーテムに載せる対応体制を返す
+func (h *CallbackHandler) rolesForPostMortem(incident *entity.Incident) []postmortem.Role {
+	var roles []postmortem.Role
+	for _, r := range h.incidentRoles() {
+		if len(incident.Roles[r.ID]) == 0 {
+			continue
+		}
+		names := make([]string, 0, len(incident.Roles[r.ID]))
+		for _, id := range incident.Roles[r.ID] {
+			names = append(names, h.userName(id))
+		}
+		roles = append(roles, postmortem.Role{Name: r.Name, Users: names})
+	}
+	return roles
+}
+
+// userName はユーザーの表示名を返す。取得できない場合は ID をそのまま返す
+func (h *CallbackHandler) userName(userID string) string {
+	if userID == "" {
+		return ""
+	}
+	user, err := h.repository.GetUserByID(userID)
+	if err != nil {
+		return userID
+	}
+	return h.repository.GetUserPreferredName(user)
+}
@@ -0,0 +1,74 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+func TestCallbackHandler_Roles(t *testing.T) {
+	incRepo := &mockIncidentRepo{
+		data: map[string]*entity.Incident{
+			"CINC": {ChannelID: "CINC", ServiceID: 1, Urgency: "none", Description: "APIが落ちた", StartedAt: time.Now()},
+		},
+	}
+	cfgRepo := &mockConfigRepo{services: []entity.Service{{ID: 1, Name: "svc"}}}
+	slackRepo := &mockSlackRepo{}
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+	h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", nil, nil, nil)
+
+	click := func(userID, actionID, roleID string) {
+		t.Helper()
+		require.NoError(t, h.Handle(&slack.InteractionCallback{
+			Type: slack.InteractionTypeBlockActions,
+			Channel: slack.Channel{
+				GroupConversation: slack.GroupConversation{
+					Conversation: slack.Conversation{ID: "CINC"},
+				},
+			},
+			Message: slack.Message{Msg: slack.Msg{Timestamp: "111.222"}},
+			User:    slack.User{ID: userID},
+			ActionCallback: slack.ActionCallbacks{
+				BlockActions: []*slack.BlockAction{{ActionID: actionID, Value: roleID}},
+			},
+		}))
+	}
+
+	click("UA", "role_assign", "commander")
+	click("UA", "role_assign", "sme")
+	click("UB", "role_assign", "sme")
+	assert.Equal(t, map[string][]string{"commander": {"UA"}, "sme": {"UA", "UB"}}, incRepo.data["CINC"].Roles)
+
+	// 1人だけの役割は前任者から引き継ぐ
+	click("UB", "role_assign", "commander")
+	assert.Equal(t, []string{"UB"}, incRepo.data["CINC"].Roles["commander"])
+	msgs := slackRepo.messages["CINC"]
+	assert.Contains(t, msgs[len(msgs)-1], "\\u003c@UA\\u003e さんから *コマンダー* を引き継ぎました")
+
+	click("UA", "role_unassign", "sme")
+	assert.Equal(t, []string{"UB"}, incRepo.data["CINC"].Roles["sme"])
+
+	// 担当していない役割から外れても何もしない
+	click("UA", "role_unassign", "scribe")
+
+	var types []entity.IncidentEventType
+	for _, ev := range incRepo.events {
+		types = append(types, ev.Type)
+	}
+	assert.Equal(t, []entity.IncidentEventType{
+		entity.IncidentEventRoleAssigned,
+		entity.IncidentEventRoleAssigned,
+		entity.IncidentEventRoleAssigned,
+		entity.IncidentEventRoleAssigned,
+		entity.IncidentEventRoleUnassigned,
+	}, types)
+	assert.Equal(t, "UA", incRepo.events[3].Metadata["from"])
+}
@@ -0,0 +1,216 @@
+package handler
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+// severityValue は提案のボタンに持たせる値。レベルと緊急度を : でつなぐ
+func severityValue(level int, urgency string) string {
+	return fmt.Sprintf("%d:%s", level, urgency)
+}
+
+func parseSeverityValue(value string) (int, string, error) {
+	l, urgency, ok := strings.Cut(value, ":")
+	if !ok {
+		return 0, "", fmt.Errorf("invalid severity value: %s", value)
+	}
+	level, err := strconv.Atoi(l)
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid severity value: %s", value)
+	}
+	return level, urgency, nil
+}
+
+func (h *CallbackHandler) levelText(level int) string {
+	l, err := h.repository.IncidentLevelByLevel(h.ctx, level)
+	if err != nil || l == nil {
+		return fmt.Sprintf("レベル%d", level)
+	}
+	return fmt.Sprintf("レベル%d %s", level, l.Description)
+}
+
+func urgencyText(urgency string) string {
+	if text, ok := blocks.UrgencyMap[urgency]; ok {
+		return text
+	}
+	return urgency
+}
+
+// suggestSeverity は事象内容とチャンネルの発言から AI にインシデントレベルと緊急度を提案させ、採用/却下ボタンと合わせて投稿する
+// 提案は採用されるまで反映しない。現在の値と同じ提案や、一度却下された提案は投稿しない
+func (h *CallbackHandler) suggestSeverity(channelID string) error {
+	if h.aiRepository == nil || (h.config != nil && h.config.AI.SeveritySuggestion.Disabled) {
+		return nil
+	}
+
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+	if !incident.RecoveredAt.IsZero() {
+		return nil
+	}
+
+	messages, err := h.repository.GetAllChannelMessages(channelID)
+	if err != nil {
+		return fmt.Errorf("failed to GetAllChannelMessages: %w", err)
+	}
+	sort.Slice(messages, func(i, j int) bool {
+		return messages[i].Timestamp < messages[j].Timestamp
+	})
+	slackMessages, err := h.aiRepository.PrepareMessagesForPostMortem(messages, incident.Description)
+	if err != nil {
+		return fmt.Errorf("failed to PrepareMessagesForPostMortem: %w", err)
+	}
+
+	description := incident.Description
+	if incident.LastSummary != "" {
+		description = fmt.Sprintf("%s\n\n最新の進捗サマリ:\n%s", description, incident.LastSummary)
+	}
+	suggestion, err := h.aiRepository.SuggestSeverity(description, slackMessages, h.repository.IncidentLevels(h.ctx), blocks.UrgencyMap)
+	if err != nil {
+		return fmt.Errorf("failed to SuggestSeverity: %w", err)
+	}
+	if suggestion.Level == incident.Level && suggestion.Urgency == incident.Urgency {
+		return nil
+	}
+
+	rejected, err := h.severitySuggestionRejected(channelID, suggestion.Level, suggestion.Urgency)
+	if err != nil {
+		return err
+	}
+	if rejected {
+		slog.Info("severity suggestion already rejected", slog.String("channelID", channelID), slog.Int("level", suggestion.Level), slog.String("urgency", suggestion.Urgency))
+		return nil
+	}
+
+	// 伏せた値のうち戻してよいものは、元の発言から戻す
+	sources := []string{incident.Description}
+	for _, m := range messages {
+		sources = append(sources, m.Text)
+	}
+	_, _, err = h.repository.PostMessage(channelID, slack.MsgOptionBlocks(blocks.SeveritySuggestion(blocks.SeveritySuggestionItem{
+		LevelText:          h.levelText(suggestion.Level),
+		UrgencyText:        urgencyText(suggestion.Urgency),
+		CurrentLevelText:   h.levelText(incident.Level),
+		CurrentUrgencyText: urgencyText(incident.Urgency),
+		Reason:             h.aiRepository.Restore(suggestion.Reason, sources...),
+		Value:              severityValue(suggestion.Level, suggestion.Urgency),
+	})...))
+	return err
+}
+
+// severitySuggestionRejected は同じインシデントレベルと緊急度の提案が却下されたことがあるかを返す
+func (h *CallbackHandler) severitySuggestionRejected(channelID string, level int, urgency string) (bool, error) {
+	events, err := h.repository.IncidentEvents(h.ctx, channelID)
+	if err != nil {
+		return false, fmt.Errorf("failed to IncidentEvents: %w", err)
+	}
+	return slices.ContainsFunc(events, func(e entity.IncidentEvent) bool {
+		return e.Type == entity.IncidentEventSeveritySuggestionRejected &&
+			e.Metadata["level"] == strconv.Itoa(level) && e.Metadata["urgency"] == urgency
+	}), nil
+}
+
+// resolveSeveritySuggestion は提案を採用または却下し、提案のメッセージのボタンを判断した人の表示に置き換える
+// value は押されたボタンの値
+func (h *CallbackHandler) resolveSeveritySuggestion(channelID, userID, value string, message slack.Message, accepted bool) error {
+	level, urgency, err := parseSeverityValue(value)
+	if err != nil {
+		return err
+	}
+
+	var resolved []slack.Block
+	for _, b := range message.Blocks.BlockSet {
+		if b.BlockType() != slack.MBTAction && b.BlockType() != slack.MBTContext {
+			resolved = append(resolved, b)
+		}
+	}
+	h.repository.UpdateMessage(channelID, message.Timestamp,
+		slack.MsgOptionBlocks(append(resolved, blocks.SeveritySuggestionResult(userID, accepted))...),
+	)
+
+	if !accepted {
+		recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventSeveritySuggestionRejected, userID, map[string]string{
+			"level":   strconv.Itoa(level),
+			"urgency": urgency,
+		})
+		return nil
+	}
+
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+	if level != incident.Level {
+		if err := h.setIncidentLevel(channelID, userID, strconv.Itoa(level)); err != nil {
+			return fmt.Errorf("failed to setIncidentLevel: %w", err)
+		}
+	}
+	if urgency != incident.Urgency {
+		if err := h.setIncidentUrgency(channelID, userID, urgency); err != nil {
+			return fmt.Errorf("failed to setIncidentUrgency: %w", err)
+		}
+	}
+	return nil
+}
+
+// setIncidentUrgency は緊急度を変更し、チャンネルのトピックを更新して通知する
+func (h *CallbackHandler) setIncidentUrgency(channelID, userID, urgency string) error {
+	if _, ok := blocks.UrgencyMap[urgency]; !ok {
+		return fmt.Errorf("invalid urgency: %s", urgency)
+	}
+	incident, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+	if err != nil {
+		return fmt.Errorf("failed to FindIncidentByChannel: %w", err)
+	}
+	if incident == nil {
+		return fmt.Errorf("incident is nil")
+	}
+
+	var previousUrgency string
+	err = updateIncident(h.ctx, h.repository, incident, func(i *entity.Incident) error {
+		previousUrgency = i.Urgency
+		i.Urgency = urgency
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to SaveIncident: %w", err)
+	}
+	recordIncidentEvent(h.ctx, h.repository, channelID, entity.IncidentEventUrgencyChanged, userID, map[string]string{
+		"from": previousUrgency,
+		"to":   urgency,
+	})
+
+	if err := h.updateIncidentTopic(incident); err != nil {
+		slog.Error("Failed to update topic", slog.Any("err", err))
+	}
+
+	notificationType := "here"
+	if h.config != nil {
+		notificationType = h.config.GetNotificationType()
+	}
+	_, _, err = h.repository.PostMessage(
+		channelID,
+		slack.MsgOptionBlocks(blocks.IncidentUrgencyChanged(userID, urgencyText(urgency), notificationType)...),
+	)
+	if err != nil {
+		slog.Error("Failed to post incident urgency changed message", slog.Any("err", err))
+	}
+	return nil
+}
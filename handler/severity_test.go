@@ -0,0 +1,125 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+// severityAI は進捗サマリと、決まったインシデントレベルと緊急度の提案を返す
+type severityAI struct {
+	mockAIRepository
+	suggestion  repository.SeveritySuggestion
+	description string
+	suggested   int
+}
+
+func (m *severityAI) SuggestSeverity(description, slackMessages string, levels []entity.IncidentLevel, urgencies map[string]string) (*repository.SeveritySuggestion, error) {
+	m.description = description
+	m.suggested++
+	s := m.suggestion
+	return &s, nil
+}
+
+func TestCallbackHandler_SeveritySuggestion(t *testing.T) {
+	newHandler := func() (*handler.CallbackHandler, *mockIncidentRepo, *mockSlackRepo, *severityAI) {
+		incRepo := &mockIncidentRepo{
+			data: map[string]*entity.Incident{
+				"CINC": {ChannelID: "CINC", ServiceID: 1, Description: "APIが遅い", Urgency: "warning", StartedAt: time.Now()},
+			},
+		}
+		cfgRepo := &mockConfigRepo{
+			services: []entity.Service{{ID: 1, Name: "svc"}},
+			levels:   []entity.IncidentLevel{{Level: 1, Description: "一部に影響"}, {Level: 2, Description: "全面停止"}},
+		}
+		slackRepo := &mockSlackRepo{history: []slack.Message{
+			{Msg: slack.Msg{User: "U1", Text: "全リクエストが500を返しています", Timestamp: "100.000001"}},
+		}}
+		ai := &severityAI{
+			mockAIRepository: mockAIRepository{summarizeProgressResult: "全面的に停止している"},
+			suggestion:       repository.SeveritySuggestion{Level: 2, Urgency: "critical", Reason: "全リクエストが失敗している"},
+		}
+		repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+		return handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", ai, nil, nil), incRepo, slackRepo, ai
+	}
+	click := func(t *testing.T, h *handler.CallbackHandler, actionID, value string) {
+		t.Helper()
+		require.NoError(t, h.Handle(&slack.InteractionCallback{
+			Type: slack.InteractionTypeBlockActions,
+			Channel: slack.Channel{
+				GroupConversation: slack.GroupConversation{
+					Conversation: slack.Conversation{ID: "CINC"},
+				},
+			},
+			Message: slack.Message{Msg: slack.Msg{Timestamp: "111.222"}},
+			User:    slack.User{ID: "UA"},
+			ActionCallback: slack.ActionCallbacks{
+				BlockActions: []*slack.BlockAction{{ActionID: actionID, Value: value}},
+			},
+		}))
+	}
+
+	t.Run("進捗サマリの作成時に提案し、採用すると反映する", func(t *testing.T) {
+		h, incRepo, slackRepo, ai := newHandler()
+
+		click(t, h, "progress_summary_action", "")
+		require.Equal(t, 1, ai.suggested)
+		assert.Contains(t, ai.description, "全面的に停止している", "最新の進捗サマリも判断の材料にする")
+
+		posted := slackRepo.messages["CINC"][len(slackRepo.messages["CINC"])-1]
+		assert.Contains(t, posted, "インシデントレベルと緊急度の提案")
+		assert.Contains(t, posted, "レベル2 全面停止")
+		assert.Contains(t, posted, "全リクエストが失敗している")
+		assert.Contains(t, posted, `"value":"2:critical"`)
+		assert.Equal(t, 0, incRepo.data["CINC"].Level, "採用するまで反映しない")
+		assert.Equal(t, "warning", incRepo.data["CINC"].Urgency)
+
+		click(t, h, "severity_suggestion_accept", "2:critical")
+		assert.Equal(t, 2, incRepo.data["CINC"].Level)
+		assert.Equal(t, "critical", incRepo.data["CINC"].Urgency)
+		assert.Contains(t, slackRepo.updated[len(slackRepo.updated)-1], "提案を採用しました")
+
+		var types []entity.IncidentEventType
+		for _, ev := range incRepo.events {
+			types = append(types, ev.Type)
+		}
+		assert.Contains(t, types, entity.IncidentEventLevelChanged)
+		assert.Contains(t, types, entity.IncidentEventUrgencyChanged)
+
+		// 現在の値と同じ提案は投稿しない
+		count := len(slackRepo.messages["CINC"])
+		slackRepo.history = append(slackRepo.history, slack.Message{Msg: slack.Msg{User: "U1", Text: "まだ復旧しません", Timestamp: "200.000002"}})
+		click(t, h, "progress_summary_action", "")
+		assert.Equal(t, 2, ai.suggested)
+		assert.Len(t, slackRepo.messages["CINC"], count+1, "進捗サマリだけを投稿する")
+	})
+
+	t.Run("却下した提案は繰り返さない", func(t *testing.T) {
+		h, incRepo, slackRepo, ai := newHandler()
+
+		click(t, h, "progress_summary_action", "")
+		click(t, h, "severity_suggestion_reject", "2:critical")
+		assert.Equal(t, 0, incRepo.data["CINC"].Level)
+		assert.Contains(t, slackRepo.updated[len(slackRepo.updated)-1], "提案を却下しました")
+
+		count := len(slackRepo.messages["CINC"])
+		slackRepo.history = append(slackRepo.history, slack.Message{Msg: slack.Msg{User: "U1", Text: "まだ復旧しません", Timestamp: "200.000002"}})
+		click(t, h, "progress_summary_action", "")
+		assert.Equal(t, 2, ai.suggested)
+		assert.Len(t, slackRepo.messages["CINC"], count+1, "却下した提案は投稿しない")
+
+		// 異なる提案は投稿する
+		ai.suggestion = repository.SeveritySuggestion{Level: 1, Urgency: "error", Reason: "一部のAPIだけが失敗している"}
+		slackRepo.history = append(slackRepo.history, slack.Message{Msg: slack.Msg{User: "U1", Text: "一部は復旧しました", Timestamp: "300.000003"}})
+		click(t, h, "progress_summary_action", "")
+		assert.Contains(t, slackRepo.messages["CINC"][len(slackRepo.messages["CINC"])-1], `"value":"1:error"`)
+	})
+}
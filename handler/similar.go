@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+const (
+	defaultSimilarIncidentsLimit    = 3
+	defaultSimilarIncidentsMinScore = 0.5
+)
+
+// SetIncidentIndex は類似したインシデントの検索に使うインデックスを設定する
+// 設定しない場合は埋め込みベクトルを計算せず、検索もしない
+func (h *CallbackHandler) SetIncidentIndex(index *repository.IncidentIndex) {
+	h.incidentIndex = index
+}
+
+func (h *CallbackHandler) similarIncidentsConfig() entity.SimilarIncidentsConfig {
+	var c entity.SimilarIncidentsConfig
+	if h.config != nil {
+		c = h.config.AI.SimilarIncidents
+	}
+	c.Limit = cmp.Or(c.Limit, defaultSimilarIncidentsLimit)
+	c.MinScore = cmp.Or(c.MinScore, defaultSimilarIncidentsMinScore)
+	return c
+}
+
+// indexIncident はインシデントの文章の埋め込みベクトルを保存し、計算したベクトルとモデルを返す
+// 検索が無効な場合や失敗した場合は nil を返す
+func (h *CallbackHandler) indexIncident(channelID, source, text string) ([]float32, string) {
+	if h.incidentIndex == nil || h.aiRepository == nil || h.similarIncidentsConfig().Disabled || strings.TrimSpace(text) == "" {
+		return nil, ""
+	}
+	vectors, model, err := h.aiRepository.Embed(text)
+	if err != nil {
+		slog.Warn("failed to embed incident", slog.String("channelID", channelID), slog.String("source", source), slog.Any("err", err))
+		return nil, ""
+	}
+	err = h.incidentIndex.Save(h.ctx, &entity.IncidentEmbedding{
+		ChannelID: channelID,
+		Source:    source,
+		Model:     model,
+		Vector:    vectors[0],
+		UpdatedAt: timeNow(),
+	})
+	if err != nil {
+		slog.Warn("failed to save incident embedding", slog.String("channelID", channelID), slog.String("source", source), slog.Any("err", err))
+	}
+	return vectors[0], model
+}
+
+// postSimilarIncidents は事象内容が似ている復旧済みの過去のインシデントをチャンネルに投稿する
+// notifyEmpty が true の場合は、見つからなかったことも投稿する
+func (h *CallbackHandler) postSimilarIncidents(incident *entity.Incident, notifyEmpty bool) error {
+	c := h.similarIncidentsConfig()
+	if h.incidentIndex == nil || c.Disabled {
+		if notifyEmpty {
+			_, _, err := h.repository.PostMessage(incident.ChannelID, slack.MsgOptionText("❌ 類似インシデントの検索が設定されていません", false))
+			return err
+		}
+		return nil
+	}
+
+	vector, model := h.indexIncident(incident.ChannelID, entity.EmbeddingSourceDescription, incident.Description)
+	if vector == nil {
+		return fmt.Errorf("failed to embed description of %s", incident.ChannelID)
+	}
+
+	candidates := map[string]*entity.Incident{}
+	found, err := h.incidentIndex.Search(h.ctx, vector, model, c.Limit, func(channelID string) bool {
+		if channelID == incident.ChannelID {
+			return false
+		}
+		i, err := h.repository.FindIncidentByChannel(h.ctx, channelID)
+		if err != nil || i == nil || i.RecoveredAt.IsZero() {
+			return false
+		}
+		candidates[channelID] = i
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to search similar incidents: %w", err)
+	}
+
+	var items []blocks.SimilarIncidentItem
+	for _, f := range found {
+		if f.Score < c.MinScore {
+			break
+		}
+		i := candidates[f.ChannelID]
+		serviceName := "不明なサービス"
+		if service, err := h.repository.ServiceByID(h.ctx, i.ServiceID); err == nil && service != nil {
+			serviceName = service.Name
+		}
+		items = append(items, blocks.SimilarIncidentItem{
+			ChannelID:     i.ChannelID,
+			ServiceName:   serviceName,
+			Description:   i.Description,
+			StartedAt:     i.StartedAt,
+			PostMortemURL: i.PostMortemURL,
+			Score:         f.Score,
+		})
+	}
+
+	if len(items) == 0 {
+		if notifyEmpty {
+			_, _, err := h.repository.PostMessage(incident.ChannelID, slack.MsgOptionText("🔍 似ている過去のインシデントは見つかりませんでした", false))
+			return err
+		}
+		return nil
+	}
+	_, _, err = h.repository.PostMessage(incident.ChannelID, slack.MsgOptionBlocks(blocks.SimilarIncidents(items)...))
+	return err
+}
@@ -0,0 +1,108 @@
+package handler_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/slack-go/slack"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+// embeddingAI は文章に含まれる単語ごとの軸を持つベクトルを返す
+type embeddingAI struct {
+	mockAIRepository
+}
+
+func (m *embeddingAI) Embed(texts ...string) ([][]float32, string, error) {
+	vectors := make([][]float32, len(texts))
+	for i, text := range texts {
+		v := make([]float32, 3)
+		for j, word := range []string{"DB", "API", "DNS"} {
+			if strings.Contains(text, word) {
+				v[j] = 1
+			}
+		}
+		vectors[i] = v
+	}
+	return vectors, "test-embedding", nil
+}
+
+type mockEmbeddingRepo struct {
+	embeddings []entity.IncidentEmbedding
+}
+
+func (m *mockEmbeddingRepo) SaveIncidentEmbedding(_ context.Context, e *entity.IncidentEmbedding) error {
+	m.embeddings = append(m.embeddings, *e)
+	return nil
+}
+
+func (m *mockEmbeddingRepo) IncidentEmbeddings(_ context.Context) ([]entity.IncidentEmbedding, error) {
+	return m.embeddings, nil
+}
+
+func TestCallbackHandler_SimilarIncidents(t *testing.T) {
+	startedAt := time.Date(2025, 3, 10, 9, 0, 0, 0, time.UTC)
+	incRepo := &mockIncidentRepo{
+		data: map[string]*entity.Incident{
+			"CDB":     {ChannelID: "CDB", ServiceID: 1, Description: "DBの接続が枯渇", StartedAt: startedAt, RecoveredAt: startedAt.Add(time.Hour), PostMortemURL: "https://example.com/pm/db"},
+			"CDNS":    {ChannelID: "CDNS", ServiceID: 1, Description: "DNSの解決に失敗", StartedAt: startedAt, RecoveredAt: startedAt.Add(time.Hour)},
+			"CACTIVE": {ChannelID: "CACTIVE", ServiceID: 1, Description: "DBが遅い", StartedAt: startedAt},
+		},
+	}
+	embeddingRepo := &mockEmbeddingRepo{embeddings: []entity.IncidentEmbedding{
+		{ChannelID: "CDB", Source: entity.EmbeddingSourcePostMortem, Model: "test-embedding", Vector: []float32{1, 0, 0}},
+		{ChannelID: "CDNS", Source: entity.EmbeddingSourceDescription, Model: "test-embedding", Vector: []float32{0, 0, 1}},
+		// 対応中のインシデントは表示しない
+		{ChannelID: "CACTIVE", Source: entity.EmbeddingSourceDescription, Model: "test-embedding", Vector: []float32{1, 0, 0}},
+		// モデルが異なるベクトルは比較しない
+		{ChannelID: "COLD", Source: entity.EmbeddingSourceDescription, Model: "old-model", Vector: []float32{1, 0, 0}},
+	}}
+	cfgRepo := &mockConfigRepo{services: []entity.Service{{ID: 1, Name: "svc"}}}
+	slackRepo := &mockSlackRepo{}
+	repo := repository.NewRepository(incRepo, incRepo, cfgRepo, cfgRepo, slackRepo)
+	h := handler.NewCallbackHandler(context.Background(), repo, "https://example.com/", &embeddingAI{}, nil, nil)
+	index, err := repository.NewIncidentIndex(context.Background(), embeddingRepo)
+	require.NoError(t, err)
+	h.SetIncidentIndex(index)
+
+	require.NoError(t, h.Handle(&slack.InteractionCallback{
+		Type: slack.InteractionTypeViewSubmission,
+		View: slack.View{
+			CallbackID: "incident_modal",
+			State: &slack.ViewState{
+				Values: map[string]map[string]slack.BlockAction{
+					"service_block":          {"service_select": {SelectedOption: slack.OptionBlockObject{Value: "1"}}},
+					"incident_summary_block": {"summary_text": {Value: "DBのCPUが高騰"}},
+					"urgency_block":          {"urgency_select": {SelectedOption: slack.OptionBlockObject{Value: "none"}}},
+				},
+			},
+		},
+		User: slack.User{ID: "UMODAL"},
+	}))
+
+	var channelID, posted string
+	for ch, msgs := range slackRepo.messages {
+		if strings.HasPrefix(ch, "C-") {
+			channelID, posted = ch, msgs[len(msgs)-1]
+		}
+	}
+	require.NotEmpty(t, channelID)
+	assert.Contains(t, posted, "似ている過去のインシデント")
+	assert.Contains(t, posted, `\u003c#CDB\u003e`)
+	assert.Contains(t, posted, "https://example.com/pm/db")
+	assert.NotContains(t, posted, "CDNS", "類似度が低いものは表示しない")
+	assert.NotContains(t, posted, "CACTIVE")
+	assert.NotContains(t, posted, "COLD")
+
+	// 新しいインシデントの事象内容も検索の対象になる
+	last := embeddingRepo.embeddings[len(embeddingRepo.embeddings)-1]
+	assert.Equal(t, channelID, last.ChannelID)
+	assert.Equal(t, entity.EmbeddingSourceDescription, last.Source)
+}
@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/presentation/blocks"
+	"github.com/slack-go/slack"
+)
+
+// TimekeeperScheduler はインシデントのレベルと緊急度に応じたチェックポイントの間隔を返す
+type TimekeeperScheduler interface {
+	TimekeeperSchedule(ctx context.Context, level int, urgency string) entity.TimekeeperSchedule
+}
+
+var errCheckpointAlreadySent = errors.New("checkpoint is already sent")
+
+// Timekeeper は未クローズのインシデントにチェックポイントを投稿する
+// 最後に投稿したチェックポイントをインシデントに記録し、次の予定時刻を過ぎていれば投稿する
+// 停止中に複数のチェックポイントを過ぎていた場合も投稿は1回だけにする
+type Timekeeper struct {
+	ctx        context.Context
+	repository repository.Repository
+	scheduler  TimekeeperScheduler
+	now        func() time.Time
+}
+
+func NewTimekeeper(ctx context.Context, repository repository.Repository, scheduler TimekeeperScheduler, now func() time.Time) *Timekeeper {
+	return &Timekeeper{
+		ctx:        ctx,
+		repository: repository,
+		scheduler:  scheduler,
+		now:        now,
+	}
+}
+
+// Tick は予定時刻を過ぎたチェックポイントを投稿する
+func (t *Timekeeper) Tick() error {
+	incidents, err := t.repository.ActiveIncidents(t.ctx)
+	if err != nil {
+		return fmt.Errorf("failed to ActiveIncidents: %w", err)
+	}
+	now := t.now()
+	for _, incident := range incidents {
+		if err := t.checkpoint(&incident, now); err != nil {
+			slog.Error("Failed to send time keeper message", slog.Any("err", err), slog.String("channelID", incident.ChannelID))
+		}
+	}
+	return nil
+}
+
+// dueCheckpoint は now までに予定されていて、まだ投稿していない最後のチェックポイントを返す
+func dueCheckpoint(incident *entity.Incident, schedule entity.TimekeeperSchedule, now time.Time) (time.Time, bool) {
+	if incident.DisableTimer || incident.StartedAt.IsZero() {
+		return time.Time{}, false
+	}
+	at := schedule.Checkpoint(now.Sub(incident.StartedAt))
+	if at < 0 {
+		return time.Time{}, false
+	}
+	due := incident.StartedAt.Add(at)
+	if !incident.LastCheckpointAt.Before(due) {
+		return time.Time{}, false
+	}
+	return due, true
+}
+
+func (t *Timekeeper) checkpoint(incident *entity.Incident, now time.Time) error {
+	schedule := t.scheduler.TimekeeperSchedule(t.ctx, incident.Level, incident.Urgency)
+	due, ok := dueCheckpoint(incident, schedule, now)
+	if !ok {
+		return nil
+	}
+
+	channel, err := t.repository.GetChannelByID(incident.ChannelID)
+	if err != nil {
+		if err == repository.ErrSlackNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to get channel %s: %w", incident.ChannelID, err)
+	}
+	if channel.IsArchived {
+		return nil
+	}
+
+	// 投稿より先に記録して、次の実行や他のプロセスと重複して投稿しないようにする
+	err = updateIncident(t.ctx, t.repository, incident, func(i *entity.Incident) error {
+		due, ok = dueCheckpoint(i, t.scheduler.TimekeeperSchedule(t.ctx, i.Level, i.Urgency), now)
+		if !ok {
+			return errCheckpointAlreadySent
+		}
+		i.LastCheckpointAt = due
+		return nil
+	})
+	if errors.Is(err, errCheckpointAlreadySent) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to save checkpoint: %w", err)
+	}
+
+	elapsed := now.Sub(incident.StartedAt)
+	hours := int(elapsed.Hours())
+	minutes := int(elapsed.Minutes()) % 60
+	elapsedStr := fmt.Sprintf("%d時間%d分", hours, minutes)
+	next := incident.StartedAt.Add(schedule.NextCheckpoint(elapsed))
+
+	_, _, err = t.repository.PostMessage(
+		incident.ChannelID,
+		slack.MsgOptionBlocks(blocks.CheckPoint(elapsedStr, next)...),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to post time keeper message %s: %w", channel.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package handler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/pyama86/YAS3/domain/repository"
+	"github.com/pyama86/YAS3/handler"
+)
+
+type mockScheduler struct {
+	schedule entity.TimekeeperSchedule
+}
+
+func (m *mockScheduler) TimekeeperSchedule(_ context.Context, _ int, _ string) entity.TimekeeperSchedule {
+	return m.schedule
+}
+
+func TestTimekeeper_Tick(t *testing.T) {
+	startedAt := time.Date(2025, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	setup := func(incident entity.Incident, schedule entity.TimekeeperSchedule) (*handler.Timekeeper, *mockIncidentRepo, *mockSlackRepo, *time.Time) {
+		incRepo := &mockIncidentRepo{data: map[string]*entity.Incident{incident.ChannelID: &incident}}
+		slackRepo := &mockSlackRepo{}
+		repo := repository.NewRepository(incRepo, incRepo, &mockConfigRepo{}, &mockConfigRepo{}, slackRepo)
+		now := startedAt
+		tk := handler.NewTimekeeper(context.Background(), repo, &mockScheduler{schedule: schedule}, func() time.Time { return now })
+		return tk, incRepo, slackRepo, &now
+	}
+	// 保存された最新のインシデントを ActiveIncidents から返す
+	tick := func(t *testing.T, tk *handler.Timekeeper, incRepo *mockIncidentRepo) {
+		t.Helper()
+		incRepo.active = nil
+		for _, inc := range incRepo.data {
+			incRepo.active = append(incRepo.active, *inc)
+		}
+		require.NoError(t, tk.Tick())
+	}
+
+	t.Run("posts each checkpoint once", func(t *testing.T) {
+		tk, incRepo, slackRepo, now := setup(entity.Incident{ChannelID: "C1", StartedAt: startedAt}, entity.TimekeeperSchedule{})
+
+		*now = startedAt.Add(30 * time.Second)
+		tick(t, tk, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 1)
+		assert.Equal(t, startedAt, incRepo.data["C1"].LastCheckpointAt)
+
+		*now = startedAt.Add(10 * time.Minute)
+		tick(t, tk, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 1)
+
+		*now = startedAt.Add(15*time.Minute + 30*time.Second)
+		tick(t, tk, incRepo)
+		tick(t, tk, incRepo)
+		require.Len(t, slackRepo.messages["C1"], 2)
+		assert.Contains(t, slackRepo.messages["C1"][1], "0時間15分")
+		assert.Equal(t, startedAt.Add(15*time.Minute), incRepo.data["C1"].LastCheckpointAt)
+	})
+
+	t.Run("catches up once after downtime", func(t *testing.T) {
+		tk, incRepo, slackRepo, now := setup(entity.Incident{ChannelID: "C1", StartedAt: startedAt, LastCheckpointAt: startedAt}, entity.TimekeeperSchedule{
+			IntervalMinutes: 5,
+			Steps:           []entity.TimekeeperStep{{AfterMinutes: 60, IntervalMinutes: 30}},
+		})
+
+		*now = startedAt.Add(100 * time.Minute)
+		tick(t, tk, incRepo)
+		tick(t, tk, incRepo)
+		assert.Len(t, slackRepo.messages["C1"], 1)
+		assert.Equal(t, startedAt.Add(90*time.Minute), incRepo.data["C1"].LastCheckpointAt)
+	})
+
+	t.Run("skips when another process sent it", func(t *testing.T) {
+		tk, incRepo, slackRepo, now := setup(entity.Incident{ChannelID: "C1", StartedAt: startedAt, LastCheckpointAt: startedAt}, entity.TimekeeperSchedule{})
+		incRepo.conflict = func(m *mockIncidentRepo) bool {
+			m.conflict = nil
+			latest := *m.data["C1"]
+			latest.LastCheckpointAt = startedAt.Add(15 * time.Minute)
+			latest.Version++
+			m.data["C1"] = &latest
+			return true
+		}
+
+		*now = startedAt.Add(16 * time.Minute)
+		tick(t, tk, incRepo)
+		assert.Empty(t, slackRepo.messages["C1"])
+	})
+
+	t.Run("disabled timer", func(t *testing.T) {
+		tk, incRepo, slackRepo, now := setup(entity.Incident{ChannelID: "C1", StartedAt: startedAt, DisableTimer: true}, entity.TimekeeperSchedule{})
+
+		*now = startedAt.Add(15 * time.Minute)
+		tick(t, tk, incRepo)
+		assert.Empty(t, slackRepo.messages["C1"])
+	})
+}
@@ -0,0 +1,67 @@
+package blocks
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+func AlertFired(title, description string, labels map[string]string, generatorURL string) []slack.Block {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	labelLines := make([]string, 0, len(keys))
+	for _, k := range keys {
+		labelLines = append(labelLines, fmt.Sprintf("• `%s`: %s", k, labels[k]))
+	}
+
+	text := fmt.Sprintf("🔔 *アラートによりインシデントチャンネルを作成しました*\n*%s*", title)
+	if description != "" && description != title {
+		text += "\n" + description
+	}
+
+	b := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil,
+			nil,
+		),
+	}
+	if len(labelLines) > 0 {
+		b = append(b, slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", "*ラベル*\n"+strings.Join(labelLines, "\n"), false, false),
+			nil,
+			nil,
+		))
+	}
+	if generatorURL != "" {
+		b = append(b, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("<%s|アラートの詳細>", generatorURL), false, false)))
+	}
+	return b
+}
+
+func AlertResolved(title, generatorURL string) []slack.Block {
+	return alertStatusChanged(fmt.Sprintf("✅ *アラートが解消しました:* %s\n状況を確認し、問題なければ復旧を宣言してください。", title), generatorURL)
+}
+
+func AlertRefired(title, generatorURL string) []slack.Block {
+	return alertStatusChanged(fmt.Sprintf("🔁 *復旧宣言後にアラートが再度発火しました:* %s\n必要であればインシデントを再開してください。", title), generatorURL)
+}
+
+func alertStatusChanged(text, generatorURL string) []slack.Block {
+	b := []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil,
+			nil,
+		),
+	}
+	if generatorURL != "" {
+		b = append(b, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("<%s|アラートの詳細>", generatorURL), false, false)))
+	}
+	return b
+}
@@ -2,11 +2,13 @@ package blocks
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/slack-go/slack"
 )
 
-func CheckPoint(elapsedStr string) []slack.Block {
+// CheckPoint は next に次回のチェックポイントの時刻を表示する
+func CheckPoint(elapsedStr string, next time.Time) []slack.Block {
 	blocks := []slack.Block{}
 
 	// 0時間0分の場合はチェックポイントメッセージを表示しない
@@ -15,7 +17,7 @@ func CheckPoint(elapsedStr string) []slack.Block {
 			slack.NewTextBlockObject(
 				"mrkdwn",
 				fmt.Sprintf(
-					"15分ごとのチェックポイントです。インシデントの検知から *%s* 経過しています。", elapsedStr,
+					"チェックポイントです。インシデントの検知から *%s* 経過しています。", elapsedStr,
 				),
 				false,
 				false,
@@ -29,7 +31,10 @@ func CheckPoint(elapsedStr string) []slack.Block {
 		slack.NewSectionBlock(
 			slack.NewTextBlockObject(
 				"mrkdwn",
-				":loudspeaker: *状況更新アナウンス*\n\n事象内容やインシデントレベルの変更など状況に変化があれば、こちらで最新情報を共有してください。",
+				fmt.Sprintf(
+					":loudspeaker: *状況更新アナウンス*\n\n事象内容やインシデントレベルの変更など状況に変化があれば、こちらで最新情報を共有してください。\n次回のチェックポイントは *<!date^%d^{time}|%s>* です。",
+					next.Unix(), next.Format("15:04"),
+				),
 				false,
 				false,
 			),
@@ -0,0 +1,166 @@
+package blocks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/slack-go/slack"
+)
+
+// AudienceNames は連絡の読み手の表示名
+var AudienceNames = map[string]string{
+	entity.AudienceExecutive:   "👔 経営層向け",
+	entity.AudienceCustomer:    "🙇 顧客向け",
+	entity.AudienceEngineering: "🛠️ エンジニア向け",
+}
+
+// セクションの文字数の上限(3000文字)に収める
+const communicationDraftMaxLength = 2900
+
+// CommunicationDraftItem は読み手ごとの連絡の下書きの表示内容
+type CommunicationDraftItem struct {
+	Audience string
+	// 下書き。作成に失敗した場合は空
+	Text string
+	// 投稿先のチャンネル名
+	Channels []string
+}
+
+// CommunicationDraftBlockID は下書きの本文を表示するセクションのID。編集時に本文を読み出す
+func CommunicationDraftBlockID(audience string) string {
+	return "communication_draft_" + audience
+}
+
+func channelList(channels []string) string {
+	names := make([]string, len(channels))
+	for i, c := range channels {
+		names[i] = "#" + c
+	}
+	return strings.Join(names, ", ")
+}
+
+// CommunicationDraftsLoading は下書きの作成中の表示
+func CommunicationDraftsLoading() []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", "✍️ 関係者向けの連絡の下書きを作成中です...", false, false),
+			nil,
+			nil,
+		),
+	}
+}
+
+// CommunicationDrafts は読み手ごとの下書きと、編集して投稿するボタン
+// 投稿先が設定されていない読み手にはボタンを表示しない
+func CommunicationDrafts(items []CommunicationDraftItem) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject("plain_text", "✍️ 関係者向けの連絡の下書き", false, false),
+		),
+		slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject("mrkdwn", "事象内容とチャンネルの発言から AI が作成した下書きです。内容を確認し、必要に応じて編集してから投稿してください", false, false),
+		),
+	}
+	for _, item := range items {
+		blocks = append(blocks, slack.NewDividerBlock())
+		if item.Text == "" {
+			blocks = append(blocks, slack.NewSectionBlock(
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*%s*\n❌ 下書きの作成に失敗しました", AudienceNames[item.Audience]), false, false),
+				nil,
+				nil,
+			))
+			continue
+		}
+
+		title := fmt.Sprintf("*%s*", AudienceNames[item.Audience])
+		if len(item.Channels) > 0 {
+			title += " → " + channelList(item.Channels)
+		}
+		text := []rune(item.Text)
+		if len(text) > communicationDraftMaxLength {
+			text = text[:communicationDraftMaxLength]
+		}
+		blocks = append(blocks,
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject("mrkdwn", title, false, false),
+				nil,
+				nil,
+			),
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject("mrkdwn", string(text), false, false),
+				nil,
+				nil,
+				slack.SectionBlockOptionBlockID(CommunicationDraftBlockID(item.Audience)),
+			),
+		)
+		if len(item.Channels) == 0 {
+			blocks = append(blocks, slack.NewContextBlock(
+				"",
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("投稿先のチャンネルが設定されていません (communication.%s)", item.Audience), false, false),
+			))
+			continue
+		}
+		blocks = append(blocks, slack.NewActionBlock(
+			"communication_edit_"+item.Audience,
+			slack.NewButtonBlockElement(
+				"communication_edit",
+				item.Audience,
+				slack.NewTextBlockObject("plain_text", "✏️ 編集して投稿", false, false),
+			),
+		))
+	}
+	return blocks
+}
+
+// EditCommunication は連絡を編集して投稿するモーダルの内容
+func EditCommunication(audience, text string, channels []string) slack.Blocks {
+	return slack.Blocks{
+		BlockSet: []slack.Block{
+			slack.NewContextBlock(
+				"",
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("%sの連絡を %s に投稿します", AudienceNames[audience], channelList(channels)), false, false),
+			),
+			&slack.InputBlock{
+				Type:    slack.MBTInput,
+				BlockID: "communication_block",
+				Label: &slack.TextBlockObject{
+					Type: "plain_text",
+					Text: "連絡の内容",
+				},
+				Element: &slack.PlainTextInputBlockElement{
+					Type:         slack.METPlainTextInput,
+					ActionID:     "communication_text",
+					InitialValue: text,
+					Multiline:    true,
+					MaxLength:    communicationDraftMaxLength,
+				},
+				Optional: false,
+			},
+		},
+	}
+}
+
+// Communication は読み手のチャンネルに投稿する連絡
+func Communication(audience, text, incidentChannelID, userID string) []slack.Block {
+	return []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject("plain_text", "📣 インシデントに関する連絡", false, false),
+		),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil,
+			nil,
+		),
+		slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("%sの連絡 | <@%s> さんが <#%s> から投稿しました", AudienceNames[audience], userID, incidentChannelID), false, false),
+		),
+	}
+}
+
+// CommunicationPosted は連絡を投稿したことの通知
+func CommunicationPosted(audience, userID string, channels []string) string {
+	return fmt.Sprintf("✅ <@%s> さんが%sの連絡を %s に投稿しました", userID, AudienceNames[audience], channelList(channels))
+}
@@ -0,0 +1,51 @@
+package blocks
+
+import (
+	"fmt"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/slack-go/slack"
+)
+
+func escalationReasonText(reason string, minutes int) string {
+	switch reason {
+	case entity.EscalationNoHandler:
+		return fmt.Sprintf("インシデントの発生から %d分 経過しましたが、ハンドラーが決まっていません。", minutes)
+	case entity.EscalationSilent:
+		return fmt.Sprintf("インシデントチャンネルで %d分 発言がありません。", minutes)
+	default:
+		return reason
+	}
+}
+
+// Escalation はインシデントチャンネルに投稿するエスカレーション
+func Escalation(mentions, reason string, minutes int) []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(
+				"mrkdwn",
+				fmt.Sprintf("🆘 *エスカレーション* %s\n%s\n状況の確認と対応をお願いします。", mentions, escalationReasonText(reason, minutes)),
+				false,
+				false,
+			),
+			nil,
+			nil,
+		),
+	}
+}
+
+// EscalationAnnouncement はアナウンスチャンネルに投稿するエスカレーション
+func EscalationAnnouncement(channelID, reason string, minutes int) []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(
+				"mrkdwn",
+				fmt.Sprintf("🆘 <#%s> をエスカレーションしました。\n%s", channelID, escalationReasonText(reason, minutes)),
+				false,
+				false,
+			),
+			nil,
+			nil,
+		),
+	}
+}
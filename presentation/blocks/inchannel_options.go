@@ -14,11 +14,26 @@ func InChannelOptions() []*slack.OptionBlockObject {
 			slack.NewTextBlockObject("plain_text", "📝 事象内容を編集する", false, false),
 			nil,
 		),
+		slack.NewOptionBlockObject(
+			"show_incident_roles",
+			slack.NewTextBlockObject("plain_text", "👥 役割を割り当てる", false, false),
+			nil,
+		),
 		slack.NewOptionBlockObject(
 			"create_progress_summary",
 			slack.NewTextBlockObject("plain_text", "📊 進捗サマリを作成する", false, false),
 			nil,
 		),
+		slack.NewOptionBlockObject(
+			"draft_communications",
+			slack.NewTextBlockObject("plain_text", "✍️ 関係者向けの連絡を作成する", false, false),
+			nil,
+		),
+		slack.NewOptionBlockObject(
+			"find_similar_incidents",
+			slack.NewTextBlockObject("plain_text", "🔍 類似インシデントを探す", false, false),
+			nil,
+		),
 		slack.NewOptionBlockObject(
 			"stop_timekeeper",
 			slack.NewTextBlockObject("plain_text", "⏹️ タイムキーパーをとめる", false, false),
@@ -0,0 +1,83 @@
+package blocks
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/slack-go/slack"
+)
+
+func IncidentCommandHelp() []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(
+				"mrkdwn",
+				strings.Join([]string{
+					"*`/incident` の使い方*",
+					"• `/incident new` インシデントチャンネルを作成",
+					"• `/incident level <n>` インシデントレベルを変更",
+					"• `/incident recover` 復旧を宣言",
+					"• `/incident reopen` 復旧済みのインシデントを再開",
+					"• `/incident handler [@user]` ハンドラを設定(省略時は自分)",
+					"• `/incident summary` 事象内容を編集",
+					"• `/incident status` インシデントの状況を表示",
+					"• `/incident ask <質問>` チャンネルの発言をもとに AI が質問に回答",
+					"• `/incident list` 未クローズのインシデント一覧を表示",
+				}, "\n"),
+				false,
+				false,
+			),
+			nil,
+			nil,
+		),
+	}
+}
+
+func IncidentStatus(incident *entity.Incident, service *entity.Service, levelText, urgencyText string, now time.Time) []slack.Block {
+	status := "🚨 対応中"
+	if !incident.RecoveredAt.IsZero() {
+		status = fmt.Sprintf("✅ 復旧済み (%s)", incident.RecoveredAt.Format("2006-01-02 15:04"))
+	}
+	handler := "未設定"
+	if incident.HandlerUserID != "" {
+		handler = fmt.Sprintf("<@%s>", incident.HandlerUserID)
+	}
+	elapsed := now.Sub(incident.StartedAt)
+
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*<#%s> の状況*", incident.ChannelID), false, false),
+			[]*slack.TextBlockObject{
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*ステータス:* %s", status), false, false),
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*サービス名:* %s", service.Name), false, false),
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*緊急度:* %s", urgencyText), false, false),
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*レベル:* %s", levelText), false, false),
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*ハンドラ:* %s", handler), false, false),
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*経過時間:* %d時間%d分", int(elapsed.Hours()), int(elapsed.Minutes())%60), false, false),
+			},
+			nil,
+		),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*事象内容:* %s", incident.Description), false, false),
+			nil,
+			nil,
+		),
+	}
+}
+
+// OpenIncidentSummaries は未クローズのインシデントを1行ずつ表示する
+func OpenIncidentSummaries(lines []string) []slack.Block {
+	text := "現在、未クローズのインシデントはありません。"
+	if len(lines) > 0 {
+		text = fmt.Sprintf("*📋 未クローズのインシデント一覧 (全%d件)*\n%s", len(lines), strings.Join(lines, "\n"))
+	}
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil,
+			nil,
+		),
+	}
+}
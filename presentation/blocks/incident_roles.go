@@ -0,0 +1,97 @@
+package blocks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pyama86/YAS3/domain/entity"
+	"github.com/slack-go/slack"
+)
+
+func mentionUsers(userIDs []string) string {
+	mentions := make([]string, 0, len(userIDs))
+	for _, id := range userIDs {
+		mentions = append(mentions, fmt.Sprintf("<@%s>", id))
+	}
+	return strings.Join(mentions, " ")
+}
+
+// RosterText は担当者がいる役割を設定の順に並べた文字列を返す。誰もいない場合は空文字を返す
+func RosterText(roles []entity.IncidentRole, assignments map[string][]string) string {
+	var parts []string
+	for _, r := range roles {
+		if len(assignments[r.ID]) == 0 {
+			continue
+		}
+		parts = append(parts, fmt.Sprintf("%s:%s", r.Name, mentionUsers(assignments[r.ID])))
+	}
+	return strings.Join(parts, " / ")
+}
+
+// RosterContext はアナウンスに添える対応体制
+func RosterContext(roles []entity.IncidentRole, assignments map[string][]string) *slack.ContextBlock {
+	return slack.NewContextBlock("roster_context",
+		slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*対応体制:* %s", RosterText(roles, assignments)), false, false),
+	)
+}
+
+// IncidentRoles は役割ごとの担当者と、担当する/外れるボタンを表示する
+func IncidentRoles(roles []entity.IncidentRole, assignments map[string][]string) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject("plain_text", "👥 対応体制", false, false),
+		),
+	}
+	for _, r := range roles {
+		assignees := "未割り当て"
+		if len(assignments[r.ID]) > 0 {
+			assignees = mentionUsers(assignments[r.ID])
+		}
+		blocks = append(blocks,
+			slack.NewSectionBlock(
+				slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("*%s:* %s", r.Name, assignees), false, false),
+				nil,
+				nil,
+			),
+			slack.NewActionBlock(
+				"role_action_"+r.ID,
+				slack.NewButtonBlockElement(
+					"role_assign",
+					r.ID,
+					slack.NewTextBlockObject("plain_text", "🙋 担当する", false, false),
+				).WithStyle(slack.StylePrimary),
+				slack.NewButtonBlockElement(
+					"role_unassign",
+					r.ID,
+					slack.NewTextBlockObject("plain_text", "外れる", false, false),
+				),
+			),
+		)
+	}
+	return blocks
+}
+
+// RoleAssigned は役割の担当や引き継ぎを知らせる。previousUserID が空の場合は新たに担当したことを知らせる
+func RoleAssigned(userID, roleName, previousUserID string) []slack.Block {
+	text := fmt.Sprintf("🙋 <@%s> さんが *%s* を担当します。", userID, roleName)
+	if previousUserID != "" {
+		text = fmt.Sprintf("🔁 <@%s> さんが <@%s> さんから *%s* を引き継ぎました。", userID, previousUserID, roleName)
+	}
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil,
+			nil,
+		),
+	}
+}
+
+func RoleUnassigned(userID, roleName string) []slack.Block {
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("👋 <@%s> さんが *%s* から外れました。", userID, roleName), false, false),
+			nil,
+			nil,
+		),
+	}
+}
@@ -0,0 +1,31 @@
+package blocks
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// OnCallHandler はオンコールの担当者をハンドラーにしたことを知らせる
+// 別の人が指揮を取る場合に備えて、ハンドラーを引き受けるボタンも表示する
+func OnCallHandler(userID string) []slack.Block {
+	return []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject("plain_text", "📟 オンコール担当を招集しました", false, false),
+		),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("👩‍💻 オンコール担当の <@%s> さんをハンドラーに設定しました。\n状況把握と指揮をお願いします！", userID), false, false),
+			nil,
+			nil,
+		),
+		slack.NewDividerBlock(),
+		slack.NewActionBlock(
+			"handler_action",
+			slack.NewButtonBlockElement(
+				"handler_button",
+				"handler_button",
+				slack.NewTextBlockObject("plain_text", "👋 ハンドラーを代わります！", false, false),
+			),
+		),
+	}
+}
@@ -22,10 +22,50 @@ func ProgressSummary(summary string) []slack.Block {
 			slack.NewTextBlockObject("plain_text", "📢 報告chに投稿", false, false),
 		).WithStyle(slack.StylePrimary),
 	))
+	blocks = append(blocks, communicationDraftsAction())
 
 	return blocks
 }
 
+// communicationDraftsAction は進捗サマリをもとに関係者向けの連絡の下書きを作成するボタン
+// 報告ボタンを消しても残すため、別のアクションブロックにする
+func communicationDraftsAction() slack.Block {
+	return slack.NewActionBlock(
+		"communication_drafts_action",
+		slack.NewButtonBlockElement(
+			"communication_drafts_action",
+			"communication_drafts_button",
+			slack.NewTextBlockObject("plain_text", "✍️ 関係者向けの連絡を作成", false, false),
+		),
+	)
+}
+
+// ScheduledProgressSummary は自動で作成した進捗サマリ
+// アナウンスチャンネルに投稿済みの場合は報告ボタンを表示しない
+func ScheduledProgressSummary(summary string, intervalMinutes int, announced bool) []slack.Block {
+	note := fmt.Sprintf("⏰ %d分ごとに自動で作成した進捗サマリです", intervalMinutes)
+	if announced {
+		note += "。アナウンスチャンネルにも投稿しました"
+	}
+	blocks := parseProgressSummaryToBlocks(summary)
+	blocks = append(blocks, slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", note, false, false)))
+	if announced {
+		return append(blocks, communicationDraftsAction())
+	}
+	return append(blocks,
+		slack.NewDividerBlock(),
+		slack.NewActionBlock(
+			"report_post_action",
+			slack.NewButtonBlockElement(
+				"report_post_action",
+				"report_post_button",
+				slack.NewTextBlockObject("plain_text", "📢 報告chに投稿", false, false),
+			).WithStyle(slack.StylePrimary),
+		),
+		communicationDraftsAction(),
+	)
+}
+
 // マークダウンサマリをSlackブロックに変換する関数
 func parseProgressSummaryToBlocks(summary string) []slack.Block {
 	var blocks []slack.Block
@@ -0,0 +1,44 @@
+package blocks
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/slack-go/slack"
+)
+
+// sectionTextLimit はセクションブロックに表示できる文字数の上限
+const sectionTextLimit = 3000
+
+// QuestionAnswer はチャンネルの発言をもとにした質問への回答と、根拠にした発言の一覧を表示する
+func QuestionAnswer(userID, question, answer string, references []string) []slack.Block {
+	if r := []rune(answer); len(r) > sectionTextLimit {
+		answer = string(r[:sectionTextLimit-1]) + "…"
+	}
+	blocks := []slack.Block{
+		slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("💬 <@%s> さんの質問: %s", userID, question), false, false),
+		),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", answer, false, false),
+			nil,
+			nil,
+		),
+	}
+	if len(references) > 0 {
+		text := "*参照した発言*\n" + strings.Join(references, "\n")
+		if r := []rune(text); len(r) > sectionTextLimit {
+			text = string(r[:sectionTextLimit-1]) + "…"
+		}
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", text, false, false),
+			nil,
+			nil,
+		))
+	}
+	return append(blocks, slack.NewContextBlock(
+		"",
+		slack.NewTextBlockObject("mrkdwn", "🤖 AI による回答です。重要な判断の前に元の発言を確認してください", false, false),
+	))
+}
@@ -0,0 +1,82 @@
+package blocks
+
+import (
+	"fmt"
+
+	"github.com/slack-go/slack"
+)
+
+// SeveritySuggestionItem は AI が提案したインシデントレベルと緊急度の表示内容
+type SeveritySuggestionItem struct {
+	LevelText          string
+	UrgencyText        string
+	CurrentLevelText   string
+	CurrentUrgencyText string
+	Reason             string
+	// 採用ボタンの値。採用時にこの値で変更する
+	Value string
+}
+
+// SeveritySuggestion は提案と採用/却下ボタン
+func SeveritySuggestion(item SeveritySuggestionItem) []slack.Block {
+	return []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject("plain_text", "🤖 インシデントレベルと緊急度の提案", false, false),
+		),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject(
+				"mrkdwn",
+				fmt.Sprintf("*インシデントレベル:* %s → *%s*\n*緊急度:* %s → *%s*\n\n*根拠:* %s",
+					item.CurrentLevelText, item.LevelText, item.CurrentUrgencyText, item.UrgencyText, item.Reason),
+				false,
+				false,
+			),
+			nil,
+			nil,
+		),
+		slack.NewContextBlock(
+			"",
+			slack.NewTextBlockObject("mrkdwn", "事象内容とチャンネルの発言から AI が提案しています。採用するまで変更されません", false, false),
+		),
+		slack.NewActionBlock(
+			"severity_suggestion",
+			slack.NewButtonBlockElement(
+				"severity_suggestion_accept",
+				item.Value,
+				slack.NewTextBlockObject("plain_text", "✅ 採用する", false, false),
+			).WithStyle(slack.StylePrimary),
+			slack.NewButtonBlockElement(
+				"severity_suggestion_reject",
+				item.Value,
+				slack.NewTextBlockObject("plain_text", "❌ 却下する", false, false),
+			),
+		),
+	}
+}
+
+// SeveritySuggestionResult は採用/却下ボタンの代わりに表示する、誰が判断したか
+func SeveritySuggestionResult(userID string, accepted bool) slack.Block {
+	result := fmt.Sprintf("❌ <@%s> さんが提案を却下しました", userID)
+	if accepted {
+		result = fmt.Sprintf("✅ <@%s> さんが提案を採用しました", userID)
+	}
+	return slack.NewContextBlock("", slack.NewTextBlockObject("mrkdwn", result, false, false))
+}
+
+// IncidentUrgencyChanged は緊急度の変更の通知
+func IncidentUrgencyChanged(userID, urgencyText, notificationType string) []slack.Block {
+	notificationText := AddNotification("緊急度が変更されました", notificationType)
+
+	return []slack.Block{
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", notificationText, false, false),
+			nil,
+			nil,
+		),
+		slack.NewSectionBlock(
+			slack.NewTextBlockObject("mrkdwn", fmt.Sprintf("<@%s> さんが緊急度を「 *%s* 」に変更しました", userID, urgencyText), false, false),
+			nil,
+			nil,
+		),
+	}
+}
@@ -0,0 +1,48 @@
+package blocks
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/slack-go/slack"
+)
+
+// SimilarIncidentItem は類似した過去のインシデントの表示内容
+type SimilarIncidentItem struct {
+	ChannelID     string
+	ServiceName   string
+	Description   string
+	StartedAt     time.Time
+	PostMortemURL string
+	// コサイン類似度
+	Score float64
+}
+
+func SimilarIncidents(items []SimilarIncidentItem) []slack.Block {
+	blocks := []slack.Block{
+		slack.NewHeaderBlock(
+			slack.NewTextBlockObject("plain_text", "🔍 似ている過去のインシデント", false, false),
+		),
+	}
+	for _, item := range items {
+		postmortem := "ポストモーテムなし"
+		if item.PostMortemURL != "" {
+			postmortem = fmt.Sprintf("<%s|📝 ポストモーテム>", item.PostMortemURL)
+		}
+		blocks = append(blocks, slack.NewSectionBlock(
+			slack.NewTextBlockObject(
+				"mrkdwn",
+				fmt.Sprintf("*<#%s>* %s (%s)\n%s\n%s ・ 類似度 %.0f%%",
+					item.ChannelID, item.ServiceName, item.StartedAt.Format("2006-01-02"), item.Description, postmortem, item.Score*100),
+				false,
+				false,
+			),
+			nil,
+			nil,
+		))
+	}
+	return append(blocks, slack.NewContextBlock(
+		"",
+		slack.NewTextBlockObject("mrkdwn", "事象内容、進捗サマリ、ポストモーテムの近さから AI が選んでいます", false, false),
+	))
+}
@@ -1,68 +1,185 @@
 package postmortem
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Data はポストモーテムのテンプレートに渡す値
+type Data struct {
+	// AI が生成したタイトル
+	Title string
+	// ポストモーテムを作成したユーザー
+	Author   string
+	Incident Incident
+	// AI が生成した各セクション。AI を使わない場合は記入例が入る
+	Sections Sections
+	// 時系列に並べたチャンネルの発言
+	Timeline string
+	// 担当者がいる役割のみ、設定の順に並ぶ
+	Roles []Role
+	Links Links
+}
+
+// Incident はインシデントの記録。ユーザーは表示名に変換済み
+type Incident struct {
+	Service     string
+	Description string
+	// none / warning / error / critical
+	Urgency     string
+	Level       int
+	Handler     string
+	CreatedBy   string
+	RecoveredBy string
+	StartedAt   time.Time
+	RecoveredAt time.Time
+}
+
+// Duration は検知から復旧宣言までの時間
+func (i Incident) Duration() time.Duration {
+	if i.RecoveredAt.IsZero() {
+		return 0
+	}
+	return i.RecoveredAt.Sub(i.StartedAt).Truncate(time.Minute)
+}
+
+type Sections struct {
+	Summary      string
+	Status       string
+	Impact       string
+	RootCause    string
+	Trigger      string
+	Solution     string
+	ActionItems  string
+	LessonsGood  string
+	LessonsBad   string
+	LessonsLucky string
+}
+
+type Role struct {
+	Name  string
+	Users []string
+}
+
+type Links struct {
+	// インシデント対応チャンネルの URL
+	Channel string
+}
 
-func Render(title, createdAt, author, summary, status, impact, rootCause, trigger, solution, actionItems, lessonsGood, lessonsBad, lessonsLucky, timeline, channelURL string) string {
-	return fmt.Sprintf(`
+// Roster は役割ごとの担当者を箇条書きにする。担当者がいない場合は空文字を返す
+func (d Data) Roster() string {
+	lines := make([]string, 0, len(d.Roles))
+	for _, r := range d.Roles {
+		lines = append(lines, fmt.Sprintf("- %s: %s", r.Name, strings.Join(r.Users, ", ")))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// DefaultTemplate はテンプレートが設定されていない場合に使う
+const DefaultTemplate = `
 # タイトル
 
-%s
+{{ .Title }}
 
 ## 発生日付
 
-%s
+{{ .Incident.StartedAt.Format "2006-01-02 15:04:05" }}
 
 ## 起票者
 
-%s
+{{ .Author }}
 
 ## ステータス
 
-%s
+{{ .Sections.Status }}
 
 ## 概要
 
-%s
+{{ .Sections.Summary }}
 
 ## 影響
 
-%s
+{{ .Sections.Impact }}
 
 ## 主な原因
 
-%s
+{{ .Sections.RootCause }}
 
 ## 障害発生のトリガー
 
-%s
+{{ .Sections.Trigger }}
 
 ## 解決策
 
-%s
+{{ .Sections.Solution }}
 
 ## アクションアイテム
 
-%s
+{{ .Sections.ActionItems }}
 
 ## 学んだ教訓
 
 ### うまくいったこと
 
-%s
+{{ .Sections.LessonsGood }}
 
 ### うまくいかなかったこと
 
-%s
+{{ .Sections.LessonsBad }}
 
 ### 幸運だったこと
 
-%s
+{{ .Sections.LessonsLucky }}
 
 ## タイムライン
 
-%s
+{{ .Timeline }}
+
+## 対応体制
+
+{{ or .Roster "記録なし" }}
 
 ## 補足情報
-- [インシデント対応チャンネル](%s)
-`, title, createdAt, author, status, summary, impact, rootCause, trigger, solution, actionItems, lessonsGood, lessonsBad, lessonsLucky, timeline, channelURL)
+- [インシデント対応チャンネル]({{ .Links.Channel }})
+`
+
+type Template struct {
+	tmpl *template.Template
+}
+
+// Parse はテンプレートを解析し、サンプルの値で描画できることを確認する
+// 存在しないフィールドの参照は描画するまで検出できないため
+func Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse postmortem template error: %w", err)
+	}
+	t := &Template{tmpl: tmpl}
+	if _, err := t.Render(Data{Roles: []Role{{Name: "コマンダー", Users: []string{"example"}}}}); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Load はファイルからテンプレートを読み込む。path が空の場合はデフォルトのテンプレートを返す
+func Load(path string) (*Template, error) {
+	if path == "" {
+		return Parse("default", DefaultTemplate)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read postmortem template error: %w", err)
+	}
+	return Parse(path, string(b))
+}
+
+func (t *Template) Render(d Data) (string, error) {
+	var b strings.Builder
+	if err := t.tmpl.Execute(&b, d); err != nil {
+		return "", fmt.Errorf("render postmortem template error: %w", err)
+	}
+	return b.String(), nil
 }
@@ -0,0 +1,83 @@
+package postmortem_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pyama86/YAS3/presentation/postmortem"
+)
+
+func TestTemplate_Render(t *testing.T) {
+	startedAt := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	data := postmortem.Data{
+		Title:  "APIの応答停止",
+		Author: "alice",
+		Incident: postmortem.Incident{
+			Service:     "api",
+			StartedAt:   startedAt,
+			RecoveredAt: startedAt.Add(90*time.Minute + 30*time.Second),
+		},
+		Sections: postmortem.Sections{Summary: "APIが応答しなかった", Status: "解決済み"},
+		Roles:    []postmortem.Role{{Name: "コマンダー", Users: []string{"alice"}}, {Name: "専門家", Users: []string{"bob", "carol"}}},
+		Links:    postmortem.Links{Channel: "https://example.slack.com/archives/C1"},
+	}
+
+	t.Run("デフォルトのテンプレート", func(t *testing.T) {
+		tmpl, err := postmortem.Load("")
+		require.NoError(t, err)
+		out, err := tmpl.Render(data)
+		require.NoError(t, err)
+		assert.Contains(t, out, "## ステータス\n\n解決済み\n")
+		assert.Contains(t, out, "## 概要\n\nAPIが応答しなかった\n")
+		assert.Contains(t, out, "## 発生日付\n\n2025-01-06 10:00:00\n")
+		assert.Contains(t, out, "- コマンダー: alice\n- 専門家: bob, carol\n")
+		assert.Contains(t, out, "(https://example.slack.com/archives/C1)")
+
+		data := data
+		data.Roles = nil
+		out, err = tmpl.Render(data)
+		require.NoError(t, err)
+		assert.Contains(t, out, "## 対応体制\n\n記録なし\n")
+	})
+
+	t.Run("ファイルから読み込む", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "postmortem.md.tmpl")
+		require.NoError(t, os.WriteFile(path, []byte(`# {{ .Title }} ({{ .Incident.Service }})
+対応時間: {{ .Incident.Duration }}
+{{ range .Roles }}{{ .Name }}={{ len .Users }} {{ end }}`), 0o600))
+
+		tmpl, err := postmortem.Load(path)
+		require.NoError(t, err)
+		out, err := tmpl.Render(data)
+		require.NoError(t, err)
+		assert.Equal(t, "# APIの応答停止 (api)\n対応時間: 1h30m0s\nコマンダー=1 専門家=2 ", out)
+	})
+
+	t.Run("サンプルのテンプレート", func(t *testing.T) {
+		tmpl, err := postmortem.Load(filepath.Join("..", "..", "example", "postmortem.md.tmpl"))
+		require.NoError(t, err)
+		out, err := tmpl.Render(data)
+		require.NoError(t, err)
+		assert.Contains(t, out, "| 検知 | 2025-01-06 10:00 |\n| 復旧 | 2025-01-06 11:30 (1h30m0s) |\n| レベル |")
+
+		// 復旧していない場合は復旧の行を出さない
+		data := data
+		data.Incident.RecoveredAt = time.Time{}
+		out, err = tmpl.Render(data)
+		require.NoError(t, err)
+		assert.Contains(t, out, "| 検知 | 2025-01-06 10:00 |\n| レベル |")
+		assert.NotContains(t, out, "0001")
+	})
+
+	t.Run("存在しないフィールドは読み込み時にエラー", func(t *testing.T) {
+		_, err := postmortem.Parse("broken", "{{ .Incident.Severity }}")
+		assert.Error(t, err)
+		_, err = postmortem.Parse("broken", "{{ .Title ")
+		assert.Error(t, err)
+	})
+}